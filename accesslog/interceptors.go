@@ -8,10 +8,9 @@ import (
 	"sync"
 	"time"
 
-	"github.com/soyacen/gox/slogx"
+	"github.com/soyacen/grpc-middleware/logging"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/peer"
-	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/metadata"
 )
 
 func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
@@ -31,25 +30,42 @@ func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
 		handler grpc.UnaryHandler,
 	) (interface{}, error) {
 		startTime := time.Now()
-		resp, err := handler(ctx, req)
-		if o.skip(info.FullMethod, err) {
+		handlerCtx, bag := logging.ContextWithFields(ctx)
+		var recorder *metadataRecorder
+		if len(o.responseHeaders.patterns) > 0 {
+			handlerCtx, recorder = recordServerMetadata(handlerCtx)
+		}
+		resp, err := handler(handlerCtx, req)
+		if o.shouldSkip(ctx, info.FullMethod, err) {
 			return resp, err
 		}
-		fields := *pool.Get().(*[]slog.Attr)
-		fields = append(fields,
-			slog.String("system", "grpc.server"),
-			slog.String("timestamp", startTime.Format(time.RFC3339)),
-			slog.String("latency", time.Since(startTime).String()),
-			slogx.Uint("status", status.Code(err)),
-			slogx.Error("error", err),
-		)
-		if peer, ok := peer.FromContext(ctx); ok {
-			fields = append(fields, slog.String("peer", peer.Addr.String()))
+		latency := time.Since(startTime)
+		sampleRate, sampled := o.sample(ctx, info.FullMethod, err, latency)
+		if !sampled {
+			return resp, err
 		}
-		if d, ok := ctx.Deadline(); ok {
-			fields = append(fields, slog.String("deadline", d.Format(time.RFC3339)))
+		fields := *pool.Get().(*[]slog.Attr)
+		fields = o.appendCallAttrs(ctx, fields, callFields{
+			system:     systemServer,
+			fullMethod: info.FullMethod,
+			startTime:  startTime,
+			latency:    latency,
+			err:        err,
+		})
+		fields = o.appendSampleRate(fields, sampleRate)
+		ws := wireStatsFromContext(ctx, false)
+		requestEncoding, responseEncoding := serverEncodings(ctx, ws)
+		fields = appendSizeAttrs(fields, req, resp)
+		fields = appendWireAttrs(fields, ws, requestEncoding, responseEncoding)
+		md, _ := metadata.FromIncomingContext(ctx)
+		var header, trailer metadata.MD
+		if recorder != nil {
+			header, trailer = recorder.snapshot()
 		}
-		slog.LogAttrs(ctx, o.level, info.FullMethod, fields...)
+		fields = o.appendHeaderAttrs(fields, md, header, trailer)
+		fields = bag.AppendAttrs(fields)
+		fields = o.appendPayloads(fields, req, resp)
+		o.getLogger().LogAttrs(ctx, o.level, info.FullMethod, fields...)
 		// Reset the slice length to 0 to reuse the underlying array
 		fields = fields[:0]
 		// Put the slice back into the pool for reuse
@@ -88,32 +104,55 @@ func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
 		ctx := stream.Context()
 		// 记录开始时间
 		startTime := time.Now()
+		// 包装流以统计收发的消息
+		wrapped := &wrappedServerStream{ServerStream: stream, stats: newStreamStats(startTime)}
+		// 在上下文中放入字段集合，供处理器添加业务字段
+		var bag *logging.Fields
+		wrapped.ctx, bag = logging.ContextWithFields(ctx)
+		// 需要记录响应元数据时，同时记录通过流和grpc.SetHeader等函数设置的元数据
+		if len(o.responseHeaders.patterns) > 0 {
+			wrapped.ctx, wrapped.recorder = recordServerMetadata(wrapped.ctx)
+		}
 		// 执行原始处理器
-		err := handler(srv, stream)
+		err := handler(srv, wrapped)
 		// 检查是否需要跳过日志记录
-		if o.skip(info.FullMethod, err) {
+		if o.shouldSkip(ctx, info.FullMethod, err) {
+			return err
+		}
+		// 计算耗时并按采样器决定是否记录
+		latency := time.Since(startTime)
+		sampleRate, sampled := o.sample(ctx, info.FullMethod, err, latency)
+		if !sampled {
 			return err
 		}
 		// 从池中获取字段切片
 		fields := *pool.Get().(*[]slog.Attr)
-		// 添加基本日志字段
-		fields = append(fields,
-			slog.String("system", "grpc.server"),
-			slog.String("timestamp", startTime.Format(time.RFC3339)),
-			slog.String("latency", time.Since(startTime).String()),
-			slogx.Uint("status", status.Code(err)),
-			slogx.Error("error", err),
-		)
-		// 添加客户端地址信息
-		if peer, ok := peer.FromContext(ctx); ok {
-			fields = append(fields, slog.String("peer", peer.Addr.String()))
-		}
-		// 添加截止时间信息
-		if d, ok := ctx.Deadline(); ok {
-			fields = append(fields, slog.String("deadline", d.Format(time.RFC3339)))
+		// 按字段规范添加调用的基础字段
+		fields = o.appendCallAttrs(ctx, fields, callFields{
+			system:     systemServer,
+			fullMethod: info.FullMethod,
+			startTime:  startTime,
+			latency:    latency,
+			err:        err,
+		})
+		fields = o.appendSampleRate(fields, sampleRate)
+		// 添加流消息统计信息
+		fields = wrapped.stats.appendAttrs(fields)
+		// 添加压缩算法和线上字节数
+		ws := wireStatsFromContext(ctx, false)
+		requestEncoding, responseEncoding := serverEncodings(ctx, ws)
+		fields = appendWireAttrs(fields, ws, requestEncoding, responseEncoding)
+		// 添加元数据信息
+		md, _ := metadata.FromIncomingContext(ctx)
+		var header, trailer metadata.MD
+		if wrapped.recorder != nil {
+			header, trailer = wrapped.recorder.snapshot()
 		}
+		fields = o.appendHeaderAttrs(fields, md, header, trailer)
+		// 添加处理器设置的业务字段
+		fields = bag.AppendAttrs(fields)
 		// 记录日志
-		slog.LogAttrs(ctx, o.level, info.FullMethod, fields...)
+		o.getLogger().LogAttrs(ctx, o.level, info.FullMethod, fields...)
 		// 重置切片长度以便复用
 		fields = fields[:0]
 		// 将切片放回池中
@@ -153,32 +192,50 @@ func UnaryClientInterceptor(opts ...Option) grpc.UnaryClientInterceptor {
 	) error {
 		// 记录开始时间
 		startTime := time.Now()
+		// 在上下文中放入新的传输层信息，供StatsHandler记录线上字节数
+		ctx, ws := withWireStats(ctx, true)
+		// 需要记录响应元数据时，通过CallOption获取响应头和trailer
+		var header, trailer metadata.MD
+		if len(o.responseHeaders.patterns) > 0 {
+			opts = append(opts[:len(opts):len(opts)], grpc.Header(&header), grpc.Trailer(&trailer))
+		}
 		// 执行gRPC调用
 		err := invoker(ctx, method, req, reply, cc, opts...)
 		// 检查是否需要跳过日志记录
-		if o.skip(method, err) {
+		if o.shouldSkip(ctx, method, err) {
+			return err
+		}
+		// 计算耗时并按采样器决定是否记录
+		latency := time.Since(startTime)
+		sampleRate, sampled := o.sample(ctx, method, err, latency)
+		if !sampled {
 			return err
 		}
 		// 从池中获取字段切片
 		fields := *pool.Get().(*[]slog.Attr)
-		// 添加基本日志字段
-		fields = append(fields,
-			slog.String("system", "grpc.client"),
-			slog.String("timestamp", startTime.Format(time.RFC3339)),
-			slog.String("latency", time.Since(startTime).String()),
-			slogx.Uint("status", status.Code(err)),
-			slogx.Error("error", err),
-		)
-		// 添加服务端地址信息
-		if peer, ok := peer.FromContext(ctx); ok {
-			fields = append(fields, slog.String("peer", peer.Addr.String()))
-		}
-		// 添加截止时间信息
-		if d, ok := ctx.Deadline(); ok {
-			fields = append(fields, slog.String("deadline", d.Format(time.RFC3339)))
-		}
+		// 按字段规范添加调用的基础字段
+		fields = o.appendCallAttrs(ctx, fields, callFields{
+			system:     systemClient,
+			fullMethod: method,
+			target:     target(cc),
+			startTime:  startTime,
+			latency:    latency,
+			err:        err,
+		})
+		fields = o.appendSampleRate(fields, sampleRate)
+		// 添加请求和响应的大小、压缩算法和线上字节数
+		requestEncoding, responseEncoding := clientEncodings(ws, opts)
+		fields = appendSizeAttrs(fields, req, reply)
+		fields = appendWireAttrs(fields, ws, requestEncoding, responseEncoding)
+		// 添加元数据信息
+		md, _ := metadata.FromOutgoingContext(ctx)
+		fields = o.appendHeaderAttrs(fields, md, header, trailer)
+		// 添加调用方设置的业务字段
+		fields = logging.FieldsFromContext(ctx).AppendAttrs(fields)
+		// 添加请求和响应内容
+		fields = o.appendPayloads(fields, req, reply)
 		// 记录日志
-		slog.LogAttrs(ctx, o.level, method, fields...)
+		o.getLogger().LogAttrs(ctx, o.level, method, fields...)
 		// 重置切片长度以便复用
 		fields = fields[:0]
 		// 将切片放回池中
@@ -189,6 +246,10 @@ func UnaryClientInterceptor(opts ...Option) grpc.UnaryClientInterceptor {
 
 // StreamClientInterceptor 创建流式客户端日志拦截器
 // 该拦截器记录流式gRPC客户端调用的详细访问日志
+// 日志在流结束时记录（RecvMsg返回io.EOF或错误、SendMsg或CloseSend返回错误、上下文取消），
+// 因此延迟为端到端耗时，状态码为流的最终状态。
+// 注意：与gRPC自身的要求一致，调用方必须读取流直到RecvMsg返回错误或取消上下文；
+// 否则不会记录日志，并且上下文取消前一直持有日志所需的状态
 //
 // 参数:
 //   - opts: 可选的配置选项
@@ -217,36 +278,67 @@ func StreamClientInterceptor(opts ...Option) grpc.StreamClientInterceptor {
 	) (grpc.ClientStream, error) {
 		// 记录开始时间
 		startTime := time.Now()
+		// 在上下文中放入新的传输层信息，供StatsHandler记录线上字节数
+		ctx, ws := withWireStats(ctx, true)
+		// 流统计对象
+		stats := newStreamStats(startTime)
+		// 客户端流，创建失败时为nil
+		var clientStream grpc.ClientStream
+		// 流结束时记录日志
+		logFunc := func(err error) {
+			// 检查是否需要跳过日志记录
+			if o.shouldSkip(ctx, method, err) {
+				return
+			}
+			// 计算耗时并按采样器决定是否记录
+			latency := time.Since(startTime)
+			sampleRate, sampled := o.sample(ctx, method, err, latency)
+			if !sampled {
+				return
+			}
+			// 从池中获取字段切片
+			fields := *pool.Get().(*[]slog.Attr)
+			// 按字段规范添加调用的基础字段
+			fields = o.appendCallAttrs(ctx, fields, callFields{
+				system:     systemClient,
+				fullMethod: method,
+				target:     target(cc),
+				startTime:  startTime,
+				latency:    latency,
+				err:        err,
+			})
+			fields = o.appendSampleRate(fields, sampleRate)
+			// 添加流消息统计信息
+			fields = stats.appendAttrs(fields)
+			// 添加压缩算法和线上字节数
+			requestEncoding, responseEncoding := clientEncodings(ws, opts)
+			fields = appendWireAttrs(fields, ws, requestEncoding, responseEncoding)
+			// 添加元数据信息，流结束后响应头和trailer均已可用
+			md, _ := metadata.FromOutgoingContext(ctx)
+			var header, trailer metadata.MD
+			if clientStream != nil && len(o.responseHeaders.patterns) > 0 {
+				header, _ = clientStream.Header()
+				trailer = clientStream.Trailer()
+			}
+			fields = o.appendHeaderAttrs(fields, md, header, trailer)
+			// 添加调用方设置的业务字段
+			fields = logging.FieldsFromContext(ctx).AppendAttrs(fields)
+			// 记录日志
+			o.getLogger().LogAttrs(ctx, o.level, method, fields...)
+			// 重置切片长度以便复用
+			fields = fields[:0]
+			// 将切片放回池中
+			pool.Put(&fields)
+		}
 		// 执行流式gRPC调用
-		clientStream, err := streamer(ctx, desc, cc, method, opts...)
-		// 检查是否需要跳过日志记录
-		if o.skip(method, err) {
+		var err error
+		clientStream, err = streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			// 流创建失败，立即记录日志
+			logFunc(err)
 			return clientStream, err
 		}
-		// 从池中获取字段切片
-		fields := *pool.Get().(*[]slog.Attr)
-		// 添加基本日志字段
-		fields = append(fields,
-			slog.String("system", "grpc.client"),
-			slog.String("timestamp", startTime.Format(time.RFC3339)),
-			slog.String("latency", time.Since(startTime).String()),
-			slogx.Uint("status", status.Code(err)),
-			slogx.Error("error", err),
-		)
-		// 添加服务端地址信息
-		if peer, ok := peer.FromContext(ctx); ok {
-			fields = append(fields, slog.String("peer", peer.Addr.String()))
-		}
-		// 添加截止时间信息
-		if d, ok := ctx.Deadline(); ok {
-			fields = append(fields, slog.String("deadline", d.Format(time.RFC3339)))
-		}
-		// 记录日志
-		slog.LogAttrs(ctx, o.level, method, fields...)
-		// 重置切片长度以便复用
-		fields = fields[:0]
-		// 将切片放回池中
-		pool.Put(&fields)
-		return clientStream, err
+		// 包装流以统计收发的消息，并在流结束时记录日志
+		return newWrappedClientStream(ctx, clientStream, desc, stats, logFunc), nil
 	}
 }
@@ -1,12 +1,15 @@
 package accesslog
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"log/slog"
 	"testing"
 
+	"github.com/soyacen/grpc-middleware/logging"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 )
@@ -302,3 +305,54 @@ func (m *mockServerStream) SetTrailer(md metadata.MD)       {}
 func (m *mockServerStream) Context() context.Context        { return m.ctx }
 func (m *mockServerStream) SendMsg(msg any) error           { return nil }
 func (m *mockServerStream) RecvMsg(msg any) error           { return nil }
+
+func TestInterceptors_WithLogger(t *testing.T) {
+	defaultBuf := captureDefaultLogger(t)
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(buf, nil))
+
+	unary := UnaryServerInterceptor(WithLogger(logger))
+	_, err := unary(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test/method"}, mockUnaryHandler("ok", nil))
+	assert.NoError(t, err)
+
+	assert.Contains(t, buf.String(), `"msg":"/test/method"`)
+	assert.Empty(t, defaultBuf.String(), "配置了logger时不应写入默认日志")
+}
+
+func TestInterceptors_ContextFields(t *testing.T) {
+	t.Run("一元服务端", func(t *testing.T) {
+		buf := captureDefaultLogger(t)
+		interceptor := UnaryServerInterceptor()
+		handler := func(ctx context.Context, req any) (any, error) {
+			logging.FieldsFromContext(ctx).Set("order_id", "o-1")
+			return nil, nil
+		}
+		_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test/method"}, handler)
+		require.NoError(t, err)
+		assert.Equal(t, "o-1", decodeRecord(t, buf)["order_id"])
+	})
+
+	t.Run("流式服务端", func(t *testing.T) {
+		buf := captureDefaultLogger(t)
+		interceptor := StreamServerInterceptor(WithResponseHeaders("x-*"))
+		handler := func(srv any, stream grpc.ServerStream) error {
+			logging.AddFields(stream.Context(), slog.String("user_id", "u-1"))
+			return nil
+		}
+		err := interceptor(nil, &mockServerStream{ctx: context.Background()}, &grpc.StreamServerInfo{FullMethod: "/test/method"}, handler)
+		require.NoError(t, err)
+		assert.Equal(t, "u-1", decodeRecord(t, buf)["user_id"])
+	})
+
+	t.Run("一元客户端读取调用方的字段", func(t *testing.T) {
+		buf := captureDefaultLogger(t)
+		interceptor := UnaryClientInterceptor()
+		ctx, fields := logging.ContextWithFields(context.Background())
+		fields.Set("order_id", "o-2")
+		invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			return nil
+		}
+		require.NoError(t, interceptor(ctx, "/test/method", nil, nil, nil, invoker))
+		assert.Equal(t, "o-2", decodeRecord(t, buf)["order_id"])
+	})
+}
@@ -0,0 +1,218 @@
+package accesslog
+
+import (
+	"context"
+	"encoding/base64"
+	"log/slog"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/soyacen/grpc-middleware/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// defaultSensitiveHeaders 默认始终脱敏的元数据键
+var defaultSensitiveHeaders = []string{
+	"authorization",
+	"proxy-authorization",
+	"cookie",
+	"set-cookie",
+	"x-api-key",
+}
+
+// defaultHeaderValueMaxLen 默认的元数据值长度上限
+const defaultHeaderValueMaxLen = 256
+
+// headerFilter 按白名单选择需要记录的元数据，并对敏感键脱敏、对值截断
+type headerFilter struct {
+	// patterns 白名单glob模式，已转为小写
+	patterns []string
+	// sensitive 始终脱敏的键，已转为小写
+	sensitive map[string]struct{}
+	// maxLen 单个值的长度上限，小于等于0表示不限制
+	maxLen int
+}
+
+// newHeaderFilter 创建使用默认敏感键和值长度上限的元数据过滤器
+func newHeaderFilter() *headerFilter {
+	sensitive := make(map[string]struct{}, len(defaultSensitiveHeaders))
+	for _, key := range defaultSensitiveHeaders {
+		sensitive[key] = struct{}{}
+	}
+	return &headerFilter{sensitive: sensitive, maxLen: defaultHeaderValueMaxLen}
+}
+
+// match 判断元数据键是否命中白名单
+func (f *headerFilter) match(key string) bool {
+	for _, pattern := range f.patterns {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// attr 将命中白名单的元数据转换为日志分组字段
+//
+// 参数:
+//   - key: 分组字段名
+//   - md: 元数据
+//
+// 返回值:
+//   - slog.Attr: 分组字段
+//   - bool: 是否有命中的元数据
+func (f *headerFilter) attr(key string, md metadata.MD) (slog.Attr, bool) {
+	if len(f.patterns) == 0 || len(md) == 0 {
+		return slog.Attr{}, false
+	}
+	keys := make([]string, 0, len(md))
+	for k := range md {
+		// metadata.MD的键已是小写，这里再次规范化以兼容直接构造的MD
+		if f.match(strings.ToLower(k)) {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return slog.Attr{}, false
+	}
+	sort.Strings(keys)
+	attrs := make([]any, 0, len(keys))
+	for _, k := range keys {
+		attrs = append(attrs, slog.String(strings.ToLower(k), f.value(strings.ToLower(k), md[k])))
+	}
+	return slog.Group(key, attrs...), true
+}
+
+// value 格式化元数据值，多个值以","连接
+func (f *headerFilter) value(key string, vals []string) string {
+	if _, ok := f.sensitive[key]; ok {
+		return logging.RedactedValue
+	}
+	binary := strings.HasSuffix(key, "-bin")
+	formatted := make([]string, 0, len(vals))
+	for _, v := range vals {
+		if binary {
+			v = base64.StdEncoding.EncodeToString([]byte(v))
+		}
+		if f.maxLen > 0 && len(v) > f.maxLen {
+			v = truncateUTF8(v, f.maxLen) + "..."
+		}
+		formatted = append(formatted, v)
+	}
+	return strings.Join(formatted, ",")
+}
+
+// truncateUTF8 将字符串截断到字节上限，截断位置不会拆分UTF-8字符
+func truncateUTF8(s string, n int) string {
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// metadataRecorder 记录服务端发送的响应头和trailer
+type metadataRecorder struct {
+	mu      sync.Mutex
+	header  metadata.MD
+	trailer metadata.MD
+}
+
+// addHeader 记录响应头
+func (r *metadataRecorder) addHeader(md metadata.MD) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.header = metadata.Join(r.header, md)
+}
+
+// addTrailer 记录trailer
+func (r *metadataRecorder) addTrailer(md metadata.MD) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.trailer = metadata.Join(r.trailer, md)
+}
+
+// snapshot 返回已记录的响应头和trailer
+func (r *metadataRecorder) snapshot() (header, trailer metadata.MD) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.header, r.trailer
+}
+
+// recordingTransportStream 包装 grpc.ServerTransportStream 以记录一元调用中
+// 通过grpc.SetHeader、grpc.SendHeader和grpc.SetTrailer设置的元数据
+type recordingTransportStream struct {
+	grpc.ServerTransportStream
+	recorder *metadataRecorder
+}
+
+// SetHeader 设置响应头并记录
+func (s *recordingTransportStream) SetHeader(md metadata.MD) error {
+	err := s.ServerTransportStream.SetHeader(md)
+	if err == nil {
+		s.recorder.addHeader(md)
+	}
+	return err
+}
+
+// SendHeader 发送响应头并记录
+func (s *recordingTransportStream) SendHeader(md metadata.MD) error {
+	err := s.ServerTransportStream.SendHeader(md)
+	if err == nil {
+		s.recorder.addHeader(md)
+	}
+	return err
+}
+
+// SetTrailer 设置trailer并记录
+func (s *recordingTransportStream) SetTrailer(md metadata.MD) error {
+	err := s.ServerTransportStream.SetTrailer(md)
+	if err == nil {
+		s.recorder.addTrailer(md)
+	}
+	return err
+}
+
+// recordServerMetadata 在上下文中安装可记录响应元数据的传输流
+// 上下文中没有传输流时原样返回上下文，记录器仍可用于记录流式调用的元数据
+//
+// 参数:
+//   - ctx: 请求上下文
+//
+// 返回值:
+//   - context.Context: 安装了记录器的上下文
+//   - *metadataRecorder: 元数据记录器
+func recordServerMetadata(ctx context.Context) (context.Context, *metadataRecorder) {
+	recorder := &metadataRecorder{}
+	stream := grpc.ServerTransportStreamFromContext(ctx)
+	if stream == nil {
+		return ctx, recorder
+	}
+	return grpc.NewContextWithServerTransportStream(ctx, &recordingTransportStream{ServerTransportStream: stream, recorder: recorder}), recorder
+}
+
+// appendHeaderAttrs 将请求元数据、响应头和trailer追加到日志字段中
+//
+// 参数:
+//   - fields: 已有的日志字段
+//   - request: 请求元数据
+//   - header: 响应头
+//   - trailer: trailer
+//
+// 返回值:
+//   - []slog.Attr: 追加后的日志字段
+func (o *options) appendHeaderAttrs(fields []slog.Attr, request, header, trailer metadata.MD) []slog.Attr {
+	if attr, ok := o.requestHeaders.attr("request_headers", request); ok {
+		fields = append(fields, attr)
+	}
+	if attr, ok := o.responseHeaders.attr("response_headers", header); ok {
+		fields = append(fields, attr)
+	}
+	if attr, ok := o.responseHeaders.attr("response_trailers", trailer); ok {
+		fields = append(fields, attr)
+	}
+	return fields
+}
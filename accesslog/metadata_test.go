@@ -0,0 +1,284 @@
+package accesslog
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type mockTransportStream struct {
+	header  metadata.MD
+	trailer metadata.MD
+}
+
+func (m *mockTransportStream) Method() string { return "/test/method" }
+func (m *mockTransportStream) SetHeader(md metadata.MD) error {
+	m.header = metadata.Join(m.header, md)
+	return nil
+}
+func (m *mockTransportStream) SendHeader(md metadata.MD) error { return m.SetHeader(md) }
+func (m *mockTransportStream) SetTrailer(md metadata.MD) error {
+	m.trailer = metadata.Join(m.trailer, md)
+	return nil
+}
+
+// groupValues 将分组字段转换为键值映射
+func groupValues(attr slog.Attr) map[string]string {
+	values := map[string]string{}
+	for _, a := range attr.Value.Group() {
+		values[a.Key] = a.Value.String()
+	}
+	return values
+}
+
+func TestHeaderFilter_Attr(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []Option
+		md     metadata.MD
+		want   map[string]string
+		wantOK bool
+	}{
+		{
+			name:   "未配置白名单不记录",
+			md:     metadata.Pairs("user-agent", "grpc-go"),
+			wantOK: false,
+		},
+		{
+			name:   "精确匹配",
+			opts:   []Option{WithRequestHeaders("user-agent", "x-request-id")},
+			md:     metadata.Pairs("user-agent", "grpc-go", "x-request-id", "r1", "x-other", "o"),
+			want:   map[string]string{"user-agent": "grpc-go", "x-request-id": "r1"},
+			wantOK: true,
+		},
+		{
+			name:   "glob匹配且不区分大小写",
+			opts:   []Option{WithRequestHeaders("X-*")},
+			md:     metadata.Pairs("x-tenant", "t1", "x-request-id", "r1", "user-agent", "grpc-go"),
+			want:   map[string]string{"x-tenant": "t1", "x-request-id": "r1"},
+			wantOK: true,
+		},
+		{
+			name:   "敏感键始终脱敏",
+			opts:   []Option{WithRequestHeaders("*")},
+			md:     metadata.Pairs("authorization", "Bearer abc", "cookie", "sid=1", "x-tenant", "t1"),
+			want:   map[string]string{"authorization": "[REDACTED]", "cookie": "[REDACTED]", "x-tenant": "t1"},
+			wantOK: true,
+		},
+		{
+			name:   "自定义敏感键",
+			opts:   []Option{WithRequestHeaders("*"), WithSensitiveHeaders("X-Secret")},
+			md:     metadata.Pairs("x-secret", "s"),
+			want:   map[string]string{"x-secret": "[REDACTED]"},
+			wantOK: true,
+		},
+		{
+			name:   "多值以逗号连接",
+			opts:   []Option{WithRequestHeaders("x-tag")},
+			md:     metadata.Pairs("x-tag", "a", "x-tag", "b"),
+			want:   map[string]string{"x-tag": "a,b"},
+			wantOK: true,
+		},
+		{
+			name:   "值长度截断",
+			opts:   []Option{WithRequestHeaders("x-long"), WithHeaderValueMaxLen(3)},
+			md:     metadata.Pairs("x-long", "abcdef"),
+			want:   map[string]string{"x-long": "abc..."},
+			wantOK: true,
+		},
+		{
+			name:   "截断不拆分多字节字符",
+			opts:   []Option{WithRequestHeaders("x-name"), WithHeaderValueMaxLen(4)},
+			md:     metadata.Pairs("x-name", "中文名"),
+			want:   map[string]string{"x-name": "中..."},
+			wantOK: true,
+		},
+		{
+			name:   "二进制值base64编码",
+			opts:   []Option{WithRequestHeaders("x-trace-bin")},
+			md:     metadata.Pairs("x-trace-bin", "\x01\x02"),
+			want:   map[string]string{"x-trace-bin": "AQI="},
+			wantOK: true,
+		},
+		{
+			name:   "无命中不记录",
+			opts:   []Option{WithRequestHeaders("x-tenant")},
+			md:     metadata.Pairs("user-agent", "grpc-go"),
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := defaultOptions().apply(tt.opts...)
+			attr, ok := o.requestHeaders.attr("request_headers", tt.md)
+			assert.Equal(t, tt.wantOK, ok)
+			if tt.wantOK {
+				assert.Equal(t, "request_headers", attr.Key)
+				assert.Equal(t, tt.want, groupValues(attr))
+			}
+		})
+	}
+}
+
+func TestWithSensitiveHeaders_AppliesToResponse(t *testing.T) {
+	o := defaultOptions().apply(WithResponseHeaders("*"), WithSensitiveHeaders("x-session"))
+	attr, ok := o.responseHeaders.attr("response_headers", metadata.Pairs("x-session", "s", "set-cookie", "c"))
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{"x-session": "[REDACTED]", "set-cookie": "[REDACTED]"}, groupValues(attr))
+}
+
+func TestRecordServerMetadata(t *testing.T) {
+	tests := []struct {
+		name          string
+		withTransport bool
+	}{
+		{"上下文中有传输流", true},
+		{"上下文中无传输流", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			transport := &mockTransportStream{}
+			if tt.withTransport {
+				ctx = grpc.NewContextWithServerTransportStream(ctx, transport)
+			}
+
+			ctx, recorder := recordServerMetadata(ctx)
+			require.NotNil(t, recorder)
+
+			err := grpc.SetHeader(ctx, metadata.Pairs("x-a", "1"))
+			if !tt.withTransport {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, grpc.SendHeader(ctx, metadata.Pairs("x-b", "2")))
+			require.NoError(t, grpc.SetTrailer(ctx, metadata.Pairs("x-c", "3")))
+
+			header, trailer := recorder.snapshot()
+			assert.Equal(t, metadata.Pairs("x-a", "1", "x-b", "2"), header)
+			assert.Equal(t, metadata.Pairs("x-c", "3"), trailer)
+			assert.Equal(t, header, transport.header, "元数据应继续传递给原始传输流")
+		})
+	}
+}
+
+func TestUnaryServerInterceptor_Headers(t *testing.T) {
+	buf := captureDefaultLogger(t)
+	interceptor := UnaryServerInterceptor(
+		WithRequestHeaders("user-agent", "x-*", "authorization"),
+		WithResponseHeaders("x-*"),
+	)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"user-agent", "grpc-go",
+		"x-request-id", "r1",
+		"authorization", "Bearer secret",
+		"content-type", "application/grpc",
+	))
+	ctx = grpc.NewContextWithServerTransportStream(ctx, &mockTransportStream{})
+	handler := func(ctx context.Context, req any) (any, error) {
+		if err := grpc.SetHeader(ctx, metadata.Pairs("x-served-by", "node-1")); err != nil {
+			return nil, err
+		}
+		if err := grpc.SetTrailer(ctx, metadata.Pairs("x-cost", "3")); err != nil {
+			return nil, err
+		}
+		return "ok", nil
+	}
+
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/test/method"}, handler)
+	require.NoError(t, err)
+
+	record := decodeRecord(t, buf)
+	assert.Equal(t, map[string]any{
+		"user-agent":    "grpc-go",
+		"x-request-id":  "r1",
+		"authorization": "[REDACTED]",
+	}, record["request_headers"])
+	assert.Equal(t, map[string]any{"x-served-by": "node-1"}, record["response_headers"])
+	assert.Equal(t, map[string]any{"x-cost": "3"}, record["response_trailers"])
+	assert.NotContains(t, buf.String(), "secret")
+}
+
+func TestStreamServerInterceptor_Headers(t *testing.T) {
+	buf := captureDefaultLogger(t)
+	interceptor := StreamServerInterceptor(WithResponseHeaders("x-*"))
+	handler := func(srv any, stream grpc.ServerStream) error {
+		if err := stream.SetHeader(metadata.Pairs("x-a", "1")); err != nil {
+			return err
+		}
+		stream.SetTrailer(metadata.Pairs("x-b", "2"))
+		return nil
+	}
+
+	err := interceptor(nil, &mockServerStream{ctx: context.Background()}, &grpc.StreamServerInfo{FullMethod: "/test/method"}, handler)
+	require.NoError(t, err)
+
+	record := decodeRecord(t, buf)
+	assert.Equal(t, map[string]any{"x-a": "1"}, record["response_headers"])
+	assert.Equal(t, map[string]any{"x-b": "2"}, record["response_trailers"])
+}
+
+func TestUnaryClientInterceptor_Headers(t *testing.T) {
+	buf := captureDefaultLogger(t)
+	interceptor := UnaryClientInterceptor(WithRequestHeaders("x-tenant"), WithResponseHeaders("x-*"))
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-tenant", "t1", "authorization", "Bearer secret")
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		for _, opt := range opts {
+			switch o := opt.(type) {
+			case grpc.HeaderCallOption:
+				*o.HeaderAddr = metadata.Pairs("x-served-by", "node-1")
+			case grpc.TrailerCallOption:
+				*o.TrailerAddr = metadata.Pairs("x-cost", "3")
+			}
+		}
+		return nil
+	}
+
+	callOpts := make([]grpc.CallOption, 0, 4)
+	err := interceptor(ctx, "/test/method", nil, nil, nil, invoker, callOpts...)
+	require.NoError(t, err)
+
+	record := decodeRecord(t, buf)
+	assert.Equal(t, map[string]any{"x-tenant": "t1"}, record["request_headers"])
+	assert.Equal(t, map[string]any{"x-served-by": "node-1"}, record["response_headers"])
+	assert.Equal(t, map[string]any{"x-cost": "3"}, record["response_trailers"])
+	assert.Nil(t, callOpts[:cap(callOpts)][0], "不应写入调用方CallOption切片的底层数组")
+	assert.False(t, strings.Contains(buf.String(), "secret"))
+}
+
+type headerClientStream struct {
+	mockClientStream
+	header  metadata.MD
+	trailer metadata.MD
+}
+
+func (m *headerClientStream) Header() (metadata.MD, error) { return m.header, nil }
+func (m *headerClientStream) Trailer() metadata.MD         { return m.trailer }
+
+func TestStreamClientInterceptor_Headers(t *testing.T) {
+	buf := captureDefaultLogger(t)
+	interceptor := StreamClientInterceptor(WithResponseHeaders("x-*"))
+	mockStream := &headerClientStream{
+		mockClientStream: mockClientStream{ctx: context.Background(), recvErr: io.EOF},
+		header:           metadata.Pairs("x-a", "1"),
+		trailer:          metadata.Pairs("x-b", "2"),
+	}
+
+	stream, err := interceptor(context.Background(), &grpc.StreamDesc{ServerStreams: true}, nil, "/test/method", mockStreamer(mockStream, nil))
+	require.NoError(t, err)
+	require.ErrorIs(t, stream.RecvMsg(nil), io.EOF)
+
+	record := decodeRecord(t, buf)
+	assert.Equal(t, map[string]any{"x-a": "1"}, record["response_headers"])
+	assert.Equal(t, map[string]any{"x-b": "2"}, record["response_trailers"])
+}
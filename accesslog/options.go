@@ -2,7 +2,11 @@
 package accesslog
 
 import (
+	"context"
 	"log/slog"
+	"strings"
+
+	"github.com/soyacen/grpc-middleware/logging"
 )
 
 // options 存储访问日志中间件的配置选项
@@ -11,6 +15,24 @@ type options struct {
 	level slog.Level
 	// skip 用于确定是否跳过日志记录的函数
 	skip func(fullMethodName string, err error) bool
+	// skipContext 结合请求上下文确定是否跳过日志记录的函数，可为nil
+	skipContext func(ctx context.Context, fullMethodName string, err error) bool
+	// logger 日志记录器，为nil时使用slog.Default()
+	logger *slog.Logger
+	// printRequest 是否记录一元调用的请求内容
+	printRequest bool
+	// printResponse 是否记录一元调用的响应内容
+	printResponse bool
+	// payloadRenderer 请求和响应的渲染器
+	payloadRenderer *logging.PayloadRenderer
+	// requestHeaders 需要记录的请求元数据
+	requestHeaders *headerFilter
+	// responseHeaders 需要记录的响应头和trailer
+	responseHeaders *headerFilter
+	// sampler 采样器，为nil时记录所有调用
+	sampler Sampler
+	// schema 基础字段的命名规范
+	schema FieldSchema
 }
 
 // apply 将给定的选项应用到选项结构体中
@@ -40,6 +62,9 @@ func defaultOptions() *options {
 		skip: func(fullMethodName string, err error) bool {
 			return false
 		},
+		payloadRenderer: logging.NewPayloadRenderer(),
+		requestHeaders:  newHeaderFilter(),
+		responseHeaders: newHeaderFilter(),
 	}
 }
 
@@ -68,3 +93,208 @@ func WithSkip(skip func(fullMethodName string, err error) bool) Option {
 		o.skip = skip
 	}
 }
+
+// WithSkipContext 设置结合请求上下文的跳过函数，与WithSkip设置的函数任一返回true即跳过
+// 上下文为拦截器收到的上下文，只包含在它之前执行的拦截器写入的值
+//
+// 参数:
+//   - skip: 确定是否跳过日志记录的函数
+//
+// 返回值:
+//   - Option: 设置跳过选项的函数
+func WithSkipContext(skip func(ctx context.Context, fullMethodName string, err error) bool) Option {
+	return func(o *options) {
+		o.skipContext = skip
+	}
+}
+
+// shouldSkip 判断是否跳过日志记录
+func (o *options) shouldSkip(ctx context.Context, fullMethodName string, err error) bool {
+	if o.skip(fullMethodName, err) {
+		return true
+	}
+	return o.skipContext != nil && o.skipContext(ctx, fullMethodName, err)
+}
+
+// WithFieldSchema 设置基础字段的命名规范
+// 默认使用SchemaLegacy，与原有日志格式保持一致
+//
+// 参数:
+//   - schema: 字段规范
+//
+// 返回值:
+//   - Option: 设置字段规范选项的函数
+func WithFieldSchema(schema FieldSchema) Option {
+	return func(o *options) {
+		o.schema = schema
+	}
+}
+
+// WithSampler 设置访问日志的采样器
+// 采样在跳过函数之后执行，设置后日志中会携带sample_rate字段
+//
+// 参数:
+//   - sampler: 采样器，为nil时记录所有调用
+//
+// 返回值:
+//   - Option: 设置采样器选项的函数
+func WithSampler(sampler Sampler) Option {
+	return func(o *options) {
+		o.sampler = sampler
+	}
+}
+
+// WithLogger 设置访问日志使用的日志记录器
+// 未设置或设置为nil时使用slog.Default()
+//
+// 参数:
+//   - logger: 日志记录器
+//
+// 返回值:
+//   - Option: 设置日志记录器选项的函数
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// getLogger 返回配置的日志记录器，未配置时返回slog.Default()
+func (o *options) getLogger() *slog.Logger {
+	if o.logger != nil {
+		return o.logger
+	}
+	return slog.Default()
+}
+
+// WithPrintRequest 设置是否记录一元调用的请求内容
+// 请求内容经过payloadRenderer脱敏和截断后记录
+//
+// 参数:
+//   - enable: 是否记录请求内容
+//
+// 返回值:
+//   - Option: 设置请求记录选项的函数
+func WithPrintRequest(enable bool) Option {
+	return func(o *options) {
+		o.printRequest = enable
+	}
+}
+
+// WithPrintResponse 设置是否记录一元调用的响应内容
+// 响应内容经过payloadRenderer脱敏和截断后记录
+//
+// 参数:
+//   - enable: 是否记录响应内容
+//
+// 返回值:
+//   - Option: 设置响应记录选项的函数
+func WithPrintResponse(enable bool) Option {
+	return func(o *options) {
+		o.printResponse = enable
+	}
+}
+
+// WithPayloadRenderer 设置请求和响应的渲染器
+// 用于配置脱敏字段和字节上限，nil值会被忽略
+//
+// 参数:
+//   - renderer: 消息渲染器
+//
+// 返回值:
+//   - Option: 设置渲染器选项的函数
+func WithPayloadRenderer(renderer *logging.PayloadRenderer) Option {
+	return func(o *options) {
+		if renderer != nil {
+			o.payloadRenderer = renderer
+		}
+	}
+}
+
+// WithRequestHeaders 设置需要记录的请求元数据
+// 服务端记录收到的元数据，客户端记录发出的元数据
+// 支持glob模式（如"x-*"），键不区分大小写
+//
+// 参数:
+//   - patterns: 元数据键的白名单模式
+//
+// 返回值:
+//   - Option: 设置请求元数据记录选项的函数
+func WithRequestHeaders(patterns ...string) Option {
+	return func(o *options) {
+		o.requestHeaders.patterns = appendPatterns(o.requestHeaders.patterns, patterns)
+	}
+}
+
+// WithResponseHeaders 设置需要记录的响应头和trailer
+// 支持glob模式（如"x-*"），键不区分大小写
+//
+// 参数:
+//   - patterns: 元数据键的白名单模式
+//
+// 返回值:
+//   - Option: 设置响应元数据记录选项的函数
+func WithResponseHeaders(patterns ...string) Option {
+	return func(o *options) {
+		o.responseHeaders.patterns = appendPatterns(o.responseHeaders.patterns, patterns)
+	}
+}
+
+// WithSensitiveHeaders 追加始终脱敏的元数据键
+// 默认脱敏authorization、proxy-authorization、cookie、set-cookie和x-api-key，
+// 即使命中白名单也只记录"[REDACTED]"
+//
+// 参数:
+//   - keys: 敏感的元数据键
+//
+// 返回值:
+//   - Option: 设置敏感元数据选项的函数
+func WithSensitiveHeaders(keys ...string) Option {
+	return func(o *options) {
+		for _, key := range keys {
+			o.requestHeaders.sensitive[strings.ToLower(key)] = struct{}{}
+			o.responseHeaders.sensitive[strings.ToLower(key)] = struct{}{}
+		}
+	}
+}
+
+// WithHeaderValueMaxLen 设置元数据值的长度上限
+// 超出部分被截断并以"..."结尾，小于等于0表示不限制，默认256
+//
+// 参数:
+//   - n: 长度上限
+//
+// 返回值:
+//   - Option: 设置元数据值长度上限选项的函数
+func WithHeaderValueMaxLen(n int) Option {
+	return func(o *options) {
+		o.requestHeaders.maxLen = n
+		o.responseHeaders.maxLen = n
+	}
+}
+
+// appendPatterns 将白名单模式转为小写后追加
+func appendPatterns(dst []string, patterns []string) []string {
+	for _, pattern := range patterns {
+		dst = append(dst, strings.ToLower(pattern))
+	}
+	return dst
+}
+
+// appendPayloads 按配置将请求和响应内容追加到日志字段中
+//
+// 参数:
+//   - fields: 已有的日志字段
+//   - req: 请求消息
+//   - resp: 响应消息
+//
+// 返回值:
+//   - []slog.Attr: 追加后的日志字段
+func (o *options) appendPayloads(fields []slog.Attr, req any, resp any) []slog.Attr {
+	if o.printRequest && req != nil {
+		fields = o.payloadRenderer.AppendAttrs(fields, "request", req)
+	}
+	if o.printResponse && resp != nil {
+		fields = o.payloadRenderer.AppendAttrs(fields, "response", resp)
+	}
+	return fields
+}
@@ -1,9 +1,12 @@
 package accesslog
 
 import (
+	"context"
+	"io"
 	"log/slog"
 	"testing"
 
+	"github.com/soyacen/grpc-middleware/logging"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -151,3 +154,109 @@ func TestOptionsApply(t *testing.T) {
 		})
 	}
 }
+
+func TestWithLogger(t *testing.T) {
+	custom := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tests := []struct {
+		name   string
+		logger *slog.Logger
+		want   *slog.Logger
+	}{
+		{
+			name:   "custom_logger",
+			logger: custom,
+			want:   custom,
+		},
+		{
+			name:   "nil_logger_falls_back_to_default",
+			logger: nil,
+			want:   slog.Default(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := defaultOptions()
+			o.apply(WithLogger(tt.logger))
+			assert.Same(t, tt.want, o.getLogger())
+		})
+	}
+}
+
+func TestAppendPayloads(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []Option
+		req       any
+		resp      any
+		wantAttrs []slog.Attr
+	}{
+		{
+			name:      "默认不记录",
+			opts:      nil,
+			req:       "req",
+			resp:      "resp",
+			wantAttrs: nil,
+		},
+		{
+			name:      "记录请求和响应",
+			opts:      []Option{WithPrintRequest(true), WithPrintResponse(true)},
+			req:       "req",
+			resp:      "resp",
+			wantAttrs: []slog.Attr{slog.String("request", "req"), slog.String("response", "resp")},
+		},
+		{
+			name:      "nil响应不记录",
+			opts:      []Option{WithPrintRequest(true), WithPrintResponse(true)},
+			req:       "req",
+			resp:      nil,
+			wantAttrs: []slog.Attr{slog.String("request", "req")},
+		},
+		{
+			name: "自定义渲染器截断",
+			opts: []Option{
+				WithPrintRequest(true),
+				WithPayloadRenderer(logging.NewPayloadRenderer(logging.WithMaxBytes(2))),
+			},
+			req:       "req",
+			wantAttrs: []slog.Attr{slog.String("request", "re"), slog.Bool("request_truncated", true)},
+		},
+		{
+			name:      "nil渲染器被忽略",
+			opts:      []Option{WithPrintRequest(true), WithPayloadRenderer(nil)},
+			req:       "req",
+			wantAttrs: []slog.Attr{slog.String("request", "req")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := defaultOptions().apply(tt.opts...)
+			assert.Equal(t, tt.wantAttrs, o.appendPayloads(nil, tt.req, tt.resp))
+		})
+	}
+}
+
+func TestWithSkipContext(t *testing.T) {
+	type ctxKey struct{}
+	skipTagged := func(ctx context.Context, _ string, _ error) bool { return ctx.Value(ctxKey{}) != nil }
+	tagged := context.WithValue(context.Background(), ctxKey{}, true)
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		ctx      context.Context
+		wantSkip bool
+	}{
+		{"上下文满足条件", []Option{WithSkipContext(skipTagged)}, tagged, true},
+		{"上下文不满足条件", []Option{WithSkipContext(skipTagged)}, context.Background(), false},
+		{"WithSkip返回true", []Option{WithSkipContext(skipTagged), WithSkip(func(string, error) bool { return true })}, context.Background(), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := defaultOptions().apply(tt.opts...)
+			assert.Equal(t, tt.wantSkip, o.shouldSkip(tt.ctx, "/test/method", nil))
+		})
+	}
+}
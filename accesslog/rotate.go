@@ -0,0 +1,261 @@
+package accesslog
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat 备份文件名中的时间格式，按字典序排序即按时间排序
+const backupTimeFormat = "20060102T150405.000000000"
+
+// defaultMaxFileSize 默认的单个日志文件大小上限（100MB）
+const defaultMaxFileSize = 100 << 20
+
+// rotateOptions 存储RotatingFile的配置选项
+type rotateOptions struct {
+	// maxSize 单个文件大小上限，小于等于0表示不按大小轮转
+	maxSize int64
+	// maxAge 单个文件写入时长上限，小于等于0表示不按时长轮转
+	maxAge time.Duration
+	// maxBackups 保留的旧文件个数，小于等于0表示全部保留
+	maxBackups int
+	// now 获取当前时间，便于测试
+	now func() time.Time
+	// openFile 打开日志文件，便于测试
+	openFile func(name string) (*os.File, error)
+}
+
+// RotateOption 定义RotatingFile配置选项的函数类型
+type RotateOption func(*rotateOptions)
+
+// WithMaxFileSize 设置单个日志文件的大小上限，超过后轮转
+//
+// 参数:
+//   - bytes: 字节数，小于等于0表示不按大小轮转，默认100MB
+//
+// 返回值:
+//   - RotateOption: 设置文件大小上限选项的函数
+func WithMaxFileSize(bytes int64) RotateOption {
+	return func(o *rotateOptions) {
+		o.maxSize = bytes
+	}
+}
+
+// WithMaxFileAge 设置单个日志文件的写入时长上限，超过后轮转
+//
+// 参数:
+//   - age: 时长，小于等于0表示不按时长轮转，默认不按时长轮转
+//
+// 返回值:
+//   - RotateOption: 设置文件时长上限选项的函数
+func WithMaxFileAge(age time.Duration) RotateOption {
+	return func(o *rotateOptions) {
+		o.maxAge = age
+	}
+}
+
+// WithMaxBackups 设置保留的旧日志文件个数，超出的最旧文件会被删除
+//
+// 参数:
+//   - n: 文件个数，小于等于0表示全部保留
+//
+// 返回值:
+//   - RotateOption: 设置保留文件个数选项的函数
+func WithMaxBackups(n int) RotateOption {
+	return func(o *rotateOptions) {
+		o.maxBackups = n
+	}
+}
+
+// RotatingFile 按大小和时长轮转的日志文件
+// 轮转时当前文件被重命名为"<名称>-<时间><扩展名>"，随后创建新文件
+// 通常作为AsyncWriter的底层写入器使用
+type RotatingFile struct {
+	filename string
+	opts     *rotateOptions
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+}
+
+// NewRotatingFile 打开或创建日志文件，目录不存在时自动创建
+//
+// 参数:
+//   - filename: 日志文件路径
+//   - opts: 可选的配置选项
+//
+// 返回值:
+//   - *RotatingFile: 轮转日志文件
+//   - error: 打开文件失败时返回错误
+func NewRotatingFile(filename string, opts ...RotateOption) (*RotatingFile, error) {
+	o := &rotateOptions{maxSize: defaultMaxFileSize, now: time.Now, openFile: openAppend}
+	for _, opt := range opts {
+		opt(o)
+	}
+	f := &RotatingFile{filename: filename, opts: o}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Write 写入日志，写入前按需轮转文件
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.shouldRotate(len(p)) {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Rotate 立即轮转日志文件
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	return f.rotate()
+}
+
+// Close 关闭日志文件
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// shouldRotate 判断写入n字节前是否需要轮转，空文件不轮转
+func (f *RotatingFile) shouldRotate(n int) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.opts.maxSize > 0 && f.size+int64(n) > f.opts.maxSize {
+		return true
+	}
+	return f.opts.maxAge > 0 && f.opts.now().Sub(f.openedAt) >= f.opts.maxAge
+}
+
+// openAppend 以追加方式打开或创建文件
+func openAppend(name string) (*os.File, error) {
+	return os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+}
+
+// open 以追加方式打开日志文件
+func (f *RotatingFile) open() error {
+	file, size, err := f.openFile()
+	if err != nil {
+		return err
+	}
+	f.file = file
+	f.size = size
+	f.openedAt = f.opts.now()
+	return nil
+}
+
+// openFile 打开日志文件并返回当前大小，目录不存在时自动创建
+func (f *RotatingFile) openFile() (*os.File, int64, error) {
+	if err := os.MkdirAll(filepath.Dir(f.filename), 0o755); err != nil {
+		return nil, 0, err
+	}
+	file, err := f.opts.openFile(f.filename)
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, 0, err
+	}
+	return file, info.Size(), nil
+}
+
+// rotate 重命名当前文件、创建新文件并清理多余的旧文件
+// 新文件创建成功前保留当前文件句柄，失败时继续写入当前文件并在下次写入时重试
+func (f *RotatingFile) rotate() error {
+	prefix, ext := f.backupPrefix()
+	backup := prefix + f.opts.now().Format(backupTimeFormat) + ext
+	// 上次轮转已重命名但未能创建新文件时，原文件已不存在
+	if err := os.Rename(f.filename, backup); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	file, size, err := f.openFile()
+	if err != nil {
+		return err
+	}
+	closeErr := f.file.Close()
+	f.file = file
+	f.size = size
+	f.openedAt = f.opts.now()
+	if closeErr != nil {
+		return closeErr
+	}
+	return f.removeOldBackups()
+}
+
+// backupPrefix 返回备份文件名的前缀和扩展名
+func (f *RotatingFile) backupPrefix() (prefix, ext string) {
+	ext = filepath.Ext(f.filename)
+	return strings.TrimSuffix(f.filename, ext) + "-", ext
+}
+
+// backups 返回按时间从旧到新排序的备份文件
+func (f *RotatingFile) backups() ([]string, error) {
+	prefix, ext := f.backupPrefix()
+	entries, err := os.ReadDir(filepath.Dir(f.filename))
+	if err != nil {
+		return nil, err
+	}
+	namePrefix := filepath.Base(prefix)
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, namePrefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, namePrefix), ext)
+		if _, err := time.Parse(backupTimeFormat, stamp); err != nil {
+			continue
+		}
+		names = append(names, filepath.Join(filepath.Dir(f.filename), name))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// removeOldBackups 删除超出保留个数的最旧文件
+func (f *RotatingFile) removeOldBackups() error {
+	if f.opts.maxBackups <= 0 {
+		return nil
+	}
+	names, err := f.backups()
+	if err != nil {
+		return err
+	}
+	for len(names) > f.opts.maxBackups {
+		if err := os.Remove(names[0]); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		names = names[1:]
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package accesslog
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock 可手动推进的时钟
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// withClock 替换RotatingFile使用的时钟
+func withClock(c *fakeClock) RotateOption {
+	return func(o *rotateOptions) {
+		o.now = c.Now
+	}
+}
+
+func readFile(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(name)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestRotatingFile_RotateBySize(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	filename := filepath.Join(dir, "logs", "access.log")
+
+	f, err := NewRotatingFile(filename, WithMaxFileSize(10), withClock(clock))
+	require.NoError(t, err)
+
+	_, err = f.Write([]byte("12345678\n"))
+	require.NoError(t, err)
+	clock.Advance(time.Millisecond)
+	// 超过上限前轮转
+	_, err = f.Write([]byte("abc\n"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	backups, err := f.backups()
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	assert.Equal(t, filepath.Join(dir, "logs", "access-20240101T000000.001000000.log"), backups[0])
+	assert.Equal(t, "12345678\n", readFile(t, backups[0]))
+	assert.Equal(t, "abc\n", readFile(t, filename))
+}
+
+func TestRotatingFile_RotateByAge(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	filename := filepath.Join(dir, "access.log")
+
+	f, err := NewRotatingFile(filename, WithMaxFileSize(0), WithMaxFileAge(time.Hour), withClock(clock))
+	require.NoError(t, err)
+
+	_, err = f.Write([]byte("a\n"))
+	require.NoError(t, err)
+	clock.Advance(30 * time.Minute)
+	_, err = f.Write([]byte("b\n"))
+	require.NoError(t, err)
+	clock.Advance(30 * time.Minute)
+	_, err = f.Write([]byte("c\n"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	backups, err := f.backups()
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	assert.Equal(t, "a\nb\n", readFile(t, backups[0]))
+	assert.Equal(t, "c\n", readFile(t, filename))
+}
+
+func TestRotatingFile_MaxBackups(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	filename := filepath.Join(dir, "access.log")
+	// 无关文件不应被清理
+	other := filepath.Join(dir, "access-other.log")
+	require.NoError(t, os.WriteFile(other, []byte("keep"), 0o644))
+
+	f, err := NewRotatingFile(filename, WithMaxBackups(2), withClock(clock))
+	require.NoError(t, err)
+	for _, line := range []string{"1\n", "2\n", "3\n", "4\n"} {
+		_, err = f.Write([]byte(line))
+		require.NoError(t, err)
+		clock.Advance(time.Second)
+		require.NoError(t, f.Rotate())
+	}
+	require.NoError(t, f.Close())
+
+	backups, err := f.backups()
+	require.NoError(t, err)
+	require.Len(t, backups, 2)
+	assert.Equal(t, "3\n", readFile(t, backups[0]))
+	assert.Equal(t, "4\n", readFile(t, backups[1]))
+	assert.FileExists(t, other)
+}
+
+func TestRotatingFile_AppendsExisting(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "access.log")
+	require.NoError(t, os.WriteFile(filename, []byte("old\n"), 0o644))
+
+	f, err := NewRotatingFile(filename, WithMaxFileSize(6))
+	require.NoError(t, err)
+	// 已有内容计入大小，写入前轮转
+	_, err = f.Write([]byte("new\n"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	assert.Equal(t, "new\n", readFile(t, filename))
+	_, err = f.Write([]byte("x"))
+	assert.ErrorIs(t, err, os.ErrClosed)
+}
+
+func TestRotatingFile_RetryOpenAfterFailedRotate(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	filename := filepath.Join(dir, "access.log")
+	openErr := errors.New("too many open files")
+	failOpen := false
+	withOpenFile := func(o *rotateOptions) {
+		o.openFile = func(name string) (*os.File, error) {
+			if failOpen {
+				return nil, openErr
+			}
+			return openAppend(name)
+		}
+	}
+
+	f, err := NewRotatingFile(filename, WithMaxFileSize(4), withClock(clock), withOpenFile)
+	require.NoError(t, err)
+	_, err = f.Write([]byte("old\n"))
+	require.NoError(t, err)
+
+	// 新文件创建失败时保留当前文件句柄
+	failOpen = true
+	_, err = f.Write([]byte("new\n"))
+	assert.ErrorIs(t, err, openErr)
+	require.NotNil(t, f.file)
+
+	// 下次写入时重新创建文件
+	failOpen = false
+	clock.Advance(time.Millisecond)
+	_, err = f.Write([]byte("new\n"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	backups, err := f.backups()
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	assert.Equal(t, "old\n", readFile(t, backups[0]))
+	assert.Equal(t, "new\n", readFile(t, filename))
+}
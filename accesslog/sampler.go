@@ -0,0 +1,220 @@
+package accesslog
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CallInfo 描述一次已完成的调用，供采样器决策
+type CallInfo struct {
+	// FullMethod 完整的方法名
+	FullMethod string
+	// Err 调用返回的错误
+	Err error
+	// Latency 调用耗时
+	Latency time.Duration
+}
+
+// Sampler 决定一次调用是否记录访问日志
+type Sampler interface {
+	// Sample 返回是否记录该调用以及采样率
+	// 采样率取值(0, 1]，下游可按1/rate还原调用次数
+	Sample(ctx context.Context, call CallInfo) (sampled bool, rate float64)
+}
+
+// SamplerFunc 是Sampler接口的函数适配器
+type SamplerFunc func(ctx context.Context, call CallInfo) (sampled bool, rate float64)
+
+// Sample 实现Sampler接口
+func (f SamplerFunc) Sample(ctx context.Context, call CallInfo) (bool, float64) {
+	return f(ctx, call)
+}
+
+// FixedRateSampler 创建固定比例的采样器
+//
+// 参数:
+//   - rate: 采样比例，大于等于1时全部记录，小于等于0时全部丢弃
+//
+// 返回值:
+//   - Sampler: 采样器
+func FixedRateSampler(rate float64) Sampler {
+	return SamplerFunc(func(ctx context.Context, call CallInfo) (bool, float64) {
+		return sampleRate(rate)
+	})
+}
+
+// PerMethodSampler 创建按方法配置比例的采样器
+//
+// 参数:
+//   - rates: 完整方法名到采样比例的映射
+//   - defaultRate: 未配置的方法使用的采样比例
+//
+// 返回值:
+//   - Sampler: 采样器
+func PerMethodSampler(rates map[string]float64, defaultRate float64) Sampler {
+	// 复制一份，避免调用方后续修改映射引发并发读写
+	copied := make(map[string]float64, len(rates))
+	for method, rate := range rates {
+		copied[method] = rate
+	}
+	return SamplerFunc(func(ctx context.Context, call CallInfo) (bool, float64) {
+		if rate, ok := copied[call.FullMethod]; ok {
+			return sampleRate(rate)
+		}
+		return sampleRate(defaultRate)
+	})
+}
+
+// TokenBucketSampler 创建按方法限速的采样器
+// 每个方法每秒最多记录perSecond条日志，采样率按上一秒该方法的调用量估算
+//
+// 参数:
+//   - perSecond: 每个方法每秒最多记录的日志条数
+//
+// 返回值:
+//   - Sampler: 采样器
+func TokenBucketSampler(perSecond int) Sampler {
+	return &tokenBucketSampler{
+		perSecond: float64(perSecond),
+		buckets:   make(map[string]*tokenBucket),
+	}
+}
+
+// AlwaysSampleErrorsAndSlow 创建对错误和慢调用始终记录的采样器
+// 非OK状态或耗时超过阈值的调用以采样率1记录，其余调用交给next决策
+//
+// 参数:
+//   - slowThreshold: 慢调用阈值，小于等于0表示不按耗时判断
+//   - next: 其余调用使用的采样器，为nil时其余调用全部记录
+//
+// 返回值:
+//   - Sampler: 采样器
+func AlwaysSampleErrorsAndSlow(slowThreshold time.Duration, next Sampler) Sampler {
+	if next == nil {
+		next = FixedRateSampler(1)
+	}
+	return SamplerFunc(func(ctx context.Context, call CallInfo) (bool, float64) {
+		if status.Code(call.Err) != codes.OK {
+			return true, 1
+		}
+		if slowThreshold > 0 && call.Latency > slowThreshold {
+			return true, 1
+		}
+		return next.Sample(ctx, call)
+	})
+}
+
+// sampleRate 按比例随机采样
+func sampleRate(rate float64) (bool, float64) {
+	switch {
+	case rate >= 1:
+		return true, 1
+	case rate <= 0:
+		return false, 0
+	default:
+		return rand.Float64() < rate, rate
+	}
+}
+
+// tokenBucketSampler 按方法限速的采样器
+type tokenBucketSampler struct {
+	perSecond float64
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+}
+
+// tokenBucket 单个方法的令牌桶及调用量统计
+type tokenBucket struct {
+	// tokens 当前可用的令牌数
+	tokens float64
+	// last 上次补充令牌的时间
+	last time.Time
+	// windowStart 当前统计窗口的开始时间
+	windowStart time.Time
+	// seen 当前窗口内的调用次数
+	seen int64
+	// prevSeen 上一个窗口内的调用次数
+	prevSeen int64
+}
+
+// Sample 实现Sampler接口
+func (s *tokenBucketSampler) Sample(ctx context.Context, call CallInfo) (bool, float64) {
+	if s.perSecond <= 0 {
+		return false, 0
+	}
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.buckets[call.FullMethod]
+	if !ok {
+		b = &tokenBucket{tokens: s.perSecond, last: now, windowStart: now}
+		s.buckets[call.FullMethod] = b
+	}
+
+	// 按流逝时间补充令牌，最多积累一秒的量
+	b.tokens += now.Sub(b.last).Seconds() * s.perSecond
+	if b.tokens > s.perSecond {
+		b.tokens = s.perSecond
+	}
+	b.last = now
+
+	// 滚动一秒的统计窗口
+	if elapsed := now.Sub(b.windowStart); elapsed >= time.Second {
+		if elapsed >= 2*time.Second {
+			b.prevSeen = 0
+		} else {
+			b.prevSeen = b.seen
+		}
+		b.seen = 0
+		b.windowStart = now
+	}
+	b.seen++
+
+	if b.tokens < 1 {
+		return false, 0
+	}
+	b.tokens--
+
+	// 按最近一秒的调用量估算采样率
+	observed := max(b.prevSeen, b.seen)
+	rate := s.perSecond / float64(observed)
+	if rate > 1 {
+		rate = 1
+	}
+	return true, rate
+}
+
+// sample 按配置的采样器决定是否记录日志
+//
+// 参数:
+//   - ctx: 请求上下文
+//   - fullMethod: 完整的方法名
+//   - err: 调用返回的错误
+//   - latency: 调用耗时
+//
+// 返回值:
+//   - float64: 采样率
+//   - bool: 是否记录日志
+func (o *options) sample(ctx context.Context, fullMethod string, err error, latency time.Duration) (float64, bool) {
+	if o.sampler == nil {
+		return 1, true
+	}
+	sampled, rate := o.sampler.Sample(ctx, CallInfo{FullMethod: fullMethod, Err: err, Latency: latency})
+	return rate, sampled
+}
+
+// appendSampleRate 配置了采样器时将采样率追加到日志字段中
+func (o *options) appendSampleRate(fields []slog.Attr, rate float64) []slog.Attr {
+	if o.sampler == nil {
+		return fields
+	}
+	return append(fields, slog.Float64("sample_rate", rate))
+}
@@ -0,0 +1,197 @@
+package accesslog
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFixedRateSampler(t *testing.T) {
+	tests := []struct {
+		name        string
+		rate        float64
+		wantSampled bool
+		wantRate    float64
+	}{
+		{"全部记录", 1, true, 1},
+		{"大于1按1处理", 2, true, 1},
+		{"全部丢弃", 0, false, 0},
+		{"负数按0处理", -1, false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sampled, rate := FixedRateSampler(tt.rate).Sample(context.Background(), CallInfo{FullMethod: "/test/method"})
+			assert.Equal(t, tt.wantSampled, sampled)
+			assert.Equal(t, tt.wantRate, rate)
+		})
+	}
+}
+
+func TestFixedRateSampler_Ratio(t *testing.T) {
+	sampler := FixedRateSampler(0.5)
+	sampledCount := 0
+	for i := 0; i < 10000; i++ {
+		sampled, rate := sampler.Sample(context.Background(), CallInfo{})
+		assert.Equal(t, 0.5, rate)
+		if sampled {
+			sampledCount++
+		}
+	}
+	assert.InDelta(t, 5000, sampledCount, 500)
+}
+
+func TestPerMethodSampler(t *testing.T) {
+	rates := map[string]float64{"/test/always": 1, "/test/never": 0}
+	sampler := PerMethodSampler(rates, 1)
+	// 修改原映射不影响采样器
+	rates["/test/other"] = 0
+
+	tests := []struct {
+		name        string
+		method      string
+		wantSampled bool
+	}{
+		{"配置为全部记录", "/test/always", true},
+		{"配置为全部丢弃", "/test/never", false},
+		{"未配置使用默认比例", "/test/other", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sampled, _ := sampler.Sample(context.Background(), CallInfo{FullMethod: tt.method})
+			assert.Equal(t, tt.wantSampled, sampled)
+		})
+	}
+}
+
+func TestTokenBucketSampler(t *testing.T) {
+	sampler := TokenBucketSampler(3)
+
+	var sampledCount int
+	var lastRate float64
+	for i := 0; i < 6; i++ {
+		sampled, rate := sampler.Sample(context.Background(), CallInfo{FullMethod: "/test/a"})
+		if sampled {
+			sampledCount++
+			lastRate = rate
+		}
+	}
+	assert.Equal(t, 3, sampledCount, "每秒最多记录3条")
+	assert.Equal(t, 1.0, lastRate, "桶未耗尽前调用量不超过上限")
+
+	// 不同方法使用独立的令牌桶
+	sampled, rate := sampler.Sample(context.Background(), CallInfo{FullMethod: "/test/b"})
+	assert.True(t, sampled)
+	assert.Equal(t, 1.0, rate)
+}
+
+func TestTokenBucketSampler_Rate(t *testing.T) {
+	s := TokenBucketSampler(2).(*tokenBucketSampler)
+	now := time.Now()
+	// 模拟上一秒有8次调用且令牌已补满
+	s.buckets["/test/a"] = &tokenBucket{
+		tokens:      2,
+		last:        now,
+		windowStart: now.Add(-1500 * time.Millisecond),
+		seen:        8,
+	}
+
+	sampled, rate := s.Sample(context.Background(), CallInfo{FullMethod: "/test/a"})
+	require.True(t, sampled)
+	assert.Equal(t, 0.25, rate)
+}
+
+func TestTokenBucketSampler_Disabled(t *testing.T) {
+	sampled, rate := TokenBucketSampler(0).Sample(context.Background(), CallInfo{FullMethod: "/test/a"})
+	assert.False(t, sampled)
+	assert.Equal(t, 0.0, rate)
+}
+
+func TestAlwaysSampleErrorsAndSlow(t *testing.T) {
+	sampler := AlwaysSampleErrorsAndSlow(time.Second, FixedRateSampler(0))
+
+	tests := []struct {
+		name        string
+		call        CallInfo
+		wantSampled bool
+		wantRate    float64
+	}{
+		{"正常调用交给下一个采样器", CallInfo{Latency: time.Millisecond}, false, 0},
+		{"错误调用始终记录", CallInfo{Err: status.Error(codes.Internal, "boom")}, true, 1},
+		{"慢调用始终记录", CallInfo{Latency: 2 * time.Second}, true, 1},
+		{"OK状态的错误不视为失败", CallInfo{Err: status.Error(codes.OK, "")}, false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sampled, rate := sampler.Sample(context.Background(), tt.call)
+			assert.Equal(t, tt.wantSampled, sampled)
+			assert.Equal(t, tt.wantRate, rate)
+		})
+	}
+}
+
+func TestAlwaysSampleErrorsAndSlow_NilNext(t *testing.T) {
+	sampler := AlwaysSampleErrorsAndSlow(time.Second, nil)
+
+	sampled, rate := sampler.Sample(context.Background(), CallInfo{Latency: time.Millisecond})
+	assert.True(t, sampled, "未配置下一个采样器时其余调用全部记录")
+	assert.Equal(t, 1.0, rate)
+}
+
+func TestUnaryServerInterceptor_Sampler(t *testing.T) {
+	tests := []struct {
+		name       string
+		sampler    Sampler
+		wantLogged bool
+		wantRate   any
+	}{
+		{"未配置采样器不记录采样率", nil, true, nil},
+		{"采样命中记录采样率", FixedRateSampler(1), true, 1.0},
+		{"采样未命中不记录日志", FixedRateSampler(0), false, nil},
+		{
+			name: "采样器收到调用信息",
+			sampler: SamplerFunc(func(ctx context.Context, call CallInfo) (bool, float64) {
+				return call.FullMethod == "/test/method" && status.Code(call.Err) == codes.NotFound, 0.1
+			}),
+			wantLogged: true,
+			wantRate:   0.1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := captureDefaultLogger(t)
+			interceptor := UnaryServerInterceptor(WithSampler(tt.sampler))
+			handler := func(ctx context.Context, req any) (any, error) {
+				return nil, status.Error(codes.NotFound, "missing")
+			}
+
+			_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test/method"}, handler)
+			assert.Equal(t, codes.NotFound, status.Code(err))
+
+			if !tt.wantLogged {
+				assert.Empty(t, buf.String())
+				return
+			}
+			record := decodeRecord(t, buf)
+			assert.Equal(t, tt.wantRate, record["sample_rate"])
+		})
+	}
+}
+
+func TestStreamClientInterceptor_Sampler(t *testing.T) {
+	buf := captureDefaultLogger(t)
+	interceptor := StreamClientInterceptor(WithSampler(FixedRateSampler(0)))
+
+	_, err := interceptor(context.Background(), &grpc.StreamDesc{}, nil, "/test/method", mockStreamer(nil, status.Error(codes.Unavailable, "down")))
+	require.Error(t, err)
+	assert.Empty(t, buf.String())
+}
@@ -0,0 +1,240 @@
+package accesslog
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/soyacen/gox/slogx"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// FieldSchema 访问日志基础字段的命名规范
+// 只影响调用本身的字段（时间、耗时、状态、地址等），
+// 流统计、元数据和请求响应内容等字段在各规范下保持一致
+type FieldSchema int
+
+const (
+	// SchemaLegacy 原有的字段布局：system、timestamp、latency、status、error、peer、deadline
+	SchemaLegacy FieldSchema = iota
+	// SchemaOTel OpenTelemetry语义约定：rpc.system、rpc.service、rpc.method、
+	// rpc.grpc.status_code、server.address、client.address及以毫秒为单位的duration_ms
+	SchemaOTel
+	// SchemaECS Elastic Common Schema：@timestamp、event.*、source.*/destination.*、
+	// error.*，gRPC相关信息位于grpc.*下
+	SchemaECS
+)
+
+// 日志记录端
+const (
+	systemServer = "grpc.server"
+	systemClient = "grpc.client"
+)
+
+// callFields 一次调用的基础信息
+type callFields struct {
+	// system 记录端，grpc.server或grpc.client
+	system string
+	// fullMethod 完整的方法名
+	fullMethod string
+	// target 客户端连接的目标地址，服务端为空
+	target string
+	// startTime 调用开始时间
+	startTime time.Time
+	// latency 调用耗时
+	latency time.Duration
+	// err 调用返回的错误
+	err error
+}
+
+// splitMethod 将完整的方法名拆分为服务名和方法名
+//
+// 参数:
+//   - fullMethod: 完整的方法名，格式为"/package.Service/Method"
+//
+// 返回值:
+//   - service: 服务名
+//   - method: 方法名
+func splitMethod(fullMethod string) (service, method string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+	return "", fullMethod
+}
+
+// splitAddr 将网络地址拆分为主机和端口，无法拆分时端口为0
+func splitAddr(addr string) (string, int) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr, 0
+	}
+	port, _ := strconv.Atoi(portStr)
+	return host, port
+}
+
+// appendAddrAttrs 追加"<prefix>.address"和"<prefix>.port"字段
+func appendAddrAttrs(fields []slog.Attr, prefix string, addr string) []slog.Attr {
+	if addr == "" {
+		return fields
+	}
+	host, port := splitAddr(addr)
+	fields = append(fields, slog.String(prefix+".address", host))
+	if port > 0 {
+		fields = append(fields, slog.Int(prefix+".port", port))
+	}
+	return fields
+}
+
+// peerAddrs 返回对端地址和本端地址
+func peerAddrs(ctx context.Context) (remote, local string) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", ""
+	}
+	if p.Addr != nil {
+		remote = p.Addr.String()
+	}
+	if p.LocalAddr != nil {
+		local = p.LocalAddr.String()
+	}
+	return remote, local
+}
+
+// appendCallAttrs 按配置的字段规范追加调用的基础字段
+//
+// 参数:
+//   - ctx: 请求上下文
+//   - fields: 已有的日志字段
+//   - call: 调用的基础信息
+//
+// 返回值:
+//   - []slog.Attr: 追加后的日志字段
+func (o *options) appendCallAttrs(ctx context.Context, fields []slog.Attr, call callFields) []slog.Attr {
+	switch o.schema {
+	case SchemaOTel:
+		return appendOTelAttrs(ctx, fields, call)
+	case SchemaECS:
+		return appendECSAttrs(ctx, fields, call)
+	default:
+		return appendLegacyAttrs(ctx, fields, call)
+	}
+}
+
+// appendLegacyAttrs 追加原有布局的基础字段
+func appendLegacyAttrs(ctx context.Context, fields []slog.Attr, call callFields) []slog.Attr {
+	fields = append(fields,
+		slog.String("system", call.system),
+		slog.String("timestamp", call.startTime.Format(time.RFC3339)),
+		slog.String("latency", call.latency.String()),
+		slogx.Uint("status", status.Code(call.err)),
+		slogx.Error("error", call.err),
+	)
+	if p, ok := peer.FromContext(ctx); ok {
+		fields = append(fields, slog.String("peer", p.Addr.String()))
+	}
+	if d, ok := ctx.Deadline(); ok {
+		fields = append(fields, slog.String("deadline", d.Format(time.RFC3339)))
+	}
+	return fields
+}
+
+// appendOTelAttrs 追加符合OpenTelemetry语义约定的基础字段
+func appendOTelAttrs(ctx context.Context, fields []slog.Attr, call callFields) []slog.Attr {
+	service, method := splitMethod(call.fullMethod)
+	code := status.Code(call.err)
+	fields = append(fields,
+		slog.String("timestamp", call.startTime.Format(time.RFC3339Nano)),
+		slog.String("rpc.system", "grpc"),
+		slog.String("rpc.service", service),
+		slog.String("rpc.method", method),
+		slog.Int("rpc.grpc.status_code", int(code)),
+		slog.Float64("duration_ms", durationMillis(call.latency)),
+	)
+	if code != codes.OK {
+		fields = append(fields, slog.String("error.type", code.String()))
+	}
+	if call.err != nil {
+		fields = append(fields, slog.String("exception.message", call.err.Error()))
+	}
+	remote, local := peerAddrs(ctx)
+	if call.system == systemServer {
+		fields = appendAddrAttrs(fields, "client", remote)
+		fields = appendAddrAttrs(fields, "server", local)
+	} else {
+		// 客户端优先使用实际连接的地址，否则使用连接目标
+		if remote == "" {
+			remote = call.target
+		}
+		fields = appendAddrAttrs(fields, "server", remote)
+	}
+	if d, ok := ctx.Deadline(); ok {
+		fields = append(fields, slog.String("deadline", d.Format(time.RFC3339Nano)))
+	}
+	return fields
+}
+
+// appendECSAttrs 追加符合Elastic Common Schema的基础字段
+func appendECSAttrs(ctx context.Context, fields []slog.Attr, call callFields) []slog.Attr {
+	service, method := splitMethod(call.fullMethod)
+	code := status.Code(call.err)
+	outcome := "success"
+	if code != codes.OK {
+		outcome = "failure"
+	}
+	fields = append(fields,
+		slog.String("@timestamp", call.startTime.Format(time.RFC3339Nano)),
+		slog.String("event.kind", "event"),
+		slog.String("event.dataset", call.system),
+		slog.String("event.outcome", outcome),
+		// ECS的event.duration以纳秒为单位
+		slog.Int64("event.duration", call.latency.Nanoseconds()),
+		slog.String("grpc.service", service),
+		slog.String("grpc.method", method),
+		slog.Int("grpc.status_code", int(code)),
+	)
+	if call.err != nil {
+		fields = append(fields,
+			slog.String("error.code", code.String()),
+			slog.String("error.message", call.err.Error()),
+		)
+	}
+	remote, local := peerAddrs(ctx)
+	if call.system == systemServer {
+		fields = appendAddrAttrs(fields, "source", remote)
+		fields = appendAddrAttrs(fields, "destination", local)
+	} else {
+		if remote == "" {
+			remote = call.target
+		}
+		fields = appendAddrAttrs(fields, "destination", remote)
+	}
+	if d, ok := ctx.Deadline(); ok {
+		fields = append(fields, slog.String("grpc.deadline", d.Format(time.RFC3339Nano)))
+	}
+	return fields
+}
+
+// target 返回客户端连接的目标地址，连接为nil时返回空字符串
+// 形如"dns:///example.com:443"的目标只保留末尾的主机和端口
+func target(cc *grpc.ClientConn) string {
+	if cc == nil {
+		return ""
+	}
+	t := cc.Target()
+	if strings.Contains(t, "://") {
+		t = t[strings.LastIndex(t, "/")+1:]
+	}
+	return t
+}
+
+// durationMillis 将耗时转换为毫秒，保留小数部分
+func durationMillis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,161 @@
+package accesslog
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestSplitMethod(t *testing.T) {
+	tests := []struct {
+		name        string
+		fullMethod  string
+		wantService string
+		wantMethod  string
+	}{
+		{"标准格式", "/helloworld.Greeter/SayHello", "helloworld.Greeter", "SayHello"},
+		{"无前导斜杠", "helloworld.Greeter/SayHello", "helloworld.Greeter", "SayHello"},
+		{"只有方法名", "SayHello", "", "SayHello"},
+		{"空字符串", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, method := splitMethod(tt.fullMethod)
+			assert.Equal(t, tt.wantService, service)
+			assert.Equal(t, tt.wantMethod, method)
+		})
+	}
+}
+
+// schemaTestContext 构造带有对端地址和截止时间的上下文
+func schemaTestContext(t *testing.T) context.Context {
+	t.Helper()
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		Addr:      &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5000},
+		LocalAddr: &net.TCPAddr{IP: net.ParseIP("10.0.0.2"), Port: 8080},
+	})
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestUnaryServerInterceptor_FieldSchema(t *testing.T) {
+	handler := func(ctx context.Context, req any) (any, error) {
+		return nil, status.Error(codes.NotFound, "missing")
+	}
+
+	tests := []struct {
+		name       string
+		schema     FieldSchema
+		wantFields map[string]any
+		wantAbsent []string
+	}{
+		{
+			name:   "legacy",
+			schema: SchemaLegacy,
+			wantFields: map[string]any{
+				"system": "grpc.server",
+				"status": float64(codes.NotFound),
+				"peer":   "10.0.0.1:5000",
+			},
+			wantAbsent: []string{"rpc.system", "@timestamp"},
+		},
+		{
+			name:   "otel",
+			schema: SchemaOTel,
+			wantFields: map[string]any{
+				"rpc.system":           "grpc",
+				"rpc.service":          "helloworld.Greeter",
+				"rpc.method":           "SayHello",
+				"rpc.grpc.status_code": float64(codes.NotFound),
+				"error.type":           "NotFound",
+				"exception.message":    "rpc error: code = NotFound desc = missing",
+				"client.address":       "10.0.0.1",
+				"client.port":          float64(5000),
+				"server.address":       "10.0.0.2",
+				"server.port":          float64(8080),
+			},
+			wantAbsent: []string{"system", "latency", "status", "peer"},
+		},
+		{
+			name:   "ecs",
+			schema: SchemaECS,
+			wantFields: map[string]any{
+				"event.kind":          "event",
+				"event.dataset":       "grpc.server",
+				"event.outcome":       "failure",
+				"grpc.service":        "helloworld.Greeter",
+				"grpc.method":         "SayHello",
+				"grpc.status_code":    float64(codes.NotFound),
+				"error.code":          "NotFound",
+				"source.address":      "10.0.0.1",
+				"destination.address": "10.0.0.2",
+			},
+			wantAbsent: []string{"system", "latency", "status", "timestamp"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := captureDefaultLogger(t)
+			interceptor := UnaryServerInterceptor(WithFieldSchema(tt.schema))
+			_, _ = interceptor(schemaTestContext(t), nil, &grpc.UnaryServerInfo{FullMethod: "/helloworld.Greeter/SayHello"}, handler)
+
+			record := decodeRecord(t, buf)
+			for k, v := range tt.wantFields {
+				assert.Equal(t, v, record[k], k)
+			}
+			for _, k := range tt.wantAbsent {
+				assert.NotContains(t, record, k)
+			}
+		})
+	}
+}
+
+func TestOTelSchema_DurationAndTimestamp(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 123456789, time.UTC)
+	o := defaultOptions().apply(WithFieldSchema(SchemaOTel))
+	fields := o.appendCallAttrs(context.Background(), nil, callFields{
+		system:     systemClient,
+		fullMethod: "/helloworld.Greeter/SayHello",
+		target:     "example.com:443",
+		startTime:  start,
+		latency:    1500 * time.Microsecond,
+	})
+
+	values := map[string]any{}
+	for _, f := range fields {
+		values[f.Key] = f.Value.Any()
+	}
+	assert.Equal(t, "2024-01-02T03:04:05.123456789Z", values["timestamp"])
+	assert.Equal(t, 1.5, values["duration_ms"])
+	assert.Equal(t, "example.com", values["server.address"])
+	assert.Equal(t, int64(443), values["server.port"])
+	assert.NotContains(t, values, "error.type")
+	assert.NotContains(t, values, "client.address")
+}
+
+func TestECSSchema_ClientSuccess(t *testing.T) {
+	buf := captureDefaultLogger(t)
+	interceptor := UnaryClientInterceptor(WithFieldSchema(SchemaECS))
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return nil
+	}
+	require.NoError(t, interceptor(context.Background(), "/helloworld.Greeter/SayHello", nil, nil, nil, invoker))
+
+	record := decodeRecord(t, buf)
+	assert.Equal(t, "grpc.client", record["event.dataset"])
+	assert.Equal(t, "success", record["event.outcome"])
+	assert.Contains(t, record, "@timestamp")
+	assert.Contains(t, record, "event.duration")
+	assert.NotContains(t, record, "error.message")
+}
@@ -0,0 +1,254 @@
+package accesslog
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// streamStats 记录流式调用的消息与字节统计
+// SendMsg 与 RecvMsg 可能在不同的goroutine中并发调用，因此所有字段均为原子类型
+type streamStats struct {
+	// startTime 流开始时间
+	startTime time.Time
+	// sentMessages 已发送的消息数量
+	sentMessages atomic.Int64
+	// recvMessages 已接收的消息数量
+	recvMessages atomic.Int64
+	// sentMessageBytes 已发送消息的序列化字节数，不含消息头且未压缩，线上字节数见wire_sent_bytes
+	sentMessageBytes atomic.Int64
+	// recvMessageBytes 已接收消息的序列化字节数，不含消息头且未压缩，线上字节数见wire_recv_bytes
+	recvMessageBytes atomic.Int64
+	// firstMessage 第一条消息的时间（UnixNano），0表示尚无消息
+	firstMessage atomic.Int64
+	// lastMessage 最后一条消息的时间（UnixNano），0表示尚无消息
+	lastMessage atomic.Int64
+}
+
+// newStreamStats 创建流统计对象
+//
+// 参数:
+//   - startTime: 流开始时间
+//
+// 返回值:
+//   - *streamStats: 流统计对象
+func newStreamStats(startTime time.Time) *streamStats {
+	return &streamStats{startTime: startTime}
+}
+
+// onSend 记录一条已发送的消息
+func (s *streamStats) onSend(m any) {
+	s.sentMessages.Add(1)
+	s.sentMessageBytes.Add(int64(messageSize(m)))
+	s.touch()
+}
+
+// onRecv 记录一条已接收的消息
+func (s *streamStats) onRecv(m any) {
+	s.recvMessages.Add(1)
+	s.recvMessageBytes.Add(int64(messageSize(m)))
+	s.touch()
+}
+
+// touch 更新第一条和最后一条消息的时间
+func (s *streamStats) touch() {
+	now := time.Now().UnixNano()
+	s.firstMessage.CompareAndSwap(0, now)
+	s.lastMessage.Store(now)
+}
+
+// appendAttrs 将流统计字段追加到日志字段中
+//
+// 参数:
+//   - fields: 已有的日志字段
+//
+// 返回值:
+//   - []slog.Attr: 追加后的日志字段
+func (s *streamStats) appendAttrs(fields []slog.Attr) []slog.Attr {
+	fields = append(fields,
+		slog.Int64("sent_messages", s.sentMessages.Load()),
+		slog.Int64("recv_messages", s.recvMessages.Load()),
+		slog.Int64("sent_message_bytes", s.sentMessageBytes.Load()),
+		slog.Int64("recv_message_bytes", s.recvMessageBytes.Load()),
+	)
+	if first := s.firstMessage.Load(); first != 0 {
+		fields = append(fields,
+			slog.String("time_to_first_message", time.Unix(0, first).Sub(s.startTime).String()),
+			slog.String("time_to_last_message", time.Unix(0, s.lastMessage.Load()).Sub(s.startTime).String()),
+		)
+	}
+	return fields
+}
+
+// messageSize 返回消息序列化后的字节数
+// 非protobuf消息无法计算大小，返回0
+func messageSize(m any) int {
+	if msg, ok := m.(proto.Message); ok {
+		return proto.Size(msg)
+	}
+	return 0
+}
+
+// wrappedServerStream 包装 grpc.ServerStream 以统计收发的消息
+// 并在需要时记录响应头和trailer
+type wrappedServerStream struct {
+	grpc.ServerStream
+	stats *streamStats
+	// ctx 替换后的上下文，为nil时使用原始流的上下文
+	ctx context.Context
+	// recorder 响应元数据记录器，为nil时不记录
+	recorder *metadataRecorder
+}
+
+// Context 返回流的上下文
+func (w *wrappedServerStream) Context() context.Context {
+	if w.ctx != nil {
+		return w.ctx
+	}
+	return w.ServerStream.Context()
+}
+
+// SetHeader 设置响应头并记录
+func (w *wrappedServerStream) SetHeader(md metadata.MD) error {
+	err := w.ServerStream.SetHeader(md)
+	if err == nil && w.recorder != nil {
+		w.recorder.addHeader(md)
+	}
+	return err
+}
+
+// SendHeader 发送响应头并记录
+func (w *wrappedServerStream) SendHeader(md metadata.MD) error {
+	err := w.ServerStream.SendHeader(md)
+	if err == nil && w.recorder != nil {
+		w.recorder.addHeader(md)
+	}
+	return err
+}
+
+// SetTrailer 设置trailer并记录
+func (w *wrappedServerStream) SetTrailer(md metadata.MD) {
+	w.ServerStream.SetTrailer(md)
+	if w.recorder != nil {
+		w.recorder.addTrailer(md)
+	}
+}
+
+// SendMsg 发送消息并记录统计
+func (w *wrappedServerStream) SendMsg(m any) error {
+	err := w.ServerStream.SendMsg(m)
+	if err == nil {
+		w.stats.onSend(m)
+	}
+	return err
+}
+
+// RecvMsg 接收消息并记录统计
+func (w *wrappedServerStream) RecvMsg(m any) error {
+	err := w.ServerStream.RecvMsg(m)
+	if err == nil {
+		w.stats.onRecv(m)
+	}
+	return err
+}
+
+// wrappedClientStream 包装 grpc.ClientStream 以统计收发的消息
+// 并在流结束时（RecvMsg返回io.EOF或错误、SendMsg或CloseSend返回错误、上下文取消）调用一次onFinish
+type wrappedClientStream struct {
+	grpc.ClientStream
+	stats *streamStats
+	// unaryResponse 服务端是否只返回一条消息，此时收到该消息即表示流结束
+	unaryResponse bool
+	// onFinish 流结束时的回调，参数为流的最终错误
+	onFinish func(err error)
+	// finishOnce 保证onFinish只被调用一次
+	finishOnce sync.Once
+	// stopWatch 取消上下文取消时的回调
+	stopWatch func() bool
+}
+
+// newWrappedClientStream 创建包装后的客户端流，并通过context.AfterFunc监听上下文取消，不启动协程
+//
+// 参数:
+//   - ctx: 调用上下文，取消时流视为结束
+//   - stream: 原始的gRPC客户端流
+//   - desc: 流描述信息
+//   - stats: 流统计对象
+//   - onFinish: 流结束时的回调
+//
+// 返回值:
+//   - *wrappedClientStream: 包装后的客户端流
+func newWrappedClientStream(ctx context.Context, stream grpc.ClientStream, desc *grpc.StreamDesc, stats *streamStats, onFinish func(err error)) *wrappedClientStream {
+	w := &wrappedClientStream{
+		ClientStream:  stream,
+		stats:         stats,
+		unaryResponse: desc != nil && !desc.ServerStreams,
+		onFinish:      onFinish,
+	}
+	// 上下文已取消时回调可能在赋值stopWatch之前执行，因此回调中不调用finish
+	w.stopWatch = context.AfterFunc(ctx, func() {
+		w.report(status.FromContextError(ctx.Err()).Err())
+	})
+	return w
+}
+
+// finish 标记流结束，取消上下文回调并调用onFinish，多次调用只生效一次
+func (w *wrappedClientStream) finish(err error) {
+	w.stopWatch()
+	w.report(err)
+}
+
+// report 调用onFinish，多次调用只生效一次
+func (w *wrappedClientStream) report(err error) {
+	w.finishOnce.Do(func() {
+		w.onFinish(err)
+	})
+}
+
+// SendMsg 发送消息并记录统计
+// 返回io.EOF时流的最终状态需要由RecvMsg获取，因此不结束流
+func (w *wrappedClientStream) SendMsg(m any) error {
+	err := w.ClientStream.SendMsg(m)
+	switch err {
+	case nil:
+		w.stats.onSend(m)
+	case io.EOF:
+	default:
+		w.finish(err)
+	}
+	return err
+}
+
+// CloseSend 关闭发送方向，返回错误时流结束
+func (w *wrappedClientStream) CloseSend() error {
+	err := w.ClientStream.CloseSend()
+	if err != nil {
+		w.finish(err)
+	}
+	return err
+}
+
+// RecvMsg 接收消息并记录统计，io.EOF或错误表示流结束
+func (w *wrappedClientStream) RecvMsg(m any) error {
+	err := w.ClientStream.RecvMsg(m)
+	switch err {
+	case nil:
+		w.stats.onRecv(m)
+		if w.unaryResponse {
+			w.finish(nil)
+		}
+	case io.EOF:
+		w.finish(nil)
+	default:
+		w.finish(err)
+	}
+	return err
+}
@@ -0,0 +1,342 @@
+package accesslog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// captureDefaultLogger 将默认日志替换为JSON输出，测试结束后恢复
+func captureDefaultLogger(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	old := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { slog.SetDefault(old) })
+	return buf
+}
+
+// decodeRecord 解析一条JSON日志记录
+func decodeRecord(t *testing.T, buf *bytes.Buffer) map[string]any {
+	t.Helper()
+	record := map[string]any{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	return record
+}
+
+type mockClientStream struct {
+	ctx          context.Context
+	sendErr      error
+	recvErr      error
+	closeSendErr error
+}
+
+func (m *mockClientStream) Header() (metadata.MD, error) { return nil, nil }
+func (m *mockClientStream) Trailer() metadata.MD         { return nil }
+func (m *mockClientStream) CloseSend() error             { return m.closeSendErr }
+func (m *mockClientStream) Context() context.Context     { return m.ctx }
+func (m *mockClientStream) SendMsg(msg any) error        { return m.sendErr }
+func (m *mockClientStream) RecvMsg(msg any) error        { return m.recvErr }
+
+func TestStreamStats(t *testing.T) {
+	msg := wrapperspb.String("hello")
+	size := int64(proto.Size(msg))
+
+	tests := []struct {
+		name      string
+		sends     []any
+		recvs     []any
+		wantSent  int64
+		wantRecv  int64
+		wantSentB int64
+		wantRecvB int64
+		wantFirst bool
+	}{
+		{
+			name:      "无消息",
+			wantFirst: false,
+		},
+		{
+			name:      "protobuf消息统计字节数",
+			sends:     []any{msg, msg},
+			recvs:     []any{msg},
+			wantSent:  2,
+			wantRecv:  1,
+			wantSentB: 2 * size,
+			wantRecvB: size,
+			wantFirst: true,
+		},
+		{
+			name:      "非protobuf消息只统计数量",
+			sends:     []any{"plain"},
+			wantSent:  1,
+			wantFirst: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStreamStats(time.Now())
+			for _, m := range tt.sends {
+				s.onSend(m)
+			}
+			for _, m := range tt.recvs {
+				s.onRecv(m)
+			}
+
+			attrs := map[string]slog.Value{}
+			for _, a := range s.appendAttrs(nil) {
+				attrs[a.Key] = a.Value
+			}
+			assert.Equal(t, tt.wantSent, attrs["sent_messages"].Int64())
+			assert.Equal(t, tt.wantRecv, attrs["recv_messages"].Int64())
+			assert.Equal(t, tt.wantSentB, attrs["sent_message_bytes"].Int64())
+			assert.Equal(t, tt.wantRecvB, attrs["recv_message_bytes"].Int64())
+			_, hasFirst := attrs["time_to_first_message"]
+			_, hasLast := attrs["time_to_last_message"]
+			assert.Equal(t, tt.wantFirst, hasFirst)
+			assert.Equal(t, tt.wantFirst, hasLast)
+		})
+	}
+}
+
+func TestStreamStats_Concurrent(t *testing.T) {
+	s := newStreamStats(time.Now())
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(2)
+		go func() { defer wg.Done(); s.onSend(nil) }()
+		go func() { defer wg.Done(); s.onRecv(nil) }()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(100), s.sentMessages.Load())
+	assert.Equal(t, int64(100), s.recvMessages.Load())
+}
+
+func TestWrappedServerStream(t *testing.T) {
+	tests := []struct {
+		name     string
+		sendErr  error
+		recvErr  error
+		wantSent int64
+		wantRecv int64
+	}{
+		{
+			name:     "成功收发计数",
+			wantSent: 1,
+			wantRecv: 1,
+		},
+		{
+			name:     "失败收发不计数",
+			sendErr:  errors.New("send error"),
+			recvErr:  errors.New("recv error"),
+			wantSent: 0,
+			wantRecv: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStreamStats(time.Now())
+			w := &wrappedServerStream{
+				ServerStream: &mockServerStreamWithErr{mockServerStream: mockServerStream{ctx: context.Background()}, sendErr: tt.sendErr, recvErr: tt.recvErr},
+				stats:        s,
+			}
+			assert.Equal(t, tt.sendErr, w.SendMsg(wrapperspb.String("a")))
+			assert.Equal(t, tt.recvErr, w.RecvMsg(wrapperspb.String("b")))
+			assert.Equal(t, tt.wantSent, s.sentMessages.Load())
+			assert.Equal(t, tt.wantRecv, s.recvMessages.Load())
+		})
+	}
+}
+
+func TestWrappedClientStream(t *testing.T) {
+	tests := []struct {
+		name       string
+		desc       *grpc.StreamDesc
+		sendErr    error
+		recvErr    error
+		wantFinish bool
+		wantErr    error
+		wantSent   int64
+		wantRecv   int64
+	}{
+		{
+			name:       "服务端流消息接收成功不结束",
+			desc:       &grpc.StreamDesc{ServerStreams: true},
+			wantFinish: false,
+			wantSent:   1,
+			wantRecv:   1,
+		},
+		{
+			name:       "服务端单条响应接收成功即结束",
+			desc:       &grpc.StreamDesc{ClientStreams: true},
+			wantFinish: true,
+			wantErr:    nil,
+			wantSent:   1,
+			wantRecv:   1,
+		},
+		{
+			name:       "RecvMsg返回io.EOF正常结束",
+			desc:       &grpc.StreamDesc{ServerStreams: true},
+			recvErr:    io.EOF,
+			wantFinish: true,
+			wantErr:    nil,
+			wantSent:   1,
+		},
+		{
+			name:       "RecvMsg返回错误以该错误结束",
+			desc:       &grpc.StreamDesc{ServerStreams: true},
+			recvErr:    status.Error(codes.Unavailable, "unavailable"),
+			wantFinish: true,
+			wantErr:    status.Error(codes.Unavailable, "unavailable"),
+			wantSent:   1,
+		},
+		{
+			name:       "SendMsg返回io.EOF不结束",
+			desc:       &grpc.StreamDesc{ServerStreams: true},
+			sendErr:    io.EOF,
+			wantFinish: false,
+			wantRecv:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			s := newStreamStats(time.Now())
+			var finishCount int
+			var finishErr error
+			w := newWrappedClientStream(ctx, &mockClientStream{ctx: ctx, sendErr: tt.sendErr, recvErr: tt.recvErr}, tt.desc, s, func(err error) {
+				finishCount++
+				finishErr = err
+			})
+
+			_ = w.SendMsg(wrapperspb.String("a"))
+			_ = w.RecvMsg(wrapperspb.String("b"))
+			// 再次接收不会重复结束
+			_ = w.RecvMsg(wrapperspb.String("c"))
+
+			if tt.wantFinish {
+				assert.Equal(t, 1, finishCount)
+				assert.Equal(t, tt.wantErr, finishErr)
+			} else {
+				assert.Equal(t, 0, finishCount)
+			}
+			assert.Equal(t, tt.wantSent, s.sentMessages.Load())
+			assert.GreaterOrEqual(t, s.recvMessages.Load(), tt.wantRecv)
+		})
+	}
+}
+
+func TestWrappedClientStream_ContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	finished := make(chan error, 1)
+	newWrappedClientStream(ctx, &mockClientStream{ctx: ctx}, &grpc.StreamDesc{ServerStreams: true}, newStreamStats(time.Now()), func(err error) {
+		finished <- err
+	})
+
+	cancel()
+
+	select {
+	case err := <-finished:
+		assert.Equal(t, codes.Canceled, status.Code(err))
+	case <-time.After(time.Second):
+		t.Fatal("上下文取消后未结束流")
+	}
+}
+
+func TestWrappedClientStream_NoGoroutine(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	before := runtime.NumGoroutine()
+	streams := make([]*wrappedClientStream, 0, 100)
+	for i := 0; i < 100; i++ {
+		streams = append(streams, newWrappedClientStream(ctx, &mockClientStream{ctx: ctx}, &grpc.StreamDesc{ServerStreams: true}, newStreamStats(time.Now()), func(err error) {}))
+	}
+	assert.Less(t, runtime.NumGoroutine()-before, len(streams), "未结束的流不应各自占用一个协程")
+}
+
+func TestWrappedClientStream_CloseSendError(t *testing.T) {
+	var finished []error
+	w := newWrappedClientStream(context.Background(), &mockClientStream{ctx: context.Background(), closeSendErr: io.ErrClosedPipe}, &grpc.StreamDesc{ServerStreams: true}, newStreamStats(time.Now()), func(err error) {
+		finished = append(finished, err)
+	})
+
+	assert.Equal(t, io.ErrClosedPipe, w.CloseSend())
+	assert.Equal(t, []error{io.ErrClosedPipe}, finished)
+}
+
+func TestStreamClientInterceptor_LogOnFinish(t *testing.T) {
+	buf := captureDefaultLogger(t)
+	interceptor := StreamClientInterceptor()
+	mockStream := &mockClientStream{ctx: context.Background(), recvErr: status.Error(codes.Internal, "boom")}
+	streamer := mockStreamer(mockStream, nil)
+
+	stream, err := interceptor(context.Background(), &grpc.StreamDesc{ServerStreams: true}, nil, "/test/method", streamer)
+	require.NoError(t, err)
+	assert.Empty(t, buf.String(), "流创建时不应记录日志")
+
+	time.Sleep(10 * time.Millisecond)
+	require.Error(t, stream.RecvMsg(wrapperspb.String("resp")))
+
+	record := decodeRecord(t, buf)
+	assert.Equal(t, float64(codes.Internal), record["status"])
+	assert.Equal(t, "/test/method", record["msg"])
+	latency, err := time.ParseDuration(record["latency"].(string))
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, latency, 10*time.Millisecond)
+}
+
+func TestStreamServerInterceptor_StreamStats(t *testing.T) {
+	buf := captureDefaultLogger(t)
+	interceptor := StreamServerInterceptor()
+	info := &grpc.StreamServerInfo{FullMethod: "/test/method"}
+	handler := func(srv any, stream grpc.ServerStream) error {
+		if err := stream.RecvMsg(wrapperspb.String("req")); err != nil {
+			return err
+		}
+		for i := 0; i < 3; i++ {
+			if err := stream.SendMsg(wrapperspb.String("resp")); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	err := interceptor(nil, &mockServerStream{ctx: context.Background()}, info, handler)
+	require.NoError(t, err)
+
+	record := decodeRecord(t, buf)
+	assert.Equal(t, float64(3), record["sent_messages"])
+	assert.Equal(t, float64(1), record["recv_messages"])
+	assert.Equal(t, float64(3*proto.Size(wrapperspb.String("resp"))), record["sent_message_bytes"])
+	assert.Equal(t, float64(proto.Size(wrapperspb.String("req"))), record["recv_message_bytes"])
+	assert.Contains(t, record, "time_to_first_message")
+}
+
+type mockServerStreamWithErr struct {
+	mockServerStream
+	sendErr error
+	recvErr error
+}
+
+func (m *mockServerStreamWithErr) SendMsg(msg any) error { return m.sendErr }
+func (m *mockServerStreamWithErr) RecvMsg(msg any) error { return m.recvErr }
@@ -0,0 +1,185 @@
+package accesslog
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/protobuf/proto"
+)
+
+// wireStats 由stats.Handler采集的一次调用的传输层信息
+type wireStats struct {
+	// sentBytes 发送消息的线上字节数（压缩后，含消息头）
+	sentBytes atomic.Int64
+	// recvBytes 接收消息的线上字节数（压缩后，含消息头）
+	recvBytes atomic.Int64
+
+	mu sync.Mutex
+	// inEncoding 收到的grpc-encoding
+	inEncoding string
+	// outEncoding 发送的grpc-encoding
+	outEncoding string
+}
+
+// wireStatsKey 传输层信息在上下文中的键，服务端和客户端分开存放，
+// 避免服务端处理函数使用收到的上下文发起客户端调用时两次调用的信息混在一起
+type wireStatsKey struct {
+	client bool
+}
+
+// withWireStats 返回带有新的传输层信息的上下文
+func withWireStats(ctx context.Context, client bool) (context.Context, *wireStats) {
+	ws := &wireStats{}
+	return context.WithValue(ctx, wireStatsKey{client: client}, ws), ws
+}
+
+// wireStatsFromContext 从上下文中获取服务端或客户端的传输层信息，没有配置StatsHandler时返回nil
+func wireStatsFromContext(ctx context.Context, client bool) *wireStats {
+	ws, _ := ctx.Value(wireStatsKey{client: client}).(*wireStats)
+	return ws
+}
+
+// encodings 返回收到和发送的grpc-encoding
+func (ws *wireStats) encodings() (in, out string) {
+	if ws == nil {
+		return "", ""
+	}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return ws.inEncoding, ws.outEncoding
+}
+
+// statsHandler 采集线上字节数和压缩算法的stats.Handler
+type statsHandler struct{}
+
+// NewStatsHandler 创建为访问日志采集传输层信息的stats.Handler
+// 服务端通过grpc.StatsHandler、客户端通过grpc.WithStatsHandler注册后，
+// 访问日志会记录线上收发字节数（wire_sent_bytes、wire_recv_bytes）和
+// 实际协商的压缩算法（request_encoding、response_encoding）
+//
+// 注意：服务端一元调用的响应在拦截器返回后才写出，因此服务端一元调用只能记录请求方向的线上字节数
+//
+// 返回值:
+//   - stats.Handler: gRPC统计处理器
+func NewStatsHandler() stats.Handler {
+	return statsHandler{}
+}
+
+// TagRPC 在服务端调用的上下文中放入传输层信息
+// 客户端调用的传输层信息由客户端拦截器放入，已放入时不再创建
+func (statsHandler) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	if wireStatsFromContext(ctx, true) != nil {
+		return ctx
+	}
+	ctx, _ = withWireStats(ctx, false)
+	return ctx
+}
+
+// HandleRPC 记录线上字节数和压缩算法
+func (statsHandler) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	ws := wireStatsFromContext(ctx, s.IsClient())
+	if ws == nil {
+		return
+	}
+	switch s := s.(type) {
+	case *stats.InHeader:
+		ws.mu.Lock()
+		ws.inEncoding = s.Compression
+		ws.mu.Unlock()
+	case *stats.OutHeader:
+		ws.mu.Lock()
+		ws.outEncoding = s.Compression
+		ws.mu.Unlock()
+	case *stats.InPayload:
+		ws.recvBytes.Add(int64(s.WireLength))
+	case *stats.OutPayload:
+		ws.sentBytes.Add(int64(s.WireLength))
+	}
+}
+
+// TagConn 实现stats.Handler接口
+func (statsHandler) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+// HandleConn 实现stats.Handler接口
+func (statsHandler) HandleConn(context.Context, stats.ConnStats) {}
+
+// appendSizeAttrs 追加一元调用请求和响应序列化后的大小，非proto消息不记录
+//
+// 参数:
+//   - fields: 已有的日志字段
+//   - req: 请求对象
+//   - resp: 响应对象
+//
+// 返回值:
+//   - []slog.Attr: 追加后的日志字段
+func appendSizeAttrs(fields []slog.Attr, req, resp any) []slog.Attr {
+	if m, ok := req.(proto.Message); ok {
+		fields = append(fields, slog.Int("request_size", proto.Size(m)))
+	}
+	if m, ok := resp.(proto.Message); ok {
+		fields = append(fields, slog.Int("response_size", proto.Size(m)))
+	}
+	return fields
+}
+
+// appendWireAttrs 追加压缩算法和线上字节数，未知的值不记录
+//
+// 参数:
+//   - fields: 已有的日志字段
+//   - ws: 传输层信息，可以为nil
+//   - requestEncoding: 请求的压缩算法
+//   - responseEncoding: 响应的压缩算法
+//
+// 返回值:
+//   - []slog.Attr: 追加后的日志字段
+func appendWireAttrs(fields []slog.Attr, ws *wireStats, requestEncoding, responseEncoding string) []slog.Attr {
+	if requestEncoding != "" {
+		fields = append(fields, slog.String("request_encoding", requestEncoding))
+	}
+	if responseEncoding != "" {
+		fields = append(fields, slog.String("response_encoding", responseEncoding))
+	}
+	if ws == nil {
+		return fields
+	}
+	if n := ws.sentBytes.Load(); n > 0 {
+		fields = append(fields, slog.Int64("wire_sent_bytes", n))
+	}
+	if n := ws.recvBytes.Load(); n > 0 {
+		fields = append(fields, slog.Int64("wire_recv_bytes", n))
+	}
+	return fields
+}
+
+// serverEncodings 返回服务端请求和响应的压缩算法
+// 优先使用StatsHandler采集的值，否则使用请求元数据中的grpc-encoding
+func serverEncodings(ctx context.Context, ws *wireStats) (request, response string) {
+	request, response = ws.encodings()
+	if request == "" {
+		if vals := metadata.ValueFromIncomingContext(ctx, "grpc-encoding"); len(vals) > 0 {
+			request = vals[0]
+		}
+	}
+	return request, response
+}
+
+// clientEncodings 返回客户端请求和响应的压缩算法
+// 优先使用StatsHandler采集的值，否则使用grpc.UseCompressor指定的压缩算法
+func clientEncodings(ws *wireStats, opts []grpc.CallOption) (request, response string) {
+	response, request = ws.encodings()
+	if request == "" {
+		for _, opt := range opts {
+			if c, ok := opt.(grpc.CompressorCallOption); ok {
+				request = c.CompressorType
+			}
+		}
+	}
+	return request, response
+}
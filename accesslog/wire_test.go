@@ -0,0 +1,193 @@
+package accesslog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestAppendSizeAttrs(t *testing.T) {
+	req := &healthpb.HealthCheckRequest{Service: "svc"}
+	resp := &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}
+
+	tests := []struct {
+		name string
+		req  any
+		resp any
+		want []slog.Attr
+	}{
+		{
+			name: "proto消息记录序列化大小",
+			req:  req,
+			resp: resp,
+			want: []slog.Attr{slog.Int("request_size", proto.Size(req)), slog.Int("response_size", proto.Size(resp))},
+		},
+		{
+			name: "非proto消息不记录",
+			req:  "plain",
+			resp: nil,
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, appendSizeAttrs(nil, tt.req, tt.resp))
+		})
+	}
+}
+
+func TestStatsHandler_HandleRPC(t *testing.T) {
+	h := NewStatsHandler()
+	ctx := h.TagRPC(context.Background(), &stats.RPCTagInfo{FullMethodName: "/test/method"})
+	ws := wireStatsFromContext(ctx, false)
+	require.NotNil(t, ws)
+	// 客户端拦截器已放入时不再创建
+	clientCtx, clientWS := withWireStats(ctx, true)
+	assert.Equal(t, clientCtx, h.TagRPC(clientCtx, &stats.RPCTagInfo{}))
+	h.HandleRPC(clientCtx, &stats.InPayload{Client: true, WireLength: 3})
+	assert.Equal(t, int64(3), clientWS.recvBytes.Load())
+
+	h.HandleRPC(ctx, &stats.InHeader{Compression: "gzip"})
+	h.HandleRPC(ctx, &stats.OutHeader{Compression: "identity"})
+	h.HandleRPC(ctx, &stats.InPayload{WireLength: 10})
+	h.HandleRPC(ctx, &stats.InPayload{WireLength: 5})
+	h.HandleRPC(ctx, &stats.OutPayload{WireLength: 7})
+	// 没有传输层信息的上下文被忽略
+	h.HandleRPC(context.Background(), &stats.InPayload{WireLength: 100})
+
+	in, out := ws.encodings()
+	assert.Equal(t, "gzip", in)
+	assert.Equal(t, "identity", out)
+	assert.Equal(t, int64(15), ws.recvBytes.Load())
+	assert.Equal(t, int64(7), ws.sentBytes.Load())
+}
+
+func TestEncodings_Fallback(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("grpc-encoding", "snappy"))
+	request, response := serverEncodings(ctx, nil)
+	assert.Equal(t, "snappy", request)
+	assert.Empty(t, response)
+
+	request, response = clientEncodings(nil, []grpc.CallOption{grpc.UseCompressor("gzip")})
+	assert.Equal(t, "gzip", request)
+	assert.Empty(t, response)
+}
+
+func TestInterceptors_WireStats(t *testing.T) {
+	serverBuf := captureDefaultLogger(t)
+	clientBuf := &bytes.Buffer{}
+	clientLogger := slog.New(slog.NewJSONHandler(clientBuf, nil))
+
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(
+		grpc.StatsHandler(NewStatsHandler()),
+		grpc.UnaryInterceptor(UnaryServerInterceptor()),
+	)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStatsHandler(NewStatsHandler()),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor(WithLogger(clientLogger))),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	req := &healthpb.HealthCheckRequest{}
+	resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), req, grpc.UseCompressor(gzip.Name))
+	require.NoError(t, err)
+
+	client := decodeRecord(t, clientBuf)
+	assert.Equal(t, float64(proto.Size(req)), client["request_size"])
+	assert.Equal(t, float64(proto.Size(resp)), client["response_size"])
+	assert.Equal(t, "gzip", client["request_encoding"])
+	assert.Equal(t, "gzip", client["response_encoding"], "服务端默认使用与请求相同的压缩算法")
+	assert.Greater(t, client["wire_sent_bytes"], float64(0))
+	assert.Greater(t, client["wire_recv_bytes"], float64(0))
+
+	// 服务端一元调用的响应在拦截器返回后才写出，只记录请求方向
+	server.GracefulStop()
+	record := decodeRecord(t, serverBuf)
+	assert.Equal(t, "gzip", record["request_encoding"])
+	assert.Equal(t, float64(proto.Size(resp)), record["response_size"])
+	assert.Greater(t, record["wire_recv_bytes"], float64(0))
+	assert.NotContains(t, record, "wire_sent_bytes")
+}
+
+// nestedHealthServer 在处理请求时使用收到的上下文调用下游服务
+type nestedHealthServer struct {
+	healthpb.UnimplementedHealthServer
+	backend healthpb.HealthClient
+}
+
+func (s *nestedHealthServer) Check(ctx context.Context, _ *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	return s.backend.Check(ctx, &healthpb.HealthCheckRequest{Service: ""})
+}
+
+func TestInterceptors_WireStats_NestedClientCall(t *testing.T) {
+	serverBuf := captureDefaultLogger(t)
+	clientBuf := &bytes.Buffer{}
+	clientLogger := slog.New(slog.NewJSONHandler(clientBuf, nil))
+
+	dial := func(lis *bufconn.Listener, opts ...grpc.DialOption) *grpc.ClientConn {
+		conn, err := grpc.NewClient("passthrough:///bufnet", append([]grpc.DialOption{
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		}, opts...)...)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = conn.Close() })
+		return conn
+	}
+
+	backendLis := bufconn.Listen(1 << 20)
+	backend := grpc.NewServer()
+	healthpb.RegisterHealthServer(backend, health.NewServer())
+	go func() { _ = backend.Serve(backendLis) }()
+	t.Cleanup(backend.Stop)
+	backendConn := dial(backendLis,
+		grpc.WithStatsHandler(NewStatsHandler()),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor(WithLogger(clientLogger))),
+	)
+
+	frontendLis := bufconn.Listen(1 << 20)
+	frontend := grpc.NewServer(
+		grpc.StatsHandler(NewStatsHandler()),
+		grpc.UnaryInterceptor(UnaryServerInterceptor()),
+	)
+	healthpb.RegisterHealthServer(frontend, &nestedHealthServer{backend: healthpb.NewHealthClient(backendConn)})
+	go func() { _ = frontend.Serve(frontendLis) }()
+	t.Cleanup(frontend.Stop)
+
+	req := &healthpb.HealthCheckRequest{Service: "a-service-name-long-enough-to-tell-apart"}
+	_, err := healthpb.NewHealthClient(dial(frontendLis)).Check(context.Background(), req)
+	require.NoError(t, err)
+
+	// 线上字节数包含5字节的消息头
+	resp := &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}
+	client := decodeRecord(t, clientBuf)
+	assert.Equal(t, float64(5), client["wire_sent_bytes"])
+	assert.Equal(t, float64(proto.Size(resp)+5), client["wire_recv_bytes"])
+
+	frontend.GracefulStop()
+	record := decodeRecord(t, serverBuf)
+	assert.Equal(t, float64(proto.Size(req)+5), record["wire_recv_bytes"])
+	assert.NotContains(t, record, "wire_sent_bytes")
+}
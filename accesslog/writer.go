@@ -0,0 +1,180 @@
+package accesslog
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrWriterClosed 写入已关闭的AsyncWriter时返回的错误
+var ErrWriterClosed = errors.New("accesslog: writer closed")
+
+// defaultBufferSize 默认的缓冲记录条数
+const defaultBufferSize = 1024
+
+// writerOptions 存储AsyncWriter的配置选项
+type writerOptions struct {
+	// bufferSize 环形缓冲区可容纳的记录条数
+	bufferSize int
+	// errorHandler 写入底层io.Writer失败时的回调
+	errorHandler func(error)
+}
+
+// WriterOption 定义AsyncWriter配置选项的函数类型
+type WriterOption func(*writerOptions)
+
+// WithBufferSize 设置环形缓冲区可容纳的记录条数
+// 缓冲区满时新记录被丢弃并计入Dropped
+//
+// 参数:
+//   - n: 记录条数，小于等于0时使用默认值1024
+//
+// 返回值:
+//   - WriterOption: 设置缓冲区大小选项的函数
+func WithBufferSize(n int) WriterOption {
+	return func(o *writerOptions) {
+		if n > 0 {
+			o.bufferSize = n
+		}
+	}
+}
+
+// WithWriteErrorHandler 设置写入底层io.Writer失败时的回调
+// 回调在后台协程中执行
+//
+// 参数:
+//   - handler: 错误回调
+//
+// 返回值:
+//   - WriterOption: 设置错误回调选项的函数
+func WithWriteErrorHandler(handler func(error)) WriterOption {
+	return func(o *writerOptions) {
+		o.errorHandler = handler
+	}
+}
+
+// AsyncWriter 异步缓冲的日志写入器
+// Write将每条记录复制到有界的环形缓冲区后立即返回，由后台协程批量写入底层io.Writer，
+// 避免磁盘压力影响请求路径的延迟。每次Write视为一条记录，
+// 与slog.NewJSONHandler、slog.NewTextHandler每条记录调用一次Write的行为一致
+type AsyncWriter struct {
+	out          io.Writer
+	errorHandler func(error)
+
+	mu     sync.Mutex
+	ring   [][]byte
+	head   int
+	size   int
+	closed bool
+
+	// batch 后台协程合并记录使用的缓冲区
+	batch   []byte
+	dropped atomic.Uint64
+	notify  chan struct{}
+	done    chan struct{}
+}
+
+// NewAsyncWriter 创建异步缓冲的日志写入器并启动后台写入协程
+//
+// 参数:
+//   - out: 底层写入器，实现io.Closer时会在Close中一并关闭
+//   - opts: 可选的配置选项
+//
+// 返回值:
+//   - *AsyncWriter: 异步写入器
+func NewAsyncWriter(out io.Writer, opts ...WriterOption) *AsyncWriter {
+	o := &writerOptions{bufferSize: defaultBufferSize}
+	for _, opt := range opts {
+		opt(o)
+	}
+	w := &AsyncWriter{
+		out:          out,
+		errorHandler: o.errorHandler,
+		ring:         make([][]byte, o.bufferSize),
+		notify:       make(chan struct{}, 1),
+		done:         make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Write 将记录放入缓冲区，缓冲区满时丢弃该记录
+// 除非写入器已关闭，否则总是返回len(p)和nil
+func (w *AsyncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return 0, ErrWriterClosed
+	}
+	if w.size == len(w.ring) {
+		w.mu.Unlock()
+		w.dropped.Add(1)
+		return len(p), nil
+	}
+	// 复用槽位的底层数组，调用方可能在Write返回后复用p
+	i := (w.head + w.size) % len(w.ring)
+	w.ring[i] = append(w.ring[i][:0], p...)
+	w.size++
+	// 持有锁时通知，避免与Close关闭通道竞争
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+	w.mu.Unlock()
+	return len(p), nil
+}
+
+// Dropped 返回因缓冲区已满而丢弃的记录条数
+func (w *AsyncWriter) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// Close 停止接收新记录，等待缓冲区中的记录全部写出，
+// 底层写入器实现io.Closer时将其关闭。重复调用是安全的
+func (w *AsyncWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		<-w.done
+		return nil
+	}
+	w.closed = true
+	close(w.notify)
+	w.mu.Unlock()
+
+	<-w.done
+	if closer, ok := w.out.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// run 后台写入协程，收到通知后批量写出缓冲区中的记录
+func (w *AsyncWriter) run() {
+	defer close(w.done)
+	for range w.notify {
+		w.flush()
+	}
+	// 通知通道关闭后，写出剩余的记录
+	w.flush()
+}
+
+// flush 将缓冲区中的记录合并为一次写入
+func (w *AsyncWriter) flush() {
+	w.mu.Lock()
+	if w.size == 0 {
+		w.mu.Unlock()
+		return
+	}
+	w.batch = w.batch[:0]
+	for ; w.size > 0; w.size-- {
+		w.batch = append(w.batch, w.ring[w.head]...)
+		w.head = (w.head + 1) % len(w.ring)
+	}
+	w.mu.Unlock()
+
+	if _, err := w.out.Write(w.batch); err != nil && w.errorHandler != nil {
+		w.errorHandler(err)
+	}
+}
@@ -0,0 +1,162 @@
+package accesslog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// blockingWriter 在release关闭前阻塞写入，用于模拟磁盘压力
+type blockingWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+	closed  bool
+}
+
+func newBlockingWriter() *blockingWriter {
+	return &blockingWriter{started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() { close(w.started) })
+	<-w.release
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *blockingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	return nil
+}
+
+func (w *blockingWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestAsyncWriter_CloseFlushes(t *testing.T) {
+	out := newBlockingWriter()
+	close(out.release)
+	w := NewAsyncWriter(out)
+
+	for _, line := range []string{"a\n", "b\n", "c\n"} {
+		n, err := w.Write([]byte(line))
+		require.NoError(t, err)
+		assert.Equal(t, len(line), n)
+	}
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, "a\nb\nc\n", out.String())
+	assert.True(t, out.closed, "Close应关闭底层写入器")
+	assert.Zero(t, w.Dropped())
+}
+
+func TestAsyncWriter_CopiesRecord(t *testing.T) {
+	out := newBlockingWriter()
+	w := NewAsyncWriter(out)
+
+	p := []byte("first\n")
+	_, err := w.Write(p)
+	require.NoError(t, err)
+	// 调用方在Write返回后复用缓冲区
+	copy(p, "xxxxx\n")
+	close(out.release)
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, "first\n", out.String())
+}
+
+func TestAsyncWriter_DropsWhenFull(t *testing.T) {
+	out := newBlockingWriter()
+	w := NewAsyncWriter(out, WithBufferSize(2))
+
+	// 第一条记录被后台协程取出后阻塞在底层写入
+	_, err := w.Write([]byte("1\n"))
+	require.NoError(t, err)
+	<-out.started
+
+	// 缓冲区容纳2条，其余被丢弃
+	for _, line := range []string{"2\n", "3\n", "4\n", "5\n"} {
+		n, err := w.Write([]byte(line))
+		require.NoError(t, err)
+		assert.Equal(t, len(line), n)
+	}
+	assert.Equal(t, uint64(2), w.Dropped())
+
+	close(out.release)
+	require.NoError(t, w.Close())
+	assert.Equal(t, "1\n2\n3\n", out.String())
+}
+
+func TestAsyncWriter_WriteAfterClose(t *testing.T) {
+	w := NewAsyncWriter(&bytes.Buffer{})
+	require.NoError(t, w.Close())
+	require.NoError(t, w.Close(), "重复关闭应是安全的")
+
+	_, err := w.Write([]byte("late\n"))
+	assert.ErrorIs(t, err, ErrWriterClosed)
+}
+
+type errWriter struct{}
+
+func (errWriter) Write(p []byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestAsyncWriter_ErrorHandler(t *testing.T) {
+	var mu sync.Mutex
+	var got []error
+	w := NewAsyncWriter(errWriter{}, WithWriteErrorHandler(func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, err)
+	}))
+	_, err := w.Write([]byte("x\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, got, 1)
+	assert.EqualError(t, got[0], "disk full")
+}
+
+func TestAsyncWriter_ConcurrentWithInterceptor(t *testing.T) {
+	out := newBlockingWriter()
+	close(out.release)
+	w := NewAsyncWriter(out, WithBufferSize(1000))
+	interceptor := UnaryServerInterceptor(WithLogger(slog.New(slog.NewJSONHandler(w, nil))))
+	handler := func(ctx context.Context, req any) (any, error) { return nil, nil }
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				_, _ = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test/method"}, handler)
+			}
+		}()
+	}
+	wg.Wait()
+	require.NoError(t, w.Close())
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	assert.Equal(t, 500-int(w.Dropped()), len(lines))
+	for _, line := range lines {
+		assert.Contains(t, line, `"msg":"/test/method"`)
+	}
+}
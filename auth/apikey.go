@@ -0,0 +1,376 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// headerAPIKey 默认的API Key头字段名
+const headerAPIKey = "x-api-key"
+
+// ErrAPIKeyNotFound 存储中找不到API Key时返回的错误
+var ErrAPIKeyNotFound = errors.New("auth: api key not found")
+
+// APIKey 存储中的API Key记录，只保存密钥的哈希
+type APIKey struct {
+	// Hash 密钥的SHA-256哈希，十六进制编码，由HashAPIKey生成
+	Hash string `json:"hash"`
+	// Owner 密钥所有者
+	Owner string `json:"owner"`
+	// Scopes 密钥授予的权限范围
+	Scopes []string `json:"scopes,omitempty"`
+	// ExpiresAt 过期时间，零值表示不过期
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+}
+
+// HasScope 判断密钥是否拥有指定的权限范围
+//
+// 参数:
+//   - scope: 权限范围
+//
+// 返回值:
+//   - bool: 是否拥有该权限范围
+func (k *APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+// HashAPIKey 计算API Key的哈希，用于生成存储中的记录
+//
+// 参数:
+//   - key: 明文密钥
+//
+// 返回值:
+//   - string: 十六进制编码的SHA-256哈希
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeyStore 按哈希查找API Key的存储
+type APIKeyStore interface {
+	// Lookup 查找哈希对应的API Key
+	//
+	// 参数:
+	//   - ctx: 请求上下文
+	//   - hash: 请求中密钥的哈希，由HashAPIKey生成
+	//
+	// 返回值:
+	//   - *APIKey: 密钥记录，调用方不应修改
+	//   - error: 找不到时返回ErrAPIKeyNotFound
+	Lookup(ctx context.Context, hash string) (*APIKey, error)
+}
+
+// apiKeyContextKey API Key在上下文中的键
+type apiKeyContextKey struct{}
+
+// APIKeyFromContext 从上下文中获取认证通过的API Key
+//
+// 参数:
+//   - ctx: 请求上下文
+//
+// 返回值:
+//   - *APIKey: 密钥记录
+//   - bool: 上下文中是否有API Key
+func APIKeyFromContext(ctx context.Context) (*APIKey, bool) {
+	key, ok := ctx.Value(apiKeyContextKey{}).(*APIKey)
+	return key, ok
+}
+
+// apiKeyOptions 存储API Key认证的配置选项
+type apiKeyOptions struct {
+	// header 携带密钥的元数据字段名
+	header string
+	// now 获取当前时间，便于测试
+	now func() time.Time
+}
+
+// APIKeyOption 定义API Key认证配置选项的函数类型
+type APIKeyOption func(*apiKeyOptions)
+
+// WithAPIKeyHeader 设置携带密钥的元数据字段名，默认为"x-api-key"
+//
+// 参数:
+//   - name: 元数据字段名
+//
+// 返回值:
+//   - APIKeyOption: 设置字段名选项的函数
+func WithAPIKeyHeader(name string) APIKeyOption {
+	return func(o *apiKeyOptions) {
+		o.header = strings.ToLower(name)
+	}
+}
+
+// NewAPIKeyAuthFunc 创建API Key认证函数
+// 请求中的密钥经哈希后在存储中查找，并以常量时间比较哈希；
+// 认证通过后密钥记录写入上下文，可通过APIKeyFromContext获取
+//
+// 参数:
+//   - store: API Key存储
+//   - opts: 可选的配置选项
+//
+// 返回值:
+//   - AuthFunc: 认证函数
+func NewAPIKeyAuthFunc(store APIKeyStore, opts ...APIKeyOption) AuthFunc {
+	o := &apiKeyOptions{header: headerAPIKey, now: time.Now}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return func(ctx context.Context, fullMethodName string) (context.Context, error) {
+		vals := metadata.ValueFromIncomingContext(ctx, o.header)
+		if len(vals) == 0 || vals[0] == "" {
+			return nil, status.Error(codes.Unauthenticated, "missing api key")
+		}
+		hash := HashAPIKey(vals[0])
+		key, err := store.Lookup(ctx, hash)
+		if errors.Is(err, ErrAPIKeyNotFound) {
+			return nil, status.Error(codes.Unauthenticated, "invalid api key")
+		}
+		if err != nil {
+			return nil, status.Error(codes.Unavailable, "api key store unavailable")
+		}
+		// 按哈希查找只暴露哈希的时序信息，这里再以常量时间确认
+		if subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hash)) != 1 {
+			return nil, status.Error(codes.Unauthenticated, "invalid api key")
+		}
+		if !key.ExpiresAt.IsZero() && !o.now().Before(key.ExpiresAt) {
+			return nil, status.Error(codes.Unauthenticated, "api key expired")
+		}
+		return context.WithValue(ctx, apiKeyContextKey{}, key), nil
+	}
+}
+
+// MemoryAPIKeyStore 内存中的API Key存储，可并发使用，零值可以直接使用
+type MemoryAPIKeyStore struct {
+	mu   sync.RWMutex
+	keys map[string]*APIKey
+}
+
+// NewMemoryAPIKeyStore 创建内存API Key存储
+//
+// 参数:
+//   - keys: 初始的密钥记录
+//
+// 返回值:
+//   - *MemoryAPIKeyStore: 密钥存储
+//   - error: 记录的哈希格式错误或重复时返回错误
+func NewMemoryAPIKeyStore(keys ...APIKey) (*MemoryAPIKeyStore, error) {
+	s := &MemoryAPIKeyStore{}
+	if err := s.Replace(keys); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Lookup 实现APIKeyStore接口
+func (s *MemoryAPIKeyStore) Lookup(_ context.Context, hash string) (*APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.keys[hash]
+	if !ok {
+		return nil, ErrAPIKeyNotFound
+	}
+	return key, nil
+}
+
+// Add 添加或替换一条密钥记录
+//
+// 参数:
+//   - key: 密钥记录
+//
+// 返回值:
+//   - error: 哈希格式错误时返回错误
+func (s *MemoryAPIKeyStore) Add(key APIKey) error {
+	hash, err := normalizeAPIKeyHash(key.Hash)
+	if err != nil {
+		return err
+	}
+	key.Hash = hash
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.keys == nil {
+		s.keys = make(map[string]*APIKey)
+	}
+	s.keys[hash] = &key
+	return nil
+}
+
+// Remove 删除哈希对应的密钥记录
+//
+// 参数:
+//   - hash: 密钥的哈希
+func (s *MemoryAPIKeyStore) Remove(hash string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.keys, strings.ToLower(hash))
+}
+
+// Replace 以给定的记录整体替换存储内容
+//
+// 参数:
+//   - keys: 新的密钥记录
+//
+// 返回值:
+//   - error: 记录的哈希格式错误或重复时返回错误，存储内容保持不变
+func (s *MemoryAPIKeyStore) Replace(keys []APIKey) error {
+	m := make(map[string]*APIKey, len(keys))
+	for _, key := range keys {
+		hash, err := normalizeAPIKeyHash(key.Hash)
+		if err != nil {
+			return err
+		}
+		if _, ok := m[hash]; ok {
+			return fmt.Errorf("auth: duplicate api key hash %s", hash)
+		}
+		key.Hash = hash
+		m[hash] = &key
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = m
+	return nil
+}
+
+// normalizeAPIKeyHash 校验哈希格式并转换为小写
+func normalizeAPIKeyHash(hash string) (string, error) {
+	hash = strings.ToLower(hash)
+	if len(hash) != sha256.Size*2 {
+		return "", fmt.Errorf("auth: invalid api key hash %q", hash)
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return "", fmt.Errorf("auth: invalid api key hash %q", hash)
+	}
+	return hash, nil
+}
+
+// apiKeyFileOptions 存储文件API Key存储的配置选项
+type apiKeyFileOptions struct {
+	// checkInterval 两次检查文件是否变化的最小间隔
+	checkInterval time.Duration
+	// now 获取当前时间，便于测试
+	now func() time.Time
+}
+
+// APIKeyFileOption 定义文件API Key存储配置选项的函数类型
+type APIKeyFileOption func(*apiKeyFileOptions)
+
+// WithCheckInterval 设置检查文件是否变化的最小间隔，默认10秒
+//
+// 参数:
+//   - d: 检查间隔
+//
+// 返回值:
+//   - APIKeyFileOption: 设置检查间隔选项的函数
+func WithCheckInterval(d time.Duration) APIKeyFileOption {
+	return func(o *apiKeyFileOptions) {
+		o.checkInterval = d
+	}
+}
+
+// FileAPIKeyStore 从JSON文件加载的API Key存储
+// 文件格式为{"keys":[{"hash":"...","owner":"...","scopes":[...],"expires_at":"..."}]}。
+// 查找时若距上次检查超过检查间隔，则根据文件的修改时间和大小判断是否需要重新加载；
+// 重新加载失败时继续使用已加载的记录
+type FileAPIKeyStore struct {
+	path  string
+	opts  *apiKeyFileOptions
+	store *MemoryAPIKeyStore
+
+	mu        sync.Mutex
+	modTime   time.Time
+	size      int64
+	checkedAt time.Time
+}
+
+// NewFileAPIKeyStore 创建从JSON文件加载的API Key存储
+//
+// 参数:
+//   - path: 文件路径
+//   - opts: 可选的配置选项
+//
+// 返回值:
+//   - *FileAPIKeyStore: 密钥存储
+//   - error: 首次加载失败时返回错误
+func NewFileAPIKeyStore(path string, opts ...APIKeyFileOption) (*FileAPIKeyStore, error) {
+	o := &apiKeyFileOptions{checkInterval: 10 * time.Second, now: time.Now}
+	for _, opt := range opts {
+		opt(o)
+	}
+	s := &FileAPIKeyStore{path: path, opts: o, store: &MemoryAPIKeyStore{}}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Lookup 实现APIKeyStore接口
+// 只有一个请求检查并重新加载文件，其他请求不等待，直接使用已加载的记录
+func (s *FileAPIKeyStore) Lookup(ctx context.Context, hash string) (*APIKey, error) {
+	if s.mu.TryLock() {
+		if s.opts.now().Sub(s.checkedAt) >= s.opts.checkInterval {
+			_ = s.reloadIfChangedLocked()
+		}
+		s.mu.Unlock()
+	}
+	return s.store.Lookup(ctx, hash)
+}
+
+// Reload 立即重新加载文件
+//
+// 返回值:
+//   - error: 读取或解析失败时返回错误，已加载的记录保持不变
+func (s *FileAPIKeyStore) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return err
+	}
+	return s.loadLocked(info)
+}
+
+// reloadIfChangedLocked 文件变化时重新加载，调用方需持有锁
+func (s *FileAPIKeyStore) reloadIfChangedLocked() error {
+	s.checkedAt = s.opts.now()
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(s.modTime) && info.Size() == s.size {
+		return nil
+	}
+	return s.loadLocked(info)
+}
+
+// loadLocked 读取并解析文件，调用方需持有锁
+func (s *FileAPIKeyStore) loadLocked(info os.FileInfo) error {
+	s.checkedAt = s.opts.now()
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	var doc struct {
+		Keys []APIKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("auth: parse api key file: %w", err)
+	}
+	if err := s.store.Replace(doc.Keys); err != nil {
+		return err
+	}
+	s.modTime, s.size = info.ModTime(), info.Size()
+	return nil
+}
@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// apiKeyContext 构造携带API Key的请求上下文
+func apiKeyContext(key string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", key))
+}
+
+func TestNewAPIKeyAuthFunc(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store, err := NewMemoryAPIKeyStore(
+		APIKey{Hash: HashAPIKey("valid-key"), Owner: "billing", Scopes: []string{"read", "write"}},
+		APIKey{Hash: HashAPIKey("expired-key"), Owner: "legacy", ExpiresAt: now.Add(-time.Second)},
+		APIKey{Hash: HashAPIKey("future-key"), Owner: "batch", ExpiresAt: now.Add(time.Hour)},
+	)
+	require.NoError(t, err)
+	authFunc := NewAPIKeyAuthFunc(store, func(o *apiKeyOptions) { o.now = func() time.Time { return now } })
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		wantOwner string
+		wantMsg   string
+	}{
+		{"合法密钥", apiKeyContext("valid-key"), "billing", ""},
+		{"未过期密钥", apiKeyContext("future-key"), "batch", ""},
+		{"已过期密钥", apiKeyContext("expired-key"), "", "api key expired"},
+		{"未知密钥", apiKeyContext("unknown-key"), "", "invalid api key"},
+		{"缺少密钥", context.Background(), "", "missing api key"},
+		{"空密钥", apiKeyContext(""), "", "missing api key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, err := authFunc(tt.ctx, "/test/method")
+			if tt.wantMsg != "" {
+				assert.Nil(t, ctx)
+				assert.Equal(t, codes.Unauthenticated, status.Code(err))
+				assert.Equal(t, tt.wantMsg, status.Convert(err).Message())
+				return
+			}
+			require.NoError(t, err)
+			key, ok := APIKeyFromContext(ctx)
+			require.True(t, ok)
+			assert.Equal(t, tt.wantOwner, key.Owner)
+		})
+	}
+}
+
+func TestNewAPIKeyAuthFunc_Header(t *testing.T) {
+	store, err := NewMemoryAPIKeyStore(APIKey{Hash: HashAPIKey("secret"), Owner: "ops", Scopes: []string{"admin"}})
+	require.NoError(t, err)
+	authFunc := NewAPIKeyAuthFunc(store, WithAPIKeyHeader("X-Service-Token"))
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-service-token", "secret"))
+	ctx, err = authFunc(ctx, "/test/method")
+	require.NoError(t, err)
+	key, _ := APIKeyFromContext(ctx)
+	assert.True(t, key.HasScope("admin"))
+	assert.False(t, key.HasScope("read"))
+}
+
+func TestNewAPIKeyAuthFunc_StoreError(t *testing.T) {
+	store := apiKeyStoreFunc(func(context.Context, string) (*APIKey, error) {
+		return nil, errors.New("connection refused")
+	})
+	_, err := NewAPIKeyAuthFunc(store)(apiKeyContext("key"), "/test/method")
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+// apiKeyStoreFunc 是APIKeyStore接口的函数适配器
+type apiKeyStoreFunc func(ctx context.Context, hash string) (*APIKey, error)
+
+func (f apiKeyStoreFunc) Lookup(ctx context.Context, hash string) (*APIKey, error) {
+	return f(ctx, hash)
+}
+
+func TestMemoryAPIKeyStore(t *testing.T) {
+	ctx := context.Background()
+	hash := HashAPIKey("key")
+
+	_, err := NewMemoryAPIKeyStore(APIKey{Hash: "not-a-hash"})
+	assert.Error(t, err)
+	_, err = NewMemoryAPIKeyStore(APIKey{Hash: hash}, APIKey{Hash: hash})
+	assert.ErrorContains(t, err, "duplicate")
+
+	store, err := NewMemoryAPIKeyStore()
+	require.NoError(t, err)
+	_, err = store.Lookup(ctx, hash)
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+
+	// 哈希不区分大小写
+	require.NoError(t, store.Add(APIKey{Hash: strings.ToUpper(hash), Owner: "a"}))
+	key, err := store.Lookup(ctx, hash)
+	require.NoError(t, err)
+	assert.Equal(t, "a", key.Owner)
+
+	store.Remove(hash)
+	_, err = store.Lookup(ctx, hash)
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+
+	// 零值可以直接使用
+	var zero MemoryAPIKeyStore
+	_, err = zero.Lookup(ctx, hash)
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+	require.NoError(t, zero.Add(APIKey{Hash: hash, Owner: "b"}))
+	key, err = zero.Lookup(ctx, hash)
+	require.NoError(t, err)
+	assert.Equal(t, "b", key.Owner)
+}
+
+// writeAPIKeyFile 写入API Key文件并设置修改时间
+func writeAPIKeyFile(t *testing.T, path, content string, modTime time.Time) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestFileAPIKeyStore_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	modTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	writeAPIKeyFile(t, path, `{"keys":[{"hash":"`+HashAPIKey("old")+`","owner":"alice","scopes":["read"],"expires_at":"2030-01-01T00:00:00Z"}]}`, modTime)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func(o *apiKeyFileOptions) { o.now = func() time.Time { return now } }
+	store, err := NewFileAPIKeyStore(path, WithCheckInterval(time.Minute), clock)
+	require.NoError(t, err)
+	authFunc := NewAPIKeyAuthFunc(store)
+
+	ctx, err := authFunc(apiKeyContext("old"), "/test/method")
+	require.NoError(t, err)
+	key, _ := APIKeyFromContext(ctx)
+	assert.Equal(t, "alice", key.Owner)
+	assert.Equal(t, []string{"read"}, key.Scopes)
+	assert.Equal(t, time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), key.ExpiresAt)
+
+	// 文件变化后，检查间隔内仍使用旧记录
+	writeAPIKeyFile(t, path, `{"keys":[{"hash":"`+HashAPIKey("new")+`","owner":"bob"}]}`, modTime.Add(time.Second))
+	_, err = authFunc(apiKeyContext("new"), "/test/method")
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// 超过检查间隔后重新加载
+	now = now.Add(2 * time.Minute)
+	_, err = authFunc(apiKeyContext("new"), "/test/method")
+	require.NoError(t, err)
+	_, err = authFunc(apiKeyContext("old"), "/test/method")
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// 文件内容错误时继续使用已加载的记录
+	writeAPIKeyFile(t, path, `{"keys":`, modTime.Add(2*time.Second))
+	now = now.Add(2 * time.Minute)
+	_, err = authFunc(apiKeyContext("new"), "/test/method")
+	require.NoError(t, err)
+	assert.Error(t, store.Reload())
+}
+
+func TestFileAPIKeyStore_LookupDuringReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	writeAPIKeyFile(t, path, `{"keys":[{"hash":"`+HashAPIKey("old")+`","owner":"alice"}]}`, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	store, err := NewFileAPIKeyStore(path, WithCheckInterval(0))
+	require.NoError(t, err)
+
+	// 模拟另一个请求正在重新加载文件
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	done := make(chan error, 1)
+	go func() {
+		_, err := store.Lookup(context.Background(), HashAPIKey("old"))
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err, "重新加载期间使用已加载的记录")
+	case <-time.After(time.Second):
+		t.Fatal("查找等待了正在进行的重新加载")
+	}
+}
+
+func TestNewFileAPIKeyStore_Errors(t *testing.T) {
+	dir := t.TempDir()
+	_, err := NewFileAPIKeyStore(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+
+	path := filepath.Join(dir, "keys.json")
+	writeAPIKeyFile(t, path, `{"keys":[{"hash":"abc"}]}`, time.Now())
+	_, err = NewFileAPIKeyStore(path)
+	assert.ErrorContains(t, err, "invalid api key hash")
+}
@@ -0,0 +1,335 @@
+package auth
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// authCacheOptions 存储认证结果缓存的配置选项
+type authCacheOptions struct {
+	// ttl 成功结果的缓存时间
+	ttl time.Duration
+	// negativeTTL 失败结果的缓存时间
+	negativeTTL time.Duration
+	// maxEntries 缓存的最大条目数
+	maxEntries int
+	// keyFunc 获取缓存键的函数
+	keyFunc func(ctx context.Context, fullMethodName string) (string, bool)
+	// expiryFunc 获取认证结果过期时间的函数
+	expiryFunc func(ctx context.Context) time.Time
+	// now 获取当前时间，便于测试
+	now func() time.Time
+}
+
+// AuthCacheOption 定义认证结果缓存配置选项的函数类型
+type AuthCacheOption func(*authCacheOptions)
+
+// WithCacheTTL 设置成功结果的缓存时间，默认5分钟
+// 实际缓存时间不超过凭证的过期时间
+//
+// 参数:
+//   - ttl: 缓存时间
+//
+// 返回值:
+//   - AuthCacheOption: 设置缓存时间选项的函数
+func WithCacheTTL(ttl time.Duration) AuthCacheOption {
+	return func(o *authCacheOptions) {
+		o.ttl = ttl
+	}
+}
+
+// WithNegativeCacheTTL 设置失败结果的缓存时间，默认10秒，为0时不缓存失败结果
+// 只缓存Unauthenticated和PermissionDenied错误，其他错误通常是暂时的，不缓存
+//
+// 参数:
+//   - ttl: 缓存时间
+//
+// 返回值:
+//   - AuthCacheOption: 设置失败结果缓存时间选项的函数
+func WithNegativeCacheTTL(ttl time.Duration) AuthCacheOption {
+	return func(o *authCacheOptions) {
+		o.negativeTTL = ttl
+	}
+}
+
+// WithMaxCacheEntries 设置缓存的最大条目数，默认10000，超出时淘汰最近最少使用的条目
+//
+// 参数:
+//   - n: 最大条目数
+//
+// 返回值:
+//   - AuthCacheOption: 设置最大条目数选项的函数
+func WithMaxCacheEntries(n int) AuthCacheOption {
+	return func(o *authCacheOptions) {
+		if n > 0 {
+			o.maxEntries = n
+		}
+	}
+}
+
+// WithCacheKeyFunc 设置获取缓存键的函数，返回false时不使用缓存
+// 默认使用方法名和authorization头，没有authorization头时使用x-api-key头；
+// 认证结果与方法无关时，可以只使用凭证作为缓存键，使不同方法共享缓存
+//
+// 参数:
+//   - f: 获取缓存键的函数，缓存键会被哈希后使用
+//
+// 返回值:
+//   - AuthCacheOption: 设置缓存键函数选项的函数
+func WithCacheKeyFunc(f func(ctx context.Context, fullMethodName string) (string, bool)) AuthCacheOption {
+	return func(o *authCacheOptions) {
+		if f != nil {
+			o.keyFunc = f
+		}
+	}
+}
+
+// WithExpiryFunc 设置从认证后的上下文中获取凭证过期时间的函数，返回零值表示没有过期时间
+// 默认读取JWT声明、令牌内省结果和API Key的过期时间
+//
+// 参数:
+//   - f: 获取过期时间的函数
+//
+// 返回值:
+//   - AuthCacheOption: 设置过期时间函数选项的函数
+func WithExpiryFunc(f func(ctx context.Context) time.Time) AuthCacheOption {
+	return func(o *authCacheOptions) {
+		if f != nil {
+			o.expiryFunc = f
+		}
+	}
+}
+
+// defaultCacheKey 默认的缓存键，为方法名和凭证，认证函数可能按方法返回不同的结果
+func defaultCacheKey(ctx context.Context, fullMethodName string) (string, bool) {
+	credential, ok := credentialCacheKey(ctx, fullMethodName)
+	if !ok {
+		return "", false
+	}
+	return fullMethodName + "|" + credential, true
+}
+
+// credentialCacheKey 只使用凭证的缓存键，为authorization或x-api-key头，用于与方法无关的认证函数
+func credentialCacheKey(ctx context.Context, _ string) (string, bool) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, key := range []string{headerAuthorize, headerAPIKey} {
+		if vals := md.Get(key); len(vals) > 0 && vals[0] != "" {
+			return key + ":" + vals[0], true
+		}
+	}
+	return "", false
+}
+
+// defaultExpiry 默认的过期时间，读取JWT声明、令牌内省结果和API Key
+func defaultExpiry(ctx context.Context) time.Time {
+	if claims, ok := ClaimsFromContext(ctx); ok {
+		return claims.ExpiresAt
+	}
+	if info, ok := IntrospectionFromContext(ctx); ok {
+		return info.ExpiresAt
+	}
+	if key, ok := APIKeyFromContext(ctx); ok {
+		return key.ExpiresAt
+	}
+	return time.Time{}
+}
+
+// NewCachingAuthFunc 创建缓存认证结果的认证函数
+// 认证结果默认按方法名和凭证的哈希缓存，相同方法和凭证的并发认证合并为一次。
+// 缓存命中时，认证函数写入上下文的值会叠加到当前请求的上下文上。
+// 缓存不保留执行认证函数时的请求上下文，认证函数返回后，其返回的上下文只能读取认证函数写入的值
+//
+// 参数:
+//   - authFunc: 被缓存的认证函数
+//   - opts: 可选的配置选项
+//
+// 返回值:
+//   - AuthFunc: 带缓存的认证函数
+func NewCachingAuthFunc(authFunc AuthFunc, opts ...AuthCacheOption) AuthFunc {
+	o := &authCacheOptions{
+		ttl:         5 * time.Minute,
+		negativeTTL: 10 * time.Second,
+		maxEntries:  10000,
+		keyFunc:     defaultCacheKey,
+		expiryFunc:  defaultExpiry,
+		now:         time.Now,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	c := &authCache{authFunc: authFunc, opts: o, order: list.New(), entries: make(map[string]*list.Element)}
+	return c.authenticate
+}
+
+// authResult 缓存的认证结果
+type authResult struct {
+	key string
+	// result 认证函数返回的上下文，只能读取认证函数写入的值
+	result    context.Context
+	err       error
+	expiresAt time.Time
+}
+
+// authCache 带LRU淘汰的认证结果缓存
+type authCache struct {
+	authFunc AuthFunc
+	opts     *authCacheOptions
+	group    singleflight.Group
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+// authenticate 实现AuthFunc
+func (c *authCache) authenticate(ctx context.Context, fullMethodName string) (context.Context, error) {
+	credential, ok := c.opts.keyFunc(ctx, fullMethodName)
+	if !ok {
+		return c.authFunc(ctx, fullMethodName)
+	}
+	sum := sha256.Sum256([]byte(credential))
+	key := hex.EncodeToString(sum[:])
+	if res, ok := c.get(key); ok {
+		return res.apply(ctx)
+	}
+	ch := c.group.DoChan(key, func() (any, error) {
+		return c.load(ctx, key, fullMethodName), nil
+	})
+	select {
+	case r := <-ch:
+		return r.Val.(*authResult).apply(ctx)
+	case <-ctx.Done():
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+}
+
+// load 执行认证函数并缓存结果
+// 认证函数在不会被取消的上下文中执行，返回后断开与请求上下文的关联，缓存的结果不再引用请求
+func (c *authCache) load(ctx context.Context, key, fullMethodName string) *authResult {
+	detachable := newDetachableContext(ctx)
+	result, err := c.authFunc(detachable, fullMethodName)
+	detachable.detach()
+	res := &authResult{key: key, result: result, err: err}
+	now := c.opts.now()
+	switch {
+	case err == nil:
+		res.expiresAt = now.Add(c.opts.ttl)
+		// 缓存时间不超过凭证的过期时间
+		if expiry := c.opts.expiryFunc(result); !expiry.IsZero() && expiry.Before(res.expiresAt) {
+			res.expiresAt = expiry
+		}
+	case c.opts.negativeTTL > 0 && isCacheableAuthError(err):
+		res.expiresAt = now.Add(c.opts.negativeTTL)
+	default:
+		return res
+	}
+	if res.expiresAt.After(now) {
+		c.put(res)
+	}
+	return res
+}
+
+// isCacheableAuthError 判断认证错误是否可以缓存
+func isCacheableAuthError(err error) bool {
+	switch status.Code(err) {
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return true
+	default:
+		return false
+	}
+}
+
+// get 获取未过期的缓存结果
+func (c *authCache) get(key string) (*authResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	res := elem.Value.(*authResult)
+	if !c.opts.now().Before(res.expiresAt) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return res, true
+}
+
+// put 缓存结果，超出容量时淘汰最近最少使用的条目
+func (c *authCache) put(res *authResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[res.key]; ok {
+		elem.Value = res
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[res.key] = c.order.PushFront(res)
+	for c.order.Len() > c.opts.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*authResult).key)
+	}
+}
+
+// apply 将认证结果应用到当前请求的上下文
+func (r *authResult) apply(ctx context.Context) (context.Context, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &authResultContext{Context: ctx, result: r.result}, nil
+}
+
+// authResultContext 将缓存的认证结果叠加到当前请求上下文上
+// 认证函数写入的值从缓存的上下文中读取，其余值以及取消和截止时间都来自当前请求
+type authResultContext struct {
+	context.Context
+	result context.Context
+}
+
+// Value 实现context.Context接口
+func (c *authResultContext) Value(key any) any {
+	if v := c.result.Value(key); v != nil {
+		return v
+	}
+	return c.Context.Value(key)
+}
+
+// detachableContext 执行认证函数时使用的上下文
+// 不会被取消，断开前可以读取请求上下文中的值，断开后不再引用请求上下文
+type detachableContext struct {
+	context.Context
+	parent atomic.Pointer[context.Context]
+}
+
+// newDetachableContext 创建关联到请求上下文的可断开上下文
+func newDetachableContext(parent context.Context) *detachableContext {
+	c := &detachableContext{Context: context.Background()}
+	c.parent.Store(&parent)
+	return c
+}
+
+// detach 断开与请求上下文的关联
+func (c *detachableContext) detach() {
+	c.parent.Store(nil)
+}
+
+// Value 实现context.Context接口
+func (c *detachableContext) Value(key any) any {
+	if parent := c.parent.Load(); parent != nil {
+		return (*parent).Value(key)
+	}
+	return nil
+}
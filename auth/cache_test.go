@@ -0,0 +1,230 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// subjectKey 测试认证函数写入上下文的键
+type subjectKey struct{}
+
+// tokenContext 创建携带Bearer令牌的请求上下文
+func tokenContext(token string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(headerAuthorize, "Bearer "+token))
+}
+
+func TestCachingAuthFunc(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func(o *authCacheOptions) { o.now = func() time.Time { return now } }
+	var calls atomic.Int32
+	authFunc := func(ctx context.Context, fullMethodName string) (context.Context, error) {
+		calls.Add(1)
+		md, _ := metadata.FromIncomingContext(ctx)
+		switch md.Get(headerAuthorize)[0] {
+		case "Bearer good":
+			return context.WithValue(ctx, subjectKey{}, "alice"), nil
+		case "Bearer short":
+			return context.WithValue(ctx, claimsKey{}, &Claims{Subject: "bob", ExpiresAt: now.Add(time.Minute)}), nil
+		case "Bearer down":
+			return nil, status.Error(codes.Unavailable, "backend unavailable")
+		default:
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+	}
+	cached := NewCachingAuthFunc(authFunc, clock, WithCacheTTL(5*time.Minute), WithNegativeCacheTTL(10*time.Second))
+
+	t.Run("成功结果被缓存并叠加到当前请求", func(t *testing.T) {
+		calls.Store(0)
+		for i := 0; i < 3; i++ {
+			ctx := context.WithValue(tokenContext("good"), requestKey{}, i)
+			newCtx, err := cached(ctx, "/test/method")
+			require.NoError(t, err)
+			assert.Equal(t, "alice", newCtx.Value(subjectKey{}))
+			// 请求自身的值来自当前请求
+			req, _ := RequestFromContext(newCtx)
+			assert.Equal(t, i, req)
+		}
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("缓存不保留首个请求的上下文", func(t *testing.T) {
+		var result context.Context
+		retain := NewCachingAuthFunc(func(ctx context.Context, fullMethodName string) (context.Context, error) {
+			assert.Equal(t, "first", ctx.Value(requestKey{}), "认证函数执行时可以读取请求上下文")
+			result = context.WithValue(ctx, subjectKey{}, "alice")
+			return result, nil
+		})
+		_, err := retain(context.WithValue(tokenContext("good"), requestKey{}, "first"), "/test/method")
+		require.NoError(t, err)
+		assert.Nil(t, result.Value(requestKey{}))
+		assert.Equal(t, "alice", result.Value(subjectKey{}))
+
+		newCtx, err := retain(context.WithValue(tokenContext("good"), requestKey{}, "second"), "/test/method")
+		require.NoError(t, err)
+		assert.Equal(t, "alice", newCtx.Value(subjectKey{}))
+		assert.Equal(t, "second", newCtx.Value(requestKey{}))
+	})
+
+	t.Run("失败结果缓存较短时间", func(t *testing.T) {
+		calls.Store(0)
+		for i := 0; i < 2; i++ {
+			_, err := cached(tokenContext("bad"), "/test/method")
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		}
+		assert.Equal(t, int32(1), calls.Load())
+		now = now.Add(11 * time.Second)
+		_, _ = cached(tokenContext("bad"), "/test/method")
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("暂时性错误不缓存", func(t *testing.T) {
+		calls.Store(0)
+		for i := 0; i < 2; i++ {
+			_, err := cached(tokenContext("down"), "/test/method")
+			assert.Equal(t, codes.Unavailable, status.Code(err))
+		}
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("缓存时间不超过令牌过期时间", func(t *testing.T) {
+		calls.Store(0)
+		newCtx, err := cached(tokenContext("short"), "/test/method")
+		require.NoError(t, err)
+		claims, ok := ClaimsFromContext(newCtx)
+		require.True(t, ok)
+		assert.Equal(t, "bob", claims.Subject)
+		now = now.Add(30 * time.Second)
+		_, _ = cached(tokenContext("short"), "/test/method")
+		assert.Equal(t, int32(1), calls.Load())
+		now = now.Add(31 * time.Second)
+		_, _ = cached(tokenContext("short"), "/test/method")
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("没有凭证时不缓存", func(t *testing.T) {
+		calls.Store(0)
+		noCredential := func(ctx context.Context, fullMethodName string) (context.Context, error) {
+			calls.Add(1)
+			return ctx, nil
+		}
+		cached := NewCachingAuthFunc(noCredential)
+		for i := 0; i < 2; i++ {
+			_, err := cached(context.Background(), "/test/method")
+			require.NoError(t, err)
+		}
+		assert.Equal(t, int32(2), calls.Load())
+	})
+}
+
+func TestCachingAuthFunc_LRU(t *testing.T) {
+	var mu sync.Mutex
+	calls := map[string]int{}
+	authFunc := func(ctx context.Context, fullMethodName string) (context.Context, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		mu.Lock()
+		calls[md.Get(headerAuthorize)[0]]++
+		mu.Unlock()
+		return ctx, nil
+	}
+	cached := NewCachingAuthFunc(authFunc, WithMaxCacheEntries(2))
+
+	for _, token := range []string{"a", "b", "a", "c", "a", "b"} {
+		_, err := cached(tokenContext(token), "/test/method")
+		require.NoError(t, err)
+	}
+	// 加入c时淘汰最近最少使用的b，a一直被访问而保留
+	assert.Equal(t, map[string]int{"Bearer a": 1, "Bearer b": 2, "Bearer c": 1}, calls)
+}
+
+func TestCachingAuthFunc_Singleflight(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	authFunc := func(ctx context.Context, fullMethodName string) (context.Context, error) {
+		calls.Add(1)
+		<-release
+		return context.WithValue(ctx, subjectKey{}, "alice"), nil
+	}
+	cached := NewCachingAuthFunc(authFunc)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, err := cached(tokenContext("good"), "/test/method")
+			if err == nil && ctx.Value(subjectKey{}) != "alice" {
+				err = fmt.Errorf("unexpected subject %v", ctx.Value(subjectKey{}))
+			}
+			errs <- err
+		}()
+	}
+	// 等待所有调用进入合并后再放行
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), calls.Load())
+
+	t.Run("等待时请求取消", func(t *testing.T) {
+		block := make(chan struct{})
+		defer close(block)
+		cached := NewCachingAuthFunc(func(ctx context.Context, fullMethodName string) (context.Context, error) {
+			<-block
+			return ctx, nil
+		})
+		ctx, cancel := context.WithCancel(tokenContext("slow"))
+		cancel()
+		_, err := cached(ctx, "/test/method")
+		assert.Equal(t, codes.Canceled, status.Code(err))
+	})
+}
+
+func TestCachingAuthFunc_KeyFunc(t *testing.T) {
+	var calls atomic.Int32
+	// 只允许调用/a的认证函数
+	authFunc := func(ctx context.Context, fullMethodName string) (context.Context, error) {
+		calls.Add(1)
+		if fullMethodName != "/a" {
+			return nil, status.Error(codes.PermissionDenied, "method not allowed")
+		}
+		return ctx, nil
+	}
+
+	t.Run("默认缓存键包含方法名", func(t *testing.T) {
+		calls.Store(0)
+		cached := NewCachingAuthFunc(authFunc)
+		for _, method := range []string{"/a", "/b", "/a", "/b"} {
+			_, err := cached(tokenContext("good"), method)
+			if method == "/a" {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, codes.PermissionDenied, status.Code(err))
+			}
+		}
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("与方法无关时只使用凭证", func(t *testing.T) {
+		calls.Store(0)
+		cached := NewCachingAuthFunc(authFunc, WithCacheKeyFunc(credentialCacheKey))
+		for _, method := range []string{"/a", "/b"} {
+			_, err := cached(tokenContext("good"), method)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, int32(1), calls.Load())
+	})
+}
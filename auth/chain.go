@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorDomain 认证失败时ErrorInfo中的错误域
+const ErrorDomain = "auth.grpc-middleware"
+
+// 认证失败时ErrorInfo中的原因
+const (
+	// ReasonMissingCredentials 请求没有携带任何方案的凭证
+	ReasonMissingCredentials = "MISSING_CREDENTIALS"
+	// ReasonInvalidCredentials 请求携带的凭证无效
+	ReasonInvalidCredentials = "INVALID_CREDENTIALS"
+)
+
+// headerWWWAuthenticate 认证失败时提示可用认证方案的响应头
+const headerWWWAuthenticate = "www-authenticate"
+
+// SchemeAnonymous 允许匿名访问时没有凭证的请求使用的方案名称
+const SchemeAnonymous = "anonymous"
+
+// Scheme 认证链中的一种认证方案
+type Scheme struct {
+	// Name 方案名称，例如"Bearer"，认证成功后可通过SchemeFromContext获取
+	Name string
+	// Present 判断请求是否携带该方案的凭证，为nil时总是使用该方案
+	Present func(ctx context.Context) bool
+	// AuthFunc 校验该方案凭证的认证函数
+	AuthFunc AuthFunc
+	// Challenge WWW-Authenticate风格的提示，例如`Bearer realm="api"`，为空时使用Name
+	Challenge string
+}
+
+// challenge 返回方案的WWW-Authenticate提示
+func (s *Scheme) challenge() string {
+	if s.Challenge != "" {
+		return s.Challenge
+	}
+	return s.Name
+}
+
+// BearerScheme 创建Bearer令牌方案，authorization头以"Bearer "开头时视为携带凭证
+//
+// 参数:
+//   - authFunc: 校验令牌的认证函数，例如NewJWTAuthFunc的返回值
+//
+// 返回值:
+//   - Scheme: 认证方案
+func BearerScheme(authFunc AuthFunc) Scheme {
+	return Scheme{
+		Name: "Bearer",
+		Present: func(ctx context.Context) bool {
+			vals := metadata.ValueFromIncomingContext(ctx, headerAuthorize)
+			if len(vals) == 0 {
+				return false
+			}
+			scheme, _, _ := strings.Cut(vals[0], " ")
+			return strings.EqualFold(scheme, "Bearer")
+		},
+		AuthFunc: authFunc,
+	}
+}
+
+// APIKeyScheme 创建API Key方案，API Key头不为空时视为携带凭证
+//
+// 参数:
+//   - authFunc: 校验API Key的认证函数，例如NewAPIKeyAuthFunc的返回值
+//   - opts: 与创建authFunc时相同的选项，用于确定API Key头
+//
+// 返回值:
+//   - Scheme: 认证方案
+func APIKeyScheme(authFunc AuthFunc, opts ...APIKeyOption) Scheme {
+	o := &apiKeyOptions{header: headerAPIKey}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return Scheme{
+		Name: "ApiKey",
+		Present: func(ctx context.Context) bool {
+			vals := metadata.ValueFromIncomingContext(ctx, o.header)
+			return len(vals) > 0 && vals[0] != ""
+		},
+		AuthFunc:  authFunc,
+		Challenge: `ApiKey header="` + o.header + `"`,
+	}
+}
+
+// MTLSScheme 创建双向TLS方案，客户端在TLS握手中提供了证书时视为携带凭证
+//
+// 参数:
+//   - authFunc: 校验客户端证书的认证函数，例如NewMTLSAuthFunc的返回值
+//
+// 返回值:
+//   - Scheme: 认证方案
+func MTLSScheme(authFunc AuthFunc) Scheme {
+	return Scheme{
+		Name: "mTLS",
+		Present: func(ctx context.Context) bool {
+			p, ok := peer.FromContext(ctx)
+			if !ok {
+				return false
+			}
+			tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+			return ok && len(tlsInfo.State.PeerCertificates) > 0
+		},
+		AuthFunc: authFunc,
+	}
+}
+
+// schemeKey 认证成功的方案在上下文中的键
+type schemeKey struct{}
+
+// SchemeFromContext 从上下文中获取认证链中认证成功的方案名称
+//
+// 参数:
+//   - ctx: 请求上下文
+//
+// 返回值:
+//   - string: 方案名称，匿名访问时为SchemeAnonymous
+//   - bool: 上下文中是否有方案名称
+func SchemeFromContext(ctx context.Context) (string, bool) {
+	scheme, ok := ctx.Value(schemeKey{}).(string)
+	return scheme, ok
+}
+
+// chainOptions 存储认证链的配置选项
+type chainOptions struct {
+	// anonymous 没有任何凭证时是否允许匿名访问
+	anonymous bool
+}
+
+// ChainOption 定义认证链配置选项的函数类型
+type ChainOption func(*chainOptions)
+
+// WithAnonymous 允许没有携带任何凭证的请求匿名访问，方案名称为SchemeAnonymous
+// 携带了无效凭证的请求仍然被拒绝
+//
+// 返回值:
+//   - ChainOption: 设置匿名访问选项的函数
+func WithAnonymous() ChainOption {
+	return func(o *chainOptions) {
+		o.anonymous = true
+	}
+}
+
+// NewChainAuthFunc 创建按顺序尝试多种认证方案的认证函数
+// 按顺序使用第一个携带了凭证的方案进行认证，凭证无效时直接拒绝，不再尝试后续方案。
+// 认证成功后方案名称写入上下文，可通过SchemeFromContext获取。
+// 携带了authorization头但没有方案能够处理时视为凭证无效，即使允许匿名访问也会拒绝。
+// 没有凭证或凭证无效时返回Unauthenticated，ErrorInfo的元数据和www-authenticate响应头中
+// 带有可用方案的提示；认证函数返回的其他错误（例如PermissionDenied）原样返回
+//
+// 参数:
+//   - schemes: 认证方案，按顺序尝试
+//   - opts: 可选的配置选项
+//
+// 返回值:
+//   - AuthFunc: 认证函数
+func NewChainAuthFunc(schemes []Scheme, opts ...ChainOption) AuthFunc {
+	o := &chainOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	challenges := make([]string, 0, len(schemes))
+	for i := range schemes {
+		challenges = append(challenges, schemes[i].challenge())
+	}
+	allChallenges := strings.Join(challenges, ", ")
+	return func(ctx context.Context, fullMethodName string) (context.Context, error) {
+		for i := range schemes {
+			scheme := &schemes[i]
+			if scheme.Present != nil && !scheme.Present(ctx) {
+				continue
+			}
+			newCtx, err := scheme.AuthFunc(ctx, fullMethodName)
+			if err != nil {
+				if status.Code(err) != codes.Unauthenticated {
+					return nil, err
+				}
+				return nil, challengeError(ctx, status.Convert(err).Message(), ReasonInvalidCredentials, scheme.Name, scheme.challenge())
+			}
+			return context.WithValue(newCtx, schemeKey{}, scheme.Name), nil
+		}
+		// 没有方案能够处理的authorization头视为无效凭证，不按没有凭证处理
+		if vals := metadata.ValueFromIncomingContext(ctx, headerAuthorize); len(vals) > 0 && vals[0] != "" {
+			return nil, challengeError(ctx, "unsupported authorization scheme", ReasonInvalidCredentials, "", allChallenges)
+		}
+		if o.anonymous {
+			return context.WithValue(ctx, schemeKey{}, SchemeAnonymous), nil
+		}
+		return nil, challengeError(ctx, "missing credentials", ReasonMissingCredentials, "", allChallenges)
+	}
+}
+
+// challengeError 创建带有WWW-Authenticate提示的Unauthenticated错误，并尽量写入响应头
+func challengeError(ctx context.Context, msg, reason, scheme, challenge string) error {
+	// 不在服务端调用中或响应头已发送时写入失败，提示仍然保留在错误详情中
+	_ = grpc.SetHeader(ctx, metadata.Pairs(headerWWWAuthenticate, challenge))
+	md := map[string]string{headerWWWAuthenticate: challenge}
+	if scheme != "" {
+		md["scheme"] = scheme
+	}
+	st := status.New(codes.Unauthenticated, msg)
+	if detailed, err := st.WithDetails(&errdetails.ErrorInfo{Reason: reason, Domain: ErrorDomain, Metadata: md}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
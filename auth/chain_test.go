@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// headerTransportStream 记录响应头的测试传输流
+type headerTransportStream struct {
+	header metadata.MD
+}
+
+func (s *headerTransportStream) Method() string { return "/test/method" }
+func (s *headerTransportStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+func (s *headerTransportStream) SendHeader(md metadata.MD) error { return s.SetHeader(md) }
+func (s *headerTransportStream) SetTrailer(md metadata.MD) error { return nil }
+
+// errorInfo 从错误中提取ErrorInfo
+func errorInfo(t *testing.T, err error) *errdetails.ErrorInfo {
+	t.Helper()
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info
+		}
+	}
+	t.Fatalf("no ErrorInfo in %v", err)
+	return nil
+}
+
+func TestChainAuthFunc(t *testing.T) {
+	cert := newClientCert(t, "orders", nil, "spiffe://example.org/ns/prod/sa/orders")
+	store, err := NewMemoryAPIKeyStore(APIKey{Hash: HashAPIKey("script-key"), Owner: "ci"})
+	require.NoError(t, err)
+	bearer := func(ctx context.Context, fullMethodName string) (context.Context, error) {
+		token, err := AuthFromMD(ctx, "Bearer")
+		if err != nil || token != "good" {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		return ctx, nil
+	}
+	schemes := []Scheme{
+		BearerScheme(bearer),
+		APIKeyScheme(NewAPIKeyAuthFunc(store)),
+		MTLSScheme(NewMTLSAuthFunc(WithAllowedPeers("/*/*", "spiffe://example.org/ns/prod/*"))),
+	}
+	chain := NewChainAuthFunc(schemes, WithAnonymous())
+	strict := NewChainAuthFunc(schemes)
+
+	tests := []struct {
+		name       string
+		authFunc   AuthFunc
+		ctx        context.Context
+		wantScheme string
+		wantCode   codes.Code
+		wantReason string
+	}{
+		{"Bearer令牌", chain, tokenContext("good"), "Bearer", codes.OK, ""},
+		{"API Key", chain, metadata.NewIncomingContext(context.Background(), metadata.Pairs(headerAPIKey, "script-key")), "ApiKey", codes.OK, ""},
+		{"客户端证书", chain, tlsPeerContext(cert), "mTLS", codes.OK, ""},
+		{"没有凭证时匿名访问", chain, context.Background(), SchemeAnonymous, codes.OK, ""},
+		{"无效令牌不回退到匿名", chain, tokenContext("bad"), "", codes.Unauthenticated, ReasonInvalidCredentials},
+		{"无效令牌不回退到其他方案", chain, metadata.NewIncomingContext(tlsPeerContext(cert), metadata.Pairs(headerAuthorize, "Bearer bad")), "", codes.Unauthenticated, ReasonInvalidCredentials},
+		{"其他认证方式的authorization头视为无效凭证", strict, metadata.NewIncomingContext(context.Background(), metadata.Pairs(headerAuthorize, "Basic dXNlcg==")), "", codes.Unauthenticated, ReasonInvalidCredentials},
+		{"其他认证方式的authorization头不回退到匿名", chain, metadata.NewIncomingContext(context.Background(), metadata.Pairs(headerAuthorize, "Basic dXNlcg==")), "", codes.Unauthenticated, ReasonInvalidCredentials},
+		{"拼写错误的方案不回退到匿名", chain, metadata.NewIncomingContext(context.Background(), metadata.Pairs(headerAuthorize, "Baerer good")), "", codes.Unauthenticated, ReasonInvalidCredentials},
+		{"不允许匿名时没有凭证", strict, context.Background(), "", codes.Unauthenticated, ReasonMissingCredentials},
+		{"PermissionDenied原样返回", chain, tlsPeerContext(newClientCert(t, "x", nil, "spiffe://other.org/x")), "", codes.PermissionDenied, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, err := tt.authFunc(tt.ctx, "/orders.Orders/Get")
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			if tt.wantCode == codes.OK {
+				scheme, ok := SchemeFromContext(ctx)
+				assert.True(t, ok)
+				assert.Equal(t, tt.wantScheme, scheme)
+				return
+			}
+			if tt.wantReason != "" {
+				info := errorInfo(t, err)
+				assert.Equal(t, tt.wantReason, info.Reason)
+				assert.Equal(t, ErrorDomain, info.Domain)
+			}
+		})
+	}
+}
+
+func TestChainAuthFunc_Challenge(t *testing.T) {
+	reject := func(ctx context.Context, fullMethodName string) (context.Context, error) {
+		return nil, status.Error(codes.Unauthenticated, "token expired")
+	}
+	chain := NewChainAuthFunc([]Scheme{
+		{Name: "Bearer", Present: BearerScheme(nil).Present, AuthFunc: reject, Challenge: `Bearer realm="api"`},
+		APIKeyScheme(reject, WithAPIKeyHeader("x-token")),
+	})
+
+	t.Run("没有凭证时提示所有方案", func(t *testing.T) {
+		stream := &headerTransportStream{}
+		_, err := chain(grpc.NewContextWithServerTransportStream(context.Background(), stream), "/test/method")
+		assert.Equal(t, "missing credentials", status.Convert(err).Message())
+		want := `Bearer realm="api", ApiKey header="x-token"`
+		assert.Equal(t, want, errorInfo(t, err).Metadata[headerWWWAuthenticate])
+		assert.Equal(t, []string{want}, stream.header.Get(headerWWWAuthenticate))
+	})
+
+	t.Run("无法处理的authorization头提示所有方案", func(t *testing.T) {
+		stream := &headerTransportStream{}
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(headerAuthorize, "Basic dXNlcg=="))
+		_, err := chain(grpc.NewContextWithServerTransportStream(ctx, stream), "/test/method")
+		assert.Equal(t, "unsupported authorization scheme", status.Convert(err).Message())
+		want := `Bearer realm="api", ApiKey header="x-token"`
+		assert.Equal(t, want, errorInfo(t, err).Metadata[headerWWWAuthenticate])
+		assert.Equal(t, []string{want}, stream.header.Get(headerWWWAuthenticate))
+	})
+
+	t.Run("凭证无效时提示该方案", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-token", "k"))
+		_, err := chain(ctx, "/test/method")
+		assert.Equal(t, "token expired", status.Convert(err).Message())
+		info := errorInfo(t, err)
+		assert.Equal(t, `ApiKey header="x-token"`, info.Metadata[headerWWWAuthenticate])
+		assert.Equal(t, "ApiKey", info.Metadata["scheme"])
+	})
+
+	t.Run("没有判断函数时总是使用该方案", func(t *testing.T) {
+		chain := NewChainAuthFunc([]Scheme{{Name: "Custom", AuthFunc: func(ctx context.Context, fullMethodName string) (context.Context, error) {
+			return ctx, nil
+		}}})
+		ctx, err := chain(context.Background(), "/test/method")
+		require.NoError(t, err)
+		scheme, _ := SchemeFromContext(ctx)
+		assert.Equal(t, "Custom", scheme)
+	})
+}
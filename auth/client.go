@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryClientInterceptor 创建为一元调用注入令牌的客户端拦截器
+// 令牌由TokenSource提供并被缓存，过期前在后台刷新；
+// 调用返回Unauthenticated时强制刷新令牌并重试一次。
+// 调用方已在出站元数据中设置authorization时不注入令牌
+//
+// 参数:
+//   - source: 令牌源，不是*CachingTokenSource时自动包装缓存
+//   - opts: 可选的配置选项
+//
+// 返回值:
+//   - grpc.UnaryClientInterceptor: gRPC一元客户端拦截器
+func UnaryClientInterceptor(source TokenSource, opts ...Option) grpc.UnaryClientInterceptor {
+	o := defaultOptions().apply(opts...)
+	tokens := newClientTokenSource(source, o)
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if hasAuthorization(ctx) {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		token, err := clientToken(ctx, tokens)
+		if err != nil {
+			return err
+		}
+		err = invoker(withToken(ctx, token), method, req, reply, cc, opts...)
+		if status.Code(err) != codes.Unauthenticated {
+			return err
+		}
+		// 令牌被拒绝，强制刷新后重试一次
+		fresh, refreshErr := tokens.Refresh(ctx, token)
+		if refreshErr != nil {
+			return err
+		}
+		return invoker(withToken(ctx, fresh), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor 创建为流式调用注入令牌的客户端拦截器
+// 行为与UnaryClientInterceptor相同，但只在创建流时返回Unauthenticated的情况下重试，
+// 流建立后在收发消息时发生的认证错误直接返回给调用方
+//
+// 参数:
+//   - source: 令牌源，不是*CachingTokenSource时自动包装缓存
+//   - opts: 可选的配置选项
+//
+// 返回值:
+//   - grpc.StreamClientInterceptor: gRPC流式客户端拦截器
+func StreamClientInterceptor(source TokenSource, opts ...Option) grpc.StreamClientInterceptor {
+	o := defaultOptions().apply(opts...)
+	tokens := newClientTokenSource(source, o)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if hasAuthorization(ctx) {
+			return streamer(ctx, desc, cc, method, opts...)
+		}
+		token, err := clientToken(ctx, tokens)
+		if err != nil {
+			return nil, err
+		}
+		stream, err := streamer(withToken(ctx, token), desc, cc, method, opts...)
+		if status.Code(err) != codes.Unauthenticated {
+			return stream, err
+		}
+		// 令牌被拒绝，强制刷新后重试一次
+		fresh, refreshErr := tokens.Refresh(ctx, token)
+		if refreshErr != nil {
+			return stream, err
+		}
+		return streamer(withToken(ctx, fresh), desc, cc, method, opts...)
+	}
+}
+
+// newClientTokenSource 返回带缓存的令牌源
+func newClientTokenSource(source TokenSource, o *options) *CachingTokenSource {
+	if caching, ok := source.(*CachingTokenSource); ok {
+		return caching
+	}
+	return NewCachingTokenSource(source, o.refreshBefore)
+}
+
+// clientToken 获取令牌，失败时转换为gRPC状态错误
+func clientToken(ctx context.Context, tokens *CachingTokenSource) (*Token, error) {
+	token, err := tokens.Token(ctx)
+	if err == nil {
+		return token, nil
+	}
+	if ctx.Err() != nil {
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+	return nil, status.Errorf(codes.Unauthenticated, "failed to get token: %v", err)
+}
+
+// hasAuthorization 判断出站元数据中是否已有authorization
+func hasAuthorization(ctx context.Context) bool {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	return len(md.Get(headerAuthorize)) > 0
+}
+
+// withToken 将令牌写入出站元数据
+func withToken(ctx context.Context, token *Token) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, headerAuthorize, token.authorization())
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// outgoingAuthorization 返回出站元数据中的authorization
+func outgoingAuthorization(ctx context.Context) []string {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	return md.Get("authorization")
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	tests := []struct {
+		name      string
+		rejects   int
+		wantErr   codes.Code
+		wantSeen  []string
+		wantFetch int32
+	}{
+		{"注入令牌", 0, codes.OK, []string{"Bearer token-1"}, 1},
+		{"认证失败后刷新并重试一次", 1, codes.OK, []string{"Bearer token-1", "Bearer token-2"}, 2},
+		{"只重试一次", 2, codes.Unauthenticated, []string{"Bearer token-1", "Bearer token-2"}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &countingSource{ttl: time.Hour, now: time.Now}
+			interceptor := UnaryClientInterceptor(source)
+
+			var seen []string
+			invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+				auth := outgoingAuthorization(ctx)
+				require.Len(t, auth, 1, "重试时不重复添加authorization")
+				seen = append(seen, auth[0])
+				if len(seen) <= tt.rejects {
+					return status.Error(codes.Unauthenticated, "token revoked")
+				}
+				return nil
+			}
+			err := interceptor(context.Background(), "/test/method", nil, nil, nil, invoker)
+			assert.Equal(t, tt.wantErr, status.Code(err))
+			assert.Equal(t, tt.wantSeen, seen)
+			assert.Equal(t, tt.wantFetch, source.calls.Load())
+		})
+	}
+}
+
+func TestUnaryClientInterceptor_PassThrough(t *testing.T) {
+	source := &countingSource{ttl: time.Hour, now: time.Now}
+	interceptor := UnaryClientInterceptor(source)
+
+	t.Run("调用方已设置authorization", func(t *testing.T) {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Basic custom")
+		invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			assert.Equal(t, []string{"Basic custom"}, outgoingAuthorization(ctx))
+			return status.Error(codes.Unauthenticated, "bad credentials")
+		}
+		err := interceptor(ctx, "/test/method", nil, nil, nil, invoker)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		assert.Equal(t, int32(0), source.calls.Load())
+	})
+
+	t.Run("非认证错误不重试", func(t *testing.T) {
+		calls := 0
+		invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			calls++
+			return status.Error(codes.PermissionDenied, "denied")
+		}
+		err := interceptor(context.Background(), "/test/method", nil, nil, nil, invoker)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func TestUnaryClientInterceptor_TokenError(t *testing.T) {
+	source := &countingSource{ttl: time.Hour, now: time.Now, failAt: 1}
+	interceptor := UnaryClientInterceptor(source)
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		t.Fatal("获取令牌失败时不应发起调用")
+		return nil
+	}
+	err := interceptor(context.Background(), "/test/method", nil, nil, nil, invoker)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestStreamClientInterceptor(t *testing.T) {
+	source := &countingSource{ttl: time.Hour, now: time.Now}
+	interceptor := StreamClientInterceptor(NewCachingTokenSource(source, time.Minute))
+
+	var seen []string
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		seen = append(seen, outgoingAuthorization(ctx)...)
+		if len(seen) == 1 {
+			return nil, status.Error(codes.Unauthenticated, "token revoked")
+		}
+		return nil, nil
+	}
+	_, err := interceptor(context.Background(), &grpc.StreamDesc{}, nil, "/test/stream", streamer)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Bearer token-1", "Bearer token-2"}, seen)
+}
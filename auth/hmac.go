@@ -0,0 +1,323 @@
+package auth
+
+import (
+	"container/heap"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// 签名相关的元数据字段名
+const (
+	// headerSignatureKeyID 签名密钥ID
+	headerSignatureKeyID = "x-signature-key-id"
+	// headerSignatureTimestamp 签名时间，Unix秒
+	headerSignatureTimestamp = "x-signature-timestamp"
+	// headerSignatureNonce 随机数
+	headerSignatureNonce = "x-signature-nonce"
+	// headerSignatureDigest 请求消息的SHA-256摘要，base64编码
+	headerSignatureDigest = "x-signature-digest"
+	// headerSignature HMAC-SHA256签名，base64编码
+	headerSignature = "x-signature"
+)
+
+// SignedRequest 通过签名校验的请求信息
+type SignedRequest struct {
+	// KeyID 签名使用的密钥ID
+	KeyID string
+	// Timestamp 签名时间
+	Timestamp time.Time
+	// Nonce 随机数
+	Nonce string
+}
+
+// signedRequestKey 签名信息在上下文中的键
+type signedRequestKey struct{}
+
+// SignedRequestFromContext 从上下文中获取通过签名校验的请求信息
+//
+// 参数:
+//   - ctx: 请求上下文
+//
+// 返回值:
+//   - *SignedRequest: 签名信息
+//   - bool: 上下文中是否有签名信息
+func SignedRequestFromContext(ctx context.Context) (*SignedRequest, bool) {
+	req, ok := ctx.Value(signedRequestKey{}).(*SignedRequest)
+	return req, ok
+}
+
+// hmacOptions 存储HMAC签名的配置选项
+type hmacOptions struct {
+	// maxSkew 签名时间与当前时间允许的最大偏差
+	maxSkew time.Duration
+	// nonceCacheSize 随机数缓存的容量
+	nonceCacheSize int
+	// now 获取当前时间，便于测试
+	now func() time.Time
+}
+
+// defaultNonceCacheSize 默认的随机数缓存容量
+const defaultNonceCacheSize = 100000
+
+// HMACOption 定义HMAC签名配置选项的函数类型
+type HMACOption func(*hmacOptions)
+
+// WithMaxSkew 设置签名时间与当前时间允许的最大偏差，默认5分钟
+// 随机数在该时间的两倍内被记住，超出偏差的签名直接拒绝
+//
+// 参数:
+//   - d: 最大偏差
+//
+// 返回值:
+//   - HMACOption: 设置最大偏差选项的函数
+func WithMaxSkew(d time.Duration) HMACOption {
+	return func(o *hmacOptions) {
+		o.maxSkew = d
+	}
+}
+
+// WithNonceCacheSize 设置随机数缓存的容量，默认100000
+// 随机数最长被记住最大偏差的两倍，容量应不小于峰值请求速率乘以该时间。
+// 缓存中都是未过期的随机数时不淘汰，以ResourceExhausted拒绝新的请求，避免被挤出的随机数可以重放
+//
+// 参数:
+//   - size: 缓存容量
+//
+// 返回值:
+//   - HMACOption: 设置缓存容量选项的函数
+func WithNonceCacheSize(size int) HMACOption {
+	return func(o *hmacOptions) {
+		o.nonceCacheSize = size
+	}
+}
+
+// newHMACOptions 创建默认的HMAC签名配置并应用选项
+func newHMACOptions(opts ...HMACOption) *hmacOptions {
+	o := &hmacOptions{maxSkew: 5 * time.Minute, nonceCacheSize: defaultNonceCacheSize, now: time.Now}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// HMACUnaryClientInterceptor 创建对一元调用签名的客户端拦截器
+// 签名覆盖方法名、时间、随机数和请求消息的摘要，请求消息按确定性序列化计算摘要
+//
+// 参数:
+//   - keyID: 密钥ID，服务端据此选择密钥，便于轮换
+//   - secret: HMAC密钥
+//   - opts: 可选的配置选项
+//
+// 返回值:
+//   - grpc.UnaryClientInterceptor: gRPC一元客户端拦截器
+func HMACUnaryClientInterceptor(keyID string, secret []byte, opts ...HMACOption) grpc.UnaryClientInterceptor {
+	o := newHMACOptions(opts...)
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		digest, err := requestDigest(req)
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to sign request: %v", err)
+		}
+		ctx, err = signContext(ctx, o, keyID, secret, method, digest)
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to sign request: %v", err)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// HMACStreamClientInterceptor 创建对流式调用签名的客户端拦截器
+// 流式调用在建立时签名，摘要为空消息的摘要，签名不覆盖流中的消息
+//
+// 参数:
+//   - keyID: 密钥ID，服务端据此选择密钥，便于轮换
+//   - secret: HMAC密钥
+//   - opts: 可选的配置选项
+//
+// 返回值:
+//   - grpc.StreamClientInterceptor: gRPC流式客户端拦截器
+func HMACStreamClientInterceptor(keyID string, secret []byte, opts ...HMACOption) grpc.StreamClientInterceptor {
+	o := newHMACOptions(opts...)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		digest, _ := requestDigest(nil)
+		ctx, err := signContext(ctx, o, keyID, secret, method, digest)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to sign request: %v", err)
+		}
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
+
+// signContext 计算签名并写入出站元数据
+func signContext(ctx context.Context, o *hmacOptions, keyID string, secret []byte, method, digest string) (context.Context, error) {
+	var raw [16]byte
+	if _, err := rand.Read(raw[:]); err != nil {
+		return nil, err
+	}
+	nonce := hex.EncodeToString(raw[:])
+	timestamp := strconv.FormatInt(o.now().Unix(), 10)
+	return metadata.AppendToOutgoingContext(ctx,
+		headerSignatureKeyID, keyID,
+		headerSignatureTimestamp, timestamp,
+		headerSignatureNonce, nonce,
+		headerSignatureDigest, digest,
+		headerSignature, sign(secret, method, timestamp, nonce, digest),
+	), nil
+}
+
+// requestDigest 计算请求消息确定性序列化后的SHA-256摘要，不是proto消息时按空消息计算
+func requestDigest(req any) (string, error) {
+	var data []byte
+	if msg, ok := req.(proto.Message); ok && msg != nil {
+		var err error
+		data, err = proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+		if err != nil {
+			return "", err
+		}
+	}
+	sum := sha256.Sum256(data)
+	return base64.StdEncoding.EncodeToString(sum[:]), nil
+}
+
+// sign 计算方法名、时间、随机数和摘要的HMAC-SHA256签名
+func sign(secret []byte, method, timestamp, nonce, digest string) string {
+	mac := hmac.New(sha256.New, secret)
+	for _, part := range []string{method, timestamp, nonce, digest} {
+		mac.Write([]byte(part))
+		mac.Write([]byte{'\n'})
+	}
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// NewHMACAuthFunc 创建校验HMAC请求签名的认证函数
+// 密钥从keys中按签名携带的密钥ID以"HS256"算法查找，例如StaticKeys{"partner-2024": secret}；
+// 签名时间超出允许偏差、随机数重复或请求消息与摘要不一致时拒绝请求。
+// 一元调用的请求消息通过RequestFromContext获取，需配合UnaryServerInterceptor使用。
+// 校验通过后签名信息写入上下文，可通过SignedRequestFromContext获取
+//
+// 参数:
+//   - keys: 签名密钥集合
+//   - opts: 可选的配置选项
+//
+// 返回值:
+//   - AuthFunc: 认证函数
+func NewHMACAuthFunc(keys KeySet, opts ...HMACOption) AuthFunc {
+	o := newHMACOptions(opts...)
+	nonces := newNonceCache(o.nonceCacheSize)
+	return func(ctx context.Context, fullMethodName string) (context.Context, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		get := func(key string) string {
+			if vals := md.Get(key); len(vals) > 0 {
+				return vals[0]
+			}
+			return ""
+		}
+		keyID, timestamp, nonce, digest, signature := get(headerSignatureKeyID), get(headerSignatureTimestamp),
+			get(headerSignatureNonce), get(headerSignatureDigest), get(headerSignature)
+		if keyID == "" || timestamp == "" || nonce == "" || digest == "" || signature == "" {
+			return nil, status.Error(codes.Unauthenticated, "missing request signature")
+		}
+		seconds, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, "invalid signature timestamp")
+		}
+		signedAt := time.Unix(seconds, 0)
+		now := o.now()
+		if signedAt.Before(now.Add(-o.maxSkew)) || signedAt.After(now.Add(o.maxSkew)) {
+			return nil, status.Error(codes.Unauthenticated, "signature timestamp out of range")
+		}
+		key, err := keys.Key(ctx, keyID, "HS256")
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, "unknown signature key")
+		}
+		secret, ok := key.([]byte)
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "unknown signature key")
+		}
+		if !hmac.Equal([]byte(signature), []byte(sign(secret, fullMethodName, timestamp, nonce, digest))) {
+			return nil, status.Error(codes.Unauthenticated, "invalid request signature")
+		}
+		// 签名有效后再校验请求内容，摘要已被签名覆盖
+		req, _ := RequestFromContext(ctx)
+		actual, err := requestDigest(req)
+		if err != nil || actual != digest {
+			return nil, status.Error(codes.Unauthenticated, "request digest mismatch")
+		}
+		// 随机数在签名时间两侧的偏差范围内都可能被重放，记住到签名时间加最大偏差之后
+		if err := nonces.add(keyID+":"+nonce, signedAt.Add(o.maxSkew), now); err != nil {
+			return nil, err
+		}
+		return context.WithValue(ctx, signedRequestKey{}, &SignedRequest{KeyID: keyID, Timestamp: signedAt, Nonce: nonce}), nil
+	}
+}
+
+// nonceEntry 随机数缓存中的条目
+type nonceEntry struct {
+	nonce     string
+	expiresAt time.Time
+}
+
+// nonceHeap 按过期时间排序的最小堆
+type nonceHeap []*nonceEntry
+
+func (h nonceHeap) Len() int           { return len(h) }
+func (h nonceHeap) Less(i, j int) bool { return h[i].expiresAt.Before(h[j].expiresAt) }
+func (h nonceHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *nonceHeap) Push(x any)        { *h = append(*h, x.(*nonceEntry)) }
+func (h *nonceHeap) Pop() any {
+	old := *h
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return entry
+}
+
+// nonceCache 有容量上限的随机数缓存，按过期时间清理过期的随机数
+// 过期时间取决于客户端的签名时间，与加入顺序无关
+type nonceCache struct {
+	mu      sync.Mutex
+	size    int
+	expiry  nonceHeap
+	entries map[string]*nonceEntry
+}
+
+// newNonceCache 创建随机数缓存，容量小于等于0时使用默认值
+func newNonceCache(size int) *nonceCache {
+	if size <= 0 {
+		size = defaultNonceCacheSize
+	}
+	return &nonceCache{size: size, entries: make(map[string]*nonceEntry)}
+}
+
+// add 记录随机数，随机数已存在且未过期时返回Unauthenticated，缓存已满时返回ResourceExhausted
+func (c *nonceCache) add(nonce string, expiresAt, now time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// 清理所有已过期的随机数
+	for len(c.expiry) > 0 && !now.Before(c.expiry[0].expiresAt) {
+		entry := heap.Pop(&c.expiry).(*nonceEntry)
+		delete(c.entries, entry.nonce)
+	}
+	if _, ok := c.entries[nonce]; ok {
+		return status.Error(codes.Unauthenticated, "nonce already used")
+	}
+	if len(c.entries) >= c.size {
+		return status.Error(codes.ResourceExhausted, "too many signed requests")
+	}
+	entry := &nonceEntry{nonce: nonce, expiresAt: expiresAt}
+	c.entries[nonce] = entry
+	heap.Push(&c.expiry, entry)
+	return nil
+}
@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// signedIncomingContext 通过客户端拦截器签名，返回服务端收到的上下文
+func signedIncomingContext(t *testing.T, interceptor grpc.UnaryClientInterceptor, method string, req any) context.Context {
+	t.Helper()
+	var md metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	require.NoError(t, interceptor(context.Background(), method, req, nil, nil, invoker))
+	return metadata.NewIncomingContext(context.Background(), md)
+}
+
+// verifySigned 通过服务端拦截器校验签名
+func verifySigned(ctx context.Context, interceptor grpc.UnaryServerInterceptor, method string, req any) (*SignedRequest, error) {
+	var signed *SignedRequest
+	_, err := interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req any) (any, error) {
+		signed, _ = SignedRequestFromContext(ctx)
+		return nil, nil
+	})
+	return signed, err
+}
+
+func TestHMAC_SignAndVerify(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := func(o *hmacOptions) { o.now = func() time.Time { return now } }
+	oldSecret, newSecret := []byte("old-secret-0123456789"), []byte("new-secret-0123456789")
+	keys := StaticKeys{"2023": oldSecret, "2024": newSecret}
+	server := UnaryServerInterceptor(NewHMACAuthFunc(keys, clock))
+	const method = "/grpc.health.v1.Health/Check"
+	req := &grpc_health_v1.HealthCheckRequest{Service: "orders"}
+
+	t.Run("轮换期间新旧密钥都有效", func(t *testing.T) {
+		for _, keyID := range []string{"2023", "2024"} {
+			secret := keys[keyID].([]byte)
+			ctx := signedIncomingContext(t, HMACUnaryClientInterceptor(keyID, secret, clock), method, req)
+			signed, err := verifySigned(ctx, server, method, req)
+			require.NoError(t, err)
+			assert.Equal(t, keyID, signed.KeyID)
+			assert.Equal(t, now.Unix(), signed.Timestamp.Unix())
+			assert.NotEmpty(t, signed.Nonce)
+		}
+	})
+
+	t.Run("重放被拒绝", func(t *testing.T) {
+		ctx := signedIncomingContext(t, HMACUnaryClientInterceptor("2024", newSecret, clock), method, req)
+		_, err := verifySigned(ctx, server, method, req)
+		require.NoError(t, err)
+		_, err = verifySigned(ctx, server, method, req)
+		assert.Equal(t, "nonce already used", status.Convert(err).Message())
+	})
+
+	t.Run("缓存已满后重放仍被拒绝", func(t *testing.T) {
+		server := UnaryServerInterceptor(NewHMACAuthFunc(keys, clock, WithNonceCacheSize(3)))
+		captured := signedIncomingContext(t, HMACUnaryClientInterceptor("2024", newSecret, clock), method, req)
+		_, err := verifySigned(captured, server, method, req)
+		require.NoError(t, err)
+		// 用新的随机数填满缓存
+		for i := 0; i < 3; i++ {
+			ctx := signedIncomingContext(t, HMACUnaryClientInterceptor("2024", newSecret, clock), method, req)
+			_, err = verifySigned(ctx, server, method, req)
+		}
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+		_, err = verifySigned(captured, server, method, req)
+		assert.Equal(t, "nonce already used", status.Convert(err).Message())
+	})
+
+	tests := []struct {
+		name    string
+		client  grpc.UnaryClientInterceptor
+		method  string
+		req     any
+		wantMsg string
+	}{
+		{"未知密钥ID", HMACUnaryClientInterceptor("2022", oldSecret, clock), method, req, "unknown signature key"},
+		{"密钥不匹配", HMACUnaryClientInterceptor("2024", oldSecret, clock), method, req, "invalid request signature"},
+		{"请求被篡改", HMACUnaryClientInterceptor("2024", newSecret, clock), method, &grpc_health_v1.HealthCheckRequest{Service: "billing"}, "request digest mismatch"},
+		{"方法被替换", HMACUnaryClientInterceptor("2024", newSecret, clock), "/grpc.health.v1.Health/Watch", req, "invalid request signature"},
+		{"签名过旧", HMACUnaryClientInterceptor("2024", newSecret, func(o *hmacOptions) { o.now = func() time.Time { return now.Add(-6 * time.Minute) } }), method, req, "signature timestamp out of range"},
+		{"签名时间在未来", HMACUnaryClientInterceptor("2024", newSecret, func(o *hmacOptions) { o.now = func() time.Time { return now.Add(6 * time.Minute) } }), method, req, "signature timestamp out of range"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 客户端按tt.method签名，服务端按method校验；篡改请求时服务端收到的是原请求
+			ctx := signedIncomingContext(t, tt.client, tt.method, tt.req)
+			_, err := verifySigned(ctx, server, method, req)
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+			assert.Equal(t, tt.wantMsg, status.Convert(err).Message())
+		})
+	}
+}
+
+func TestHMAC_MissingOrMalformed(t *testing.T) {
+	authFunc := NewHMACAuthFunc(StaticKeys{"k": []byte("secret")})
+	base := metadata.Pairs(
+		headerSignatureKeyID, "k",
+		headerSignatureTimestamp, "1700000000",
+		headerSignatureNonce, "n",
+		headerSignatureDigest, "d",
+		headerSignature, "s",
+	)
+
+	tests := []struct {
+		name    string
+		modify  func(md metadata.MD)
+		wantMsg string
+	}{
+		{"缺少签名", func(md metadata.MD) { md.Delete(headerSignature) }, "missing request signature"},
+		{"缺少随机数", func(md metadata.MD) { md.Delete(headerSignatureNonce) }, "missing request signature"},
+		{"时间格式错误", func(md metadata.MD) { md.Set(headerSignatureTimestamp, "yesterday") }, "invalid signature timestamp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md := base.Copy()
+			tt.modify(md)
+			_, err := authFunc(metadata.NewIncomingContext(context.Background(), md), "/test/method")
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+			assert.Equal(t, tt.wantMsg, status.Convert(err).Message())
+		})
+	}
+}
+
+func TestHMAC_Stream(t *testing.T) {
+	secret := []byte("stream-secret")
+	client := HMACStreamClientInterceptor("k", secret)
+	var md metadata.MD
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		md, _ = metadata.FromOutgoingContext(ctx)
+		return nil, nil
+	}
+	_, err := client(context.Background(), &grpc.StreamDesc{}, nil, "/chat.Chat/Join", streamer)
+	require.NoError(t, err)
+
+	server := StreamServerInterceptor(NewHMACAuthFunc(StaticKeys{"k": secret}))
+	stream := &mockServerStream{ctx: metadata.NewIncomingContext(context.Background(), md)}
+	err = server(nil, stream, &grpc.StreamServerInfo{FullMethod: "/chat.Chat/Join"}, func(srv any, stream grpc.ServerStream) error {
+		signed, ok := SignedRequestFromContext(stream.Context())
+		assert.True(t, ok)
+		assert.Equal(t, "k", signed.KeyID)
+		return nil
+	})
+	assert.NoError(t, err)
+}
+
+func TestNonceCache(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := newNonceCache(2)
+
+	assert.NoError(t, cache.add("a", now.Add(time.Minute), now))
+	assert.Equal(t, codes.Unauthenticated, status.Code(cache.add("a", now.Add(time.Minute), now)), "未过期的随机数不能重复使用")
+
+	// 过期后从缓存中清理
+	later := now.Add(2 * time.Minute)
+	assert.NoError(t, cache.add("a", later.Add(time.Minute), later))
+	assert.Len(t, cache.entries, 1)
+
+	// 缓存已满时拒绝新的随机数，不淘汰未过期的随机数
+	assert.NoError(t, cache.add("b", later.Add(time.Minute), later))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(cache.add("c", later.Add(time.Minute), later)))
+	assert.Equal(t, codes.Unauthenticated, status.Code(cache.add("a", later.Add(time.Minute), later)))
+
+	// 过期后可以加入新的随机数
+	latest := later.Add(time.Minute)
+	assert.NoError(t, cache.add("c", latest.Add(time.Minute), latest))
+
+	assert.Equal(t, defaultNonceCacheSize, newNonceCache(0).size)
+}
+
+func TestNonceCache_OutOfOrderExpiry(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := newNonceCache(3)
+
+	// 先加入的随机数签名时间靠后，过期时间晚于之后加入的随机数
+	require.NoError(t, cache.add("future", now.Add(time.Hour), now))
+	require.NoError(t, cache.add("a", now.Add(time.Minute), now))
+	require.NoError(t, cache.add("b", now.Add(time.Minute), now))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(cache.add("c", now.Add(time.Minute), now)))
+
+	// 之后加入的随机数过期后被清理，不被先加入的随机数阻塞
+	later := now.Add(2 * time.Minute)
+	assert.NoError(t, cache.add("c", later.Add(time.Minute), later))
+	assert.NoError(t, cache.add("d", later.Add(time.Minute), later))
+	assert.Equal(t, codes.Unauthenticated, status.Code(cache.add("future", later.Add(time.Minute), later)))
+}
+
+func TestRequestFromContext(t *testing.T) {
+	_, ok := RequestFromContext(context.Background())
+	assert.False(t, ok)
+
+	interceptor := UnaryServerInterceptor(func(ctx context.Context, fullMethodName string) (context.Context, error) {
+		req, ok := RequestFromContext(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, "request", req)
+		return ctx, nil
+	})
+	_, err := interceptor(context.Background(), "request", &grpc.UnaryServerInfo{FullMethod: "/test/method"}, func(ctx context.Context, req any) (any, error) {
+		_, ok := RequestFromContext(ctx)
+		assert.False(t, ok, "处理函数的上下文中没有请求消息")
+		return nil, nil
+	})
+	assert.NoError(t, err)
+}
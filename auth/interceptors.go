@@ -17,38 +17,115 @@ import (
 //   - error: 认证错误
 type AuthFunc func(ctx context.Context, fullMethodName string) (context.Context, error)
 
+// ServiceAuthFuncOverride 服务可实现该接口以替代拦截器的全局认证函数
+// 拦截器从grpc.UnaryServerInfo.Server或流式处理的srv中发现该接口
+type ServiceAuthFuncOverride interface {
+	// AuthFuncOverride 对该服务的请求执行认证
+	//
+	// 参数:
+	//   - ctx: 请求上下文
+	//   - fullMethodName: 完整的方法名
+	//
+	// 返回值:
+	//   - context.Context: 认证后的上下文
+	//   - error: 认证错误
+	AuthFuncOverride(ctx context.Context, fullMethodName string) (context.Context, error)
+}
+
+// requestKey 一元调用的请求消息在上下文中的键
+type requestKey struct{}
+
+// RequestFromContext 从上下文中获取一元调用的请求消息
+// UnaryServerInterceptor在执行认证函数前写入请求消息，供需要校验请求内容的认证函数使用；
+// 请求消息只在认证函数的上下文中可用，传给处理函数的上下文中没有请求消息。流式调用没有请求消息
+//
+// 参数:
+//   - ctx: 请求上下文
+//
+// 返回值:
+//   - any: 请求消息
+//   - bool: 上下文中是否有请求消息
+func RequestFromContext(ctx context.Context) (any, bool) {
+	req := ctx.Value(requestKey{})
+	return req, req != nil
+}
+
+// requestHiddenContext 对处理函数隐藏请求消息的上下文
+// 认证函数返回的上下文派生自写入了请求消息的上下文，无法移除已有的值，因此在读取时屏蔽
+type requestHiddenContext struct {
+	context.Context
+}
+
+// Value 请求消息的键返回nil，其余键交给原上下文
+func (c requestHiddenContext) Value(key any) any {
+	if _, ok := key.(requestKey); ok {
+		return nil
+	}
+	return c.Context.Value(key)
+}
+
+// authenticate 按公开方法、服务覆盖和全局认证函数的顺序执行认证
+//
+// 参数:
+//   - ctx: 请求上下文
+//   - srv: 服务实现
+//   - fullMethodName: 完整的方法名
+//   - authFunc: 全局认证函数
+//   - o: 配置选项
+//
+// 返回值:
+//   - context.Context: 认证后的上下文
+//   - error: 认证错误
+func authenticate(ctx context.Context, srv any, fullMethodName string, authFunc AuthFunc, o *options) (context.Context, error) {
+	if o.isPublic(fullMethodName) {
+		return ctx, nil
+	}
+	if override, ok := srv.(ServiceAuthFuncOverride); ok {
+		return override.AuthFuncOverride(ctx, fullMethodName)
+	}
+	return authFunc(ctx, fullMethodName)
+}
+
 // UnaryServerInterceptor 创建一元服务器拦截器
 // 该拦截器在处理每个一元gRPC请求前执行认证逻辑
 //
 // 参数:
-//   - authFunc: 认证函数
+//   - authFunc: 认证函数，服务实现ServiceAuthFuncOverride时被其替代
+//   - opts: 可选的配置选项
 //
 // 返回值:
 //   - grpc.UnaryServerInterceptor: gRPC一元服务器拦截器
-func UnaryServerInterceptor(authFunc AuthFunc) grpc.UnaryServerInterceptor {
+func UnaryServerInterceptor(authFunc AuthFunc, opts ...Option) grpc.UnaryServerInterceptor {
+	o := defaultOptions().apply(opts...)
 	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
-		// 执行认证逻辑，获取新的上下文
-		newCtx, err := authFunc(ctx, info.FullMethod)
+		// 写入请求消息供认证函数校验，然后执行认证逻辑，获取新的上下文
+		newCtx, err := authenticate(context.WithValue(ctx, requestKey{}, req), info.Server, info.FullMethod, authFunc, o)
 		if err != nil {
 			return nil, err
 		}
-		// 使用认证后的上下文继续处理请求
-		return handler(newCtx, req)
+		// 使用认证后的上下文继续处理请求，请求消息只提供给认证函数
+		return handler(requestHiddenContext{newCtx}, req)
 	}
 }
 
 // StreamServerInterceptor 创建流式服务器拦截器
-// 该拦截器在处理每个流式gRPC请求前执行认证逻辑
+// 该拦截器在处理每个流式gRPC请求前执行认证逻辑，启用WithStreamReauth时在流的生命周期内重新认证
 //
 // 参数:
-//   - authFunc: 认证函数
+//   - authFunc: 认证函数，服务实现ServiceAuthFuncOverride时被其替代
+//   - opts: 可选的配置选项
 //
 // 返回值:
 //   - grpc.StreamServerInterceptor: gRPC流式服务器拦截器
-func StreamServerInterceptor(authFunc AuthFunc) grpc.StreamServerInterceptor {
+func StreamServerInterceptor(authFunc AuthFunc, opts ...Option) grpc.StreamServerInterceptor {
+	o := defaultOptions().apply(opts...)
 	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		// 启用重新认证时，在流的生命周期内定期重新认证
+		if o.streamReauth && !o.isPublic(info.FullMethod) {
+			return serveWithReauth(srv, stream, info.FullMethod, handler, authFunc, o)
+		}
 		// 执行认证逻辑，获取新的上下文
-		newCtx, err := authFunc(stream.Context(), info.FullMethod)
+		newCtx, err := authenticate(stream.Context(), srv, info.FullMethod, authFunc, o)
 		if err != nil {
 			return err
 		}
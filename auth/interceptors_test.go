@@ -411,3 +411,95 @@ func TestWrappedServerStream_NestedWrap(t *testing.T) {
 
 	assert.Equal(t, wrapped1, wrapped2, "多次包装应该返回同一个实例")
 }
+
+// overrideService 实现ServiceAuthFuncOverride的服务
+type overrideService struct {
+	called bool
+	err    error
+}
+
+func (s *overrideService) AuthFuncOverride(ctx context.Context, fullMethodName string) (context.Context, error) {
+	s.called = true
+	if s.err != nil {
+		return nil, s.err
+	}
+	return context.WithValue(ctx, "auth", "override"), nil
+}
+
+func TestInterceptors_AuthOverride(t *testing.T) {
+	errGlobal := errors.New("global auth failed")
+	errOverride := errors.New("override auth failed")
+
+	tests := []struct {
+		name           string
+		server         any
+		opts           []Option
+		fullMethod     string
+		wantErr        error
+		wantAuthValue  any
+		wantGlobalCall bool
+	}{
+		{
+			name:           "服务未实现覆盖接口时使用全局认证",
+			server:         struct{}{},
+			fullMethod:     "/test.Service/Method",
+			wantErr:        errGlobal,
+			wantGlobalCall: true,
+		},
+		{
+			name:          "服务实现覆盖接口时替代全局认证",
+			server:        &overrideService{},
+			fullMethod:    "/test.Service/Method",
+			wantAuthValue: "override",
+		},
+		{
+			name:       "覆盖认证失败",
+			server:     &overrideService{err: errOverride},
+			fullMethod: "/test.Service/Method",
+			wantErr:    errOverride,
+		},
+		{
+			name:       "公开方法跳过全部认证",
+			server:     &overrideService{err: errOverride},
+			opts:       []Option{WithPublicMethods("/grpc.health.v1.Health/*")},
+			fullMethod: "/grpc.health.v1.Health/Check",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, kind := range []string{"unary", "stream"} {
+				globalCalled := false
+				authFunc := func(ctx context.Context, fullMethod string) (context.Context, error) {
+					globalCalled = true
+					return nil, errGlobal
+				}
+
+				var gotCtx context.Context
+				var err error
+				if kind == "unary" {
+					interceptor := UnaryServerInterceptor(authFunc, tt.opts...)
+					info := &grpc.UnaryServerInfo{Server: tt.server, FullMethod: tt.fullMethod}
+					_, err = interceptor(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) {
+						gotCtx = ctx
+						return nil, nil
+					})
+				} else {
+					interceptor := StreamServerInterceptor(authFunc, tt.opts...)
+					info := &grpc.StreamServerInfo{FullMethod: tt.fullMethod}
+					err = interceptor(tt.server, &mockServerStream{ctx: context.Background()}, info, func(srv any, stream grpc.ServerStream) error {
+						gotCtx = stream.Context()
+						return nil
+					})
+				}
+
+				assert.ErrorIs(t, err, tt.wantErr, kind)
+				assert.Equal(t, tt.wantGlobalCall, globalCalled, kind)
+				if tt.wantErr == nil {
+					assert.NotNil(t, gotCtx, kind)
+					assert.Equal(t, tt.wantAuthValue, gotCtx.Value("auth"), kind)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Introspection RFC 7662令牌内省返回的令牌信息
+type Introspection struct {
+	// Active 令牌是否有效，认证通过的令牌总是为true
+	Active bool
+	// Subject 令牌的主体(sub)
+	Subject string
+	// ClientID 申请令牌的客户端(client_id)
+	ClientID string
+	// Username 资源所有者的用户名(username)
+	Username string
+	// Scopes 令牌的权限范围(scope)
+	Scopes []string
+	// ExpiresAt 过期时间(exp)，未返回时为零值
+	ExpiresAt time.Time
+	// Raw 内省响应中的全部字段
+	Raw map[string]any
+}
+
+// HasScope 判断令牌是否拥有权限范围
+//
+// 参数:
+//   - scope: 权限范围
+//
+// 返回值:
+//   - bool: 是否拥有该权限范围
+func (i *Introspection) HasScope(scope string) bool {
+	return slices.Contains(i.Scopes, scope)
+}
+
+// introspectionKey 内省结果在上下文中的键
+type introspectionKey struct{}
+
+// IntrospectionFromContext 从上下文中获取令牌内省认证后的令牌信息
+//
+// 参数:
+//   - ctx: 请求上下文
+//
+// 返回值:
+//   - *Introspection: 令牌信息
+//   - bool: 上下文中是否有令牌信息
+func IntrospectionFromContext(ctx context.Context) (*Introspection, bool) {
+	info, ok := ctx.Value(introspectionKey{}).(*Introspection)
+	return info, ok
+}
+
+// introspectionOptions 存储令牌内省的配置选项
+type introspectionOptions struct {
+	// scheme 认证方案
+	scheme string
+	// timeout 调用内省端点的超时时间
+	timeout time.Duration
+	// cacheTTL 内省结果的缓存时间，为0时不缓存
+	cacheTTL time.Duration
+	// tokenTypeHint 请求中的token_type_hint
+	tokenTypeHint string
+	// httpClient 调用内省端点使用的HTTP客户端
+	httpClient *http.Client
+	// now 获取当前时间，便于测试
+	now func() time.Time
+}
+
+// IntrospectionOption 定义令牌内省配置选项的函数类型
+type IntrospectionOption func(*introspectionOptions)
+
+// WithIntrospectionScheme 设置authorization头中的认证方案，默认为"Bearer"
+//
+// 参数:
+//   - scheme: 认证方案
+//
+// 返回值:
+//   - IntrospectionOption: 设置认证方案选项的函数
+func WithIntrospectionScheme(scheme string) IntrospectionOption {
+	return func(o *introspectionOptions) {
+		o.scheme = scheme
+	}
+}
+
+// WithIntrospectionTimeout 设置调用内省端点的超时时间，默认5秒
+// 超时或端点不可用时拒绝请求
+//
+// 参数:
+//   - d: 超时时间
+//
+// 返回值:
+//   - IntrospectionOption: 设置超时时间选项的函数
+func WithIntrospectionTimeout(d time.Duration) IntrospectionOption {
+	return func(o *introspectionOptions) {
+		o.timeout = d
+	}
+}
+
+// WithIntrospectionCacheTTL 设置内省结果的缓存时间，默认1分钟，为0时不缓存
+// 实际缓存时间不超过令牌的exp，无效令牌的结果缓存10秒，端点不可用的结果不缓存
+//
+// 参数:
+//   - ttl: 缓存时间
+//
+// 返回值:
+//   - IntrospectionOption: 设置缓存时间选项的函数
+func WithIntrospectionCacheTTL(ttl time.Duration) IntrospectionOption {
+	return func(o *introspectionOptions) {
+		o.cacheTTL = ttl
+	}
+}
+
+// WithTokenTypeHint 设置请求中的token_type_hint，默认为"access_token"，为空时不发送
+//
+// 参数:
+//   - hint: 令牌类型提示
+//
+// 返回值:
+//   - IntrospectionOption: 设置令牌类型提示选项的函数
+func WithTokenTypeHint(hint string) IntrospectionOption {
+	return func(o *introspectionOptions) {
+		o.tokenTypeHint = hint
+	}
+}
+
+// WithIntrospectionHTTPClient 设置调用内省端点使用的HTTP客户端
+//
+// 参数:
+//   - client: HTTP客户端
+//
+// 返回值:
+//   - IntrospectionOption: 设置HTTP客户端选项的函数
+func WithIntrospectionHTTPClient(client *http.Client) IntrospectionOption {
+	return func(o *introspectionOptions) {
+		if client != nil {
+			o.httpClient = client
+		}
+	}
+}
+
+// NewIntrospectionAuthFunc 创建通过RFC 7662内省端点校验不透明令牌的认证函数
+// 使用客户端凭证以HTTP Basic认证调用内省端点，令牌有效时将令牌信息写入上下文，
+// 可通过IntrospectionFromContext获取。
+// 令牌无效或已过期时返回Unauthenticated；端点超时、不可用或响应无法解析时返回Unavailable，不放行请求
+//
+// 参数:
+//   - endpoint: 内省端点地址
+//   - clientID: 客户端ID
+//   - clientSecret: 客户端密钥
+//   - opts: 可选的配置选项
+//
+// 返回值:
+//   - AuthFunc: 认证函数
+func NewIntrospectionAuthFunc(endpoint, clientID, clientSecret string, opts ...IntrospectionOption) AuthFunc {
+	o := &introspectionOptions{
+		scheme:        "Bearer",
+		timeout:       5 * time.Second,
+		cacheTTL:      time.Minute,
+		tokenTypeHint: "access_token",
+		httpClient:    http.DefaultClient,
+		now:           time.Now,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	authFunc := func(ctx context.Context, fullMethodName string) (context.Context, error) {
+		token, err := AuthFromMD(ctx, o.scheme)
+		if err != nil {
+			return nil, err
+		}
+		info, err := introspect(ctx, o, endpoint, clientID, clientSecret, token)
+		if err != nil {
+			return nil, status.Error(codes.Unavailable, "token introspection unavailable")
+		}
+		if !info.Active {
+			return nil, status.Error(codes.Unauthenticated, "inactive token")
+		}
+		if !info.ExpiresAt.IsZero() && !o.now().Before(info.ExpiresAt) {
+			return nil, status.Error(codes.Unauthenticated, "token expired")
+		}
+		return context.WithValue(ctx, introspectionKey{}, info), nil
+	}
+	if o.cacheTTL <= 0 {
+		return authFunc
+	}
+	// 内省结果与方法无关，不同方法共享缓存
+	return NewCachingAuthFunc(authFunc, WithCacheTTL(o.cacheTTL), WithCacheKeyFunc(credentialCacheKey), func(co *authCacheOptions) {
+		co.now = o.now
+	})
+}
+
+// introspect 调用内省端点
+func introspect(ctx context.Context, o *introspectionOptions, endpoint, clientID, clientSecret, token string) (*Introspection, error) {
+	ctx, cancel := context.WithTimeout(ctx, o.timeout)
+	defer cancel()
+	form := url.Values{"token": {token}}
+	if o.tokenTypeHint != "" {
+		form.Set("token_type_hint", o.tokenTypeHint)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(clientSecret))
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("auth: introspect token: unexpected status %s", resp.Status)
+	}
+	var raw map[string]any
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, err
+	}
+	return newIntrospection(raw), nil
+}
+
+// newIntrospection 从内省响应中提取令牌信息
+func newIntrospection(raw map[string]any) *Introspection {
+	str := func(key string) string {
+		s, _ := raw[key].(string)
+		return s
+	}
+	info := &Introspection{
+		Subject:  str("sub"),
+		ClientID: str("client_id"),
+		Username: str("username"),
+		Scopes:   strings.Fields(str("scope")),
+		Raw:      raw,
+	}
+	info.Active, _ = raw["active"].(bool)
+	if exp, ok := raw["exp"].(json.Number); ok {
+		if seconds, err := exp.Int64(); err == nil {
+			info.ExpiresAt = time.Unix(seconds, 0)
+		} else if f, err := exp.Float64(); err == nil {
+			info.ExpiresAt = time.Unix(int64(f), 0)
+		}
+	}
+	return info
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// introspectionServer 模拟RFC 7662内省端点，按令牌返回响应
+func introspectionServer(t *testing.T, calls *atomic.Int32, responses map[string]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		clientID, secret, ok := r.BasicAuth()
+		if !ok || clientID != "gateway" || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "access_token", r.PostFormValue("token_type_hint"))
+		switch token := r.PostFormValue("token"); token {
+		case "slow":
+			time.Sleep(200 * time.Millisecond)
+			_, _ = w.Write([]byte(`{"active":true}`))
+		case "broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			resp, ok := responses[token]
+			if !ok {
+				resp = `{"active":false}`
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(resp))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestIntrospectionAuthFunc(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	var calls atomic.Int32
+	server := introspectionServer(t, &calls, map[string]string{
+		"opaque":  `{"active":true,"sub":"alice","client_id":"web","username":"alice@example.com","scope":"orders:read orders:write","exp":` + strconv.FormatInt(now.Add(time.Hour).Unix(), 10) + `,"tenant":"acme"}`,
+		"expired": `{"active":true,"sub":"bob","exp":` + strconv.FormatInt(now.Add(-time.Minute).Unix(), 10) + `}`,
+	})
+	authFunc := NewIntrospectionAuthFunc(server.URL, "gateway", "s3cret", WithIntrospectionCacheTTL(0), WithIntrospectionTimeout(50*time.Millisecond))
+
+	t.Run("有效令牌", func(t *testing.T) {
+		ctx, err := authFunc(tokenContext("opaque"), "/test/method")
+		require.NoError(t, err)
+		info, ok := IntrospectionFromContext(ctx)
+		require.True(t, ok)
+		assert.True(t, info.Active)
+		assert.Equal(t, "alice", info.Subject)
+		assert.Equal(t, "web", info.ClientID)
+		assert.Equal(t, "alice@example.com", info.Username)
+		assert.Equal(t, []string{"orders:read", "orders:write"}, info.Scopes)
+		assert.True(t, info.HasScope("orders:write"))
+		assert.Equal(t, now.Add(time.Hour), info.ExpiresAt)
+		assert.Equal(t, "acme", info.Raw["tenant"])
+	})
+
+	tests := []struct {
+		name     string
+		authFunc AuthFunc
+		ctx      context.Context
+		wantCode codes.Code
+		wantMsg  string
+	}{
+		{"没有令牌", authFunc, context.Background(), codes.Unauthenticated, "Request unauthenticated with Bearer"},
+		{"无效令牌", authFunc, tokenContext("revoked"), codes.Unauthenticated, "inactive token"},
+		{"已过期令牌", authFunc, tokenContext("expired"), codes.Unauthenticated, "token expired"},
+		{"端点错误时拒绝", authFunc, tokenContext("broken"), codes.Unavailable, "token introspection unavailable"},
+		{"端点超时时拒绝", authFunc, tokenContext("slow"), codes.Unavailable, "token introspection unavailable"},
+		{"客户端凭证错误时拒绝", NewIntrospectionAuthFunc(server.URL, "gateway", "wrong"), tokenContext("opaque"), codes.Unavailable, "token introspection unavailable"},
+		{"端点不可达时拒绝", NewIntrospectionAuthFunc("http://127.0.0.1:1/introspect", "gateway", "s3cret"), tokenContext("opaque"), codes.Unavailable, "token introspection unavailable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.authFunc(tt.ctx, "/test/method")
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			assert.Equal(t, tt.wantMsg, status.Convert(err).Message())
+		})
+	}
+}
+
+func TestIntrospectionAuthFunc_Cache(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	clock := func(o *introspectionOptions) { o.now = func() time.Time { return now } }
+	var calls atomic.Int32
+	server := introspectionServer(t, &calls, map[string]string{
+		"long":  `{"active":true,"sub":"alice","exp":` + strconv.FormatInt(now.Add(time.Hour).Unix(), 10) + `}`,
+		"short": `{"active":true,"sub":"bob","exp":` + strconv.FormatInt(now.Add(30*time.Second).Unix(), 10) + `}`,
+	})
+	authFunc := NewIntrospectionAuthFunc(server.URL, "gateway", "s3cret", clock, WithIntrospectionCacheTTL(5*time.Minute))
+
+	introspectTwice := func(token string) int32 {
+		calls.Store(0)
+		for i := 0; i < 2; i++ {
+			_, _ = authFunc(tokenContext(token), "/test/method")
+		}
+		return calls.Load()
+	}
+
+	assert.Equal(t, int32(1), introspectTwice("long"), "有效令牌被缓存")
+	assert.Equal(t, int32(1), introspectTwice("revoked"), "无效令牌被短暂缓存")
+	assert.Equal(t, int32(2), introspectTwice("broken"), "端点错误不缓存")
+
+	// 缓存时间不超过exp
+	assert.Equal(t, int32(1), introspectTwice("short"))
+	now = now.Add(31 * time.Second)
+	calls.Store(0)
+	_, err := authFunc(tokenContext("short"), "/test/method")
+	assert.Equal(t, "token expired", status.Convert(err).Message())
+	assert.Equal(t, int32(1), calls.Load())
+}
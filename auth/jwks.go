@@ -0,0 +1,462 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrKeyNotFound 找不到令牌对应的验证密钥时返回的错误
+var ErrKeyNotFound = errors.New("auth: verification key not found")
+
+// KeySet 提供JWT验证密钥
+type KeySet interface {
+	// Key 返回与密钥ID和签名算法匹配的验证密钥
+	//
+	// 参数:
+	//   - ctx: 请求上下文
+	//   - kid: 令牌头中的密钥ID，可能为空
+	//   - alg: 令牌头中的签名算法
+	//
+	// 返回值:
+	//   - any: 验证密钥，HS256为[]byte，RS256为*rsa.PublicKey，
+	//     ES256为*ecdsa.PublicKey，EdDSA为ed25519.PublicKey
+	//   - error: 找不到密钥时返回ErrKeyNotFound
+	Key(ctx context.Context, kid, alg string) (any, error)
+}
+
+// StaticKeys 固定的验证密钥集合，键为密钥ID
+// 令牌没有kid时，若集合中只有一个与算法匹配的密钥则使用该密钥
+type StaticKeys map[string]any
+
+// Key 实现KeySet接口
+func (s StaticKeys) Key(_ context.Context, kid, alg string) (any, error) {
+	return lookupKey(s, nil, kid, alg)
+}
+
+// lookupKey 按密钥ID和算法查找密钥
+// algs为密钥ID到JWK中alg成员的映射，密钥声明了算法时只用于该算法
+func lookupKey(keys map[string]any, algs map[string]string, kid, alg string) (any, error) {
+	matches := func(id string, key any) bool {
+		if keyAlg := algs[id]; keyAlg != "" && keyAlg != alg {
+			return false
+		}
+		return keyMatchesAlg(key, alg)
+	}
+	if kid != "" {
+		key, ok := keys[kid]
+		if !ok || !matches(kid, key) {
+			return nil, ErrKeyNotFound
+		}
+		return key, nil
+	}
+	var found any
+	for id, key := range keys {
+		if !matches(id, key) {
+			continue
+		}
+		if found != nil {
+			// 多个候选密钥时要求令牌携带kid
+			return nil, ErrKeyNotFound
+		}
+		found = key
+	}
+	if found == nil {
+		return nil, ErrKeyNotFound
+	}
+	return found, nil
+}
+
+// keyMatchesAlg 判断密钥类型是否与签名算法匹配，避免算法混淆
+func keyMatchesAlg(key any, alg string) bool {
+	switch k := key.(type) {
+	case []byte:
+		return alg == "HS256"
+	case *rsa.PublicKey:
+		return alg == "RS256"
+	case *ecdsa.PublicKey:
+		return alg == "ES256" && k.Curve == elliptic.P256()
+	case ed25519.PublicKey:
+		return alg == "EdDSA"
+	default:
+		return false
+	}
+}
+
+// jwksOptions 存储JWKS的配置选项
+type jwksOptions struct {
+	// refreshInterval 定期刷新的间隔
+	refreshInterval time.Duration
+	// minRefreshInterval 遇到未知kid时两次强制刷新的最小间隔
+	minRefreshInterval time.Duration
+	// httpClient 获取远程JWKS使用的HTTP客户端
+	httpClient *http.Client
+	// refreshTimeout 单次刷新的超时时间
+	refreshTimeout time.Duration
+	// now 获取当前时间，便于测试
+	now func() time.Time
+}
+
+// JWKSOption 定义JWKS配置选项的函数类型
+type JWKSOption func(*jwksOptions)
+
+// WithRefreshInterval 设置JWKS定期刷新的间隔，默认1小时
+//
+// 参数:
+//   - d: 刷新间隔
+//
+// 返回值:
+//   - JWKSOption: 设置刷新间隔选项的函数
+func WithRefreshInterval(d time.Duration) JWKSOption {
+	return func(o *jwksOptions) {
+		o.refreshInterval = d
+	}
+}
+
+// WithMinRefreshInterval 设置遇到未知kid时两次强制刷新的最小间隔，默认1分钟
+// 用于在密钥轮换后尽快获取新密钥，同时避免伪造kid的请求频繁触发刷新
+//
+// 参数:
+//   - d: 最小刷新间隔
+//
+// 返回值:
+//   - JWKSOption: 设置最小刷新间隔选项的函数
+func WithMinRefreshInterval(d time.Duration) JWKSOption {
+	return func(o *jwksOptions) {
+		o.minRefreshInterval = d
+	}
+}
+
+// WithHTTPClient 设置获取远程JWKS使用的HTTP客户端，默认超时10秒
+//
+// 参数:
+//   - client: HTTP客户端
+//
+// 返回值:
+//   - JWKSOption: 设置HTTP客户端选项的函数
+func WithHTTPClient(client *http.Client) JWKSOption {
+	return func(o *jwksOptions) {
+		if client != nil {
+			o.httpClient = client
+		}
+	}
+}
+
+// JWKS 从JSON Web Key Set文档加载并缓存的验证密钥
+// 密钥在超过刷新间隔后的下一次查找时在后台重新加载，遇到未知kid时提前刷新；
+// 刷新期间和刷新失败时继续使用已缓存的密钥。JWK声明了alg时该密钥只用于对应的签名算法
+type JWKS struct {
+	fetch func(ctx context.Context) ([]byte, error)
+	opts  *jwksOptions
+	group singleflight.Group
+
+	mu          sync.Mutex
+	keys        map[string]any
+	algs        map[string]string
+	fetchedAt   time.Time
+	lastAttempt time.Time
+}
+
+// NewJWKSFromFile 创建从本地文件加载的JWKS
+//
+// 参数:
+//   - path: JWKS文件路径
+//   - opts: 可选的配置选项
+//
+// 返回值:
+//   - *JWKS: 密钥集合
+//   - error: 首次加载失败时返回错误
+func NewJWKSFromFile(path string, opts ...JWKSOption) (*JWKS, error) {
+	return newJWKS(func(context.Context) ([]byte, error) {
+		return os.ReadFile(path)
+	}, newJWKSOptions(opts...))
+}
+
+// NewJWKSFromURL 创建从URL加载的JWKS
+//
+// 参数:
+//   - url: JWKS地址
+//   - opts: 可选的配置选项
+//
+// 返回值:
+//   - *JWKS: 密钥集合
+//   - error: 首次加载失败时返回错误
+func NewJWKSFromURL(url string, opts ...JWKSOption) (*JWKS, error) {
+	o := newJWKSOptions(opts...)
+	return newJWKS(func(ctx context.Context) ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := o.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("auth: fetch jwks: unexpected status %s", resp.Status)
+		}
+		return io.ReadAll(resp.Body)
+	}, o)
+}
+
+// newJWKSOptions 创建默认的JWKS配置并应用选项
+func newJWKSOptions(opts ...JWKSOption) *jwksOptions {
+	o := &jwksOptions{
+		refreshInterval:    time.Hour,
+		minRefreshInterval: time.Minute,
+		httpClient:         &http.Client{Timeout: 10 * time.Second},
+		refreshTimeout:     10 * time.Second,
+		now:                time.Now,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// newJWKS 创建JWKS并立即加载一次
+func newJWKS(fetch func(ctx context.Context) ([]byte, error), o *jwksOptions) (*JWKS, error) {
+	j := &JWKS{fetch: fetch, opts: o}
+	if err := j.Refresh(context.Background()); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Refresh 立即重新加载密钥
+// 加载在独立的上下文中执行，ctx结束时停止等待，加载继续进行
+//
+// 参数:
+//   - ctx: 上下文
+//
+// 返回值:
+//   - error: 加载或解析失败时返回错误，已缓存的密钥保持不变
+func (j *JWKS) Refresh(ctx context.Context) error {
+	j.mu.Lock()
+	j.lastAttempt = j.opts.now()
+	ch := j.refresh()
+	j.mu.Unlock()
+	select {
+	case r := <-ch:
+		return r.Err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// refresh 在后台重新加载密钥，进行中的加载合并为一次，调用方需持有锁
+// 加载使用不依赖请求的带超时的上下文，请求取消不会中断加载
+func (j *JWKS) refresh() <-chan singleflight.Result {
+	return j.group.DoChan("refresh", func() (any, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), j.opts.refreshTimeout)
+		defer cancel()
+		startedAt := j.opts.now()
+		data, err := j.fetch(ctx)
+		if err != nil {
+			return nil, err
+		}
+		keys, algs, err := parseJWKS(data)
+		if err != nil {
+			return nil, err
+		}
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		j.keys, j.algs = keys, algs
+		j.fetchedAt = startedAt
+		return nil, nil
+	})
+}
+
+// Key 实现KeySet接口
+func (j *JWKS) Key(ctx context.Context, kid, alg string) (any, error) {
+	j.mu.Lock()
+	now := j.opts.now()
+	var ch <-chan singleflight.Result
+	// 超过刷新间隔时在后台重新加载，加载期间和失败时继续使用缓存
+	if j.opts.refreshInterval > 0 && now.Sub(j.fetchedAt) >= j.opts.refreshInterval &&
+		now.Sub(j.lastAttempt) >= j.opts.minRefreshInterval {
+		j.lastAttempt = now
+		ch = j.refresh()
+	}
+	key, err := lookupKey(j.keys, j.algs, kid, alg)
+	if err == nil || kid == "" {
+		j.mu.Unlock()
+		return key, err
+	}
+	// 未知kid可能是密钥已轮换，限频强制刷新并等待刷新结果
+	if ch == nil {
+		if now.Sub(j.lastAttempt) < j.opts.minRefreshInterval {
+			j.mu.Unlock()
+			return nil, err
+		}
+		j.lastAttempt = now
+		ch = j.refresh()
+	}
+	j.mu.Unlock()
+	select {
+	case r := <-ch:
+		if r.Err != nil {
+			return nil, err
+		}
+	case <-ctx.Done():
+		return nil, err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return lookupKey(j.keys, j.algs, kid, alg)
+}
+
+// jsonWebKey JWK文档中的单个密钥
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// ParseJWKS 解析JSON Web Key Set文档
+// 支持RSA、EC(P-256)、OKP(Ed25519)和oct类型的密钥，其他类型、用途不是签名以及格式错误的密钥被忽略，
+// 避免单个密钥导致整个集合无法加载。返回的映射不包含JWK的alg成员，JWKS会额外按alg限制密钥的算法
+//
+// 参数:
+//   - data: JWKS文档
+//
+// 返回值:
+//   - map[string]any: 密钥ID到验证密钥的映射，没有kid的密钥以其在文档中的序号为键
+//   - error: 文档格式错误或文档中的密钥都不可用时返回错误
+func ParseJWKS(data []byte) (map[string]any, error) {
+	keys, _, err := parseJWKS(data)
+	return keys, err
+}
+
+// parseJWKS 解析JWKS文档，同时返回密钥ID到JWK中alg成员的映射
+func parseJWKS(data []byte) (map[string]any, map[string]string, error) {
+	var doc struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("auth: parse jwks: %w", err)
+	}
+	keys := make(map[string]any, len(doc.Keys))
+	algs := make(map[string]string)
+	var errs []error
+	for i, jwk := range doc.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("auth: parse jwk %q: %w", jwk.Kid, err))
+			continue
+		}
+		if key == nil {
+			continue
+		}
+		kid := jwk.Kid
+		if kid == "" {
+			kid = fmt.Sprintf("#%d", i)
+		}
+		keys[kid] = key
+		if jwk.Alg != "" {
+			algs[kid] = jwk.Alg
+		}
+	}
+	if len(keys) == 0 && len(doc.Keys) > 0 {
+		return nil, nil, errors.Join(append([]error{errors.New("auth: parse jwks: no usable keys")}, errs...)...)
+	}
+	return keys, algs, nil
+}
+
+// publicKey 将JWK转换为验证密钥，不支持的类型返回nil
+func (k jsonWebKey) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBase64URL(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBase64URL(k.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() < 2 || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid rsa key")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, nil
+		}
+		x, err := decodeBase64URL(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBase64URL(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) > 32 || len(y) > 32 {
+			return nil, errors.New("invalid ec key")
+		}
+		// 使用非压缩点格式校验坐标是否在曲线上
+		point := make([]byte, 65)
+		point[0] = 4
+		new(big.Int).SetBytes(x).FillBytes(point[1:33])
+		new(big.Int).SetBytes(y).FillBytes(point[33:])
+		key, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), point)
+		if err != nil {
+			return nil, err
+		}
+		return key, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, nil
+		}
+		x, err := decodeBase64URL(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	case "oct":
+		secret, err := decodeBase64URL(k.K)
+		if err != nil {
+			return nil, err
+		}
+		if len(secret) == 0 {
+			return nil, errors.New("empty symmetric key")
+		}
+		return secret, nil
+	default:
+		return nil, nil
+	}
+}
+
+// decodeBase64URL 解码JWK中无填充的base64url字段
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
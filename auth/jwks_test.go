@@ -0,0 +1,285 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// jwkJSON 将公钥编码为JWK
+func jwkJSON(t *testing.T, kid string, key any) map[string]string {
+	t.Helper()
+	enc := base64.RawURLEncoding.EncodeToString
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return map[string]string{"kty": "RSA", "kid": kid, "n": enc(k.N.Bytes()), "e": enc(big.NewInt(int64(k.E)).Bytes())}
+	case *ecdsa.PublicKey:
+		raw, err := k.Bytes()
+		require.NoError(t, err)
+		return map[string]string{"kty": "EC", "kid": kid, "crv": "P-256", "x": enc(raw[1:33]), "y": enc(raw[33:])}
+	case ed25519.PublicKey:
+		return map[string]string{"kty": "OKP", "kid": kid, "crv": "Ed25519", "x": enc(k)}
+	case []byte:
+		return map[string]string{"kty": "oct", "kid": kid, "k": enc(k)}
+	}
+	t.Fatalf("unsupported key %T", key)
+	return nil
+}
+
+// jwksDocument 将密钥集合编码为JWKS文档
+func jwksDocument(t *testing.T, keys map[string]any) []byte {
+	t.Helper()
+	doc := struct {
+		Keys []map[string]string `json:"keys"`
+	}{}
+	for kid, key := range keys {
+		doc.Keys = append(doc.Keys, jwkJSON(t, kid, key))
+	}
+	data, err := json.Marshal(doc)
+	require.NoError(t, err)
+	return data
+}
+
+func TestParseJWKS(t *testing.T) {
+	keys := newTestKeys(t)
+	static := keys.staticKeys()
+	data := jwksDocument(t, static)
+
+	parsed, err := ParseJWKS(data)
+	require.NoError(t, err)
+	require.Len(t, parsed, 4)
+	assert.Equal(t, static["hs"], parsed["hs"])
+	assert.True(t, keys.rsa.PublicKey.Equal(parsed["rs"]))
+	assert.True(t, keys.ecdsa.PublicKey.Equal(parsed["es"]))
+	assert.Equal(t, static["eddsa"], parsed["eddsa"])
+}
+
+func TestParseJWKS_Skips(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		wantLen int
+		wantErr bool
+	}{
+		{"加密用途的密钥被忽略", `{"keys":[{"kty":"oct","kid":"a","use":"enc","k":"YWJj"},{"kty":"oct","kid":"b","k":"YWJj"}]}`, 1, false},
+		{"不支持的曲线被忽略", `{"keys":[{"kty":"EC","kid":"a","crv":"P-384","x":"","y":""},{"kty":"oct","kid":"b","k":"YWJj"}]}`, 1, false},
+		{"不支持的类型被忽略", `{"keys":[{"kty":"XYZ","kid":"a"},{"kty":"oct","kid":"b","k":"YWJj"}]}`, 1, false},
+		{"没有kid时使用序号", `{"keys":[{"kty":"oct","k":"YWJj"}]}`, 1, false},
+		{"格式错误的密钥被忽略", `{"keys":[{"kty":"EC","kid":"a","crv":"P-256","x":"AQ","y":"AQ"},{"kty":"oct","kid":"b","k":"YWJj"}]}`, 1, false},
+		{"空集合", `{"keys":[]}`, 0, false},
+		{"没有可用的密钥", `{"keys":[{"kty":"XYZ","kid":"a"}]}`, 0, true},
+		{"坐标不在曲线上", `{"keys":[{"kty":"EC","kid":"a","crv":"P-256","x":"AQ","y":"AQ"}]}`, 0, true},
+		{"Ed25519长度错误", `{"keys":[{"kty":"OKP","kid":"a","crv":"Ed25519","x":"AQ"}]}`, 0, true},
+		{"文档格式错误", `{"keys":`, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := ParseJWKS([]byte(tt.doc))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, parsed, tt.wantLen)
+		})
+	}
+}
+
+func TestJWKS_EnforcesAlg(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	doc := `{"keys":[{"kty":"oct","kid":"hs","alg":"HS384","k":"YWJj"},{"kty":"oct","kid":"any","k":"YWJj"}]}`
+	require.NoError(t, os.WriteFile(path, []byte(doc), 0o644))
+	jwks, err := NewJWKSFromFile(path)
+	require.NoError(t, err)
+
+	_, err = jwks.Key(context.Background(), "hs", "HS256")
+	assert.ErrorIs(t, err, ErrKeyNotFound, "密钥声明的算法与令牌不一致")
+	_, err = jwks.Key(context.Background(), "any", "HS256")
+	assert.NoError(t, err)
+	key, err := jwks.Key(context.Background(), "", "HS256")
+	require.NoError(t, err, "没有kid时跳过算法不一致的密钥")
+	assert.Equal(t, []byte("abc"), key)
+}
+
+func TestNewJWKSFromFile(t *testing.T) {
+	keys := newTestKeys(t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, jwksDocument(t, map[string]any{"rs": &keys.rsa.PublicKey}), 0o644))
+
+	jwks, err := NewJWKSFromFile(path)
+	require.NoError(t, err)
+	authFunc := NewJWTAuthFunc(jwks)
+	token := keys.sign(t, "RS256", "rs", jwt.MapClaims{"exp": time.Now().Add(time.Hour).Unix()})
+	_, err = authFunc(bearerContext(token), "/test/method")
+	assert.NoError(t, err)
+
+	_, err = NewJWKSFromFile(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
+
+// jwksServer 可替换文档内容的JWKS服务
+type jwksServer struct {
+	mu       sync.Mutex
+	doc      []byte
+	status   int
+	requests atomic.Int32
+}
+
+func (s *jwksServer) set(doc []byte, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.doc, s.status = doc, status
+}
+
+func (s *jwksServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.requests.Add(1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.WriteHeader(s.status)
+	_, _ = w.Write(s.doc)
+}
+
+func TestNewJWKSFromURL_Refresh(t *testing.T) {
+	oldKeys, newKeys := newTestKeys(t), newTestKeys(t)
+	handler := &jwksServer{}
+	handler.set(jwksDocument(t, map[string]any{"old": &oldKeys.ecdsa.PublicKey}), http.StatusOK)
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func(o *jwksOptions) { o.now = func() time.Time { return now } }
+	jwks, err := NewJWKSFromURL(server.URL, WithRefreshInterval(time.Hour), WithMinRefreshInterval(time.Minute), clock)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), handler.requests.Load())
+
+	ctx := context.Background()
+	_, err = jwks.Key(ctx, "old", "ES256")
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), handler.requests.Load(), "缓存有效期内不重新加载")
+
+	// 密钥轮换后，未知kid在最小间隔内不触发刷新
+	handler.set(jwksDocument(t, map[string]any{"new": &newKeys.ecdsa.PublicKey}), http.StatusOK)
+	_, err = jwks.Key(ctx, "new", "ES256")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	assert.Equal(t, int32(1), handler.requests.Load())
+
+	// 超过最小间隔后，未知kid触发刷新
+	now = now.Add(2 * time.Minute)
+	_, err = jwks.Key(ctx, "new", "ES256")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), handler.requests.Load())
+
+	// 刷新失败时继续使用缓存的密钥
+	handler.set(nil, http.StatusInternalServerError)
+	now = now.Add(2 * time.Hour)
+	_, err = jwks.Key(ctx, "new", "ES256")
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return handler.requests.Load() == 3 }, time.Second, time.Millisecond)
+}
+
+func TestNewJWKSFromURL_SlowRefresh(t *testing.T) {
+	oldKeys, newKeys := newTestKeys(t), newTestKeys(t)
+	handler := &jwksServer{}
+	handler.set(jwksDocument(t, map[string]any{"old": &oldKeys.ecdsa.PublicKey}), http.StatusOK)
+	release := make(chan struct{})
+	var blocked atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if blocked.Load() {
+			<-release
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	var mu sync.Mutex
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func(o *jwksOptions) {
+		o.now = func() time.Time {
+			mu.Lock()
+			defer mu.Unlock()
+			return now
+		}
+	}
+	jwks, err := NewJWKSFromURL(server.URL, WithRefreshInterval(time.Hour), WithMinRefreshInterval(time.Minute), clock)
+	require.NoError(t, err)
+
+	blocked.Store(true)
+	handler.set(jwksDocument(t, map[string]any{"new": &newKeys.ecdsa.PublicKey}), http.StatusOK)
+	mu.Lock()
+	now = now.Add(2 * time.Hour)
+	mu.Unlock()
+
+	// 后台刷新期间继续使用缓存的密钥，不等待刷新
+	_, err = jwks.Key(context.Background(), "old", "ES256")
+	require.NoError(t, err)
+
+	// 请求取消时停止等待，刷新继续进行
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = jwks.Key(ctx, "new", "ES256")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		_, err := jwks.Key(context.Background(), "new", "ES256")
+		return err == nil
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int32(2), handler.requests.Load(), "并发的刷新合并为一次")
+}
+
+func TestNewJWKSFromURL_InitialFailure(t *testing.T) {
+	handler := &jwksServer{}
+	handler.set(nil, http.StatusNotFound)
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	_, err := NewJWKSFromURL(server.URL)
+	assert.ErrorContains(t, err, "404")
+}
+
+func TestStaticKeys_Lookup(t *testing.T) {
+	keys := newTestKeys(t)
+	other := newTestKeys(t)
+	static := StaticKeys{"a": &keys.rsa.PublicKey, "b": &other.rsa.PublicKey, "hs": keys.hmac}
+
+	tests := []struct {
+		name    string
+		kid     string
+		alg     string
+		wantErr bool
+	}{
+		{"kid与算法匹配", "a", "RS256", false},
+		{"kid存在但算法不匹配", "a", "HS256", true},
+		{"未知kid", "c", "RS256", true},
+		{"无kid且唯一候选", "", "HS256", false},
+		{"无kid且多个候选", "", "RS256", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := static.Key(context.Background(), tt.kid, tt.alg)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrKeyNotFound)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// defaultJWTAlgorithms 默认允许的签名算法
+var defaultJWTAlgorithms = []string{"HS256", "RS256", "ES256", "EdDSA"}
+
+// Claims JWT中的声明
+type Claims struct {
+	// Issuer 签发者(iss)
+	Issuer string
+	// Subject 主体(sub)
+	Subject string
+	// Audience 受众(aud)
+	Audience []string
+	// ExpiresAt 过期时间(exp)，令牌未设置时为零值
+	ExpiresAt time.Time
+	// NotBefore 生效时间(nbf)，令牌未设置时为零值
+	NotBefore time.Time
+	// IssuedAt 签发时间(iat)，令牌未设置时为零值
+	IssuedAt time.Time
+	// ID 令牌ID(jti)
+	ID string
+	// Raw 令牌中的全部声明，包括自定义声明
+	Raw map[string]any
+}
+
+// claimsKey 声明在上下文中的键
+type claimsKey struct{}
+
+// ClaimsFromContext 从上下文中获取JWT认证后的声明
+//
+// 参数:
+//   - ctx: 请求上下文
+//
+// 返回值:
+//   - *Claims: 令牌中的声明
+//   - bool: 上下文中是否有声明
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok
+}
+
+// jwtOptions 存储JWT认证的配置选项
+type jwtOptions struct {
+	// scheme 认证方案
+	scheme string
+	// algorithms 允许的签名算法
+	algorithms []string
+	// issuers 允许的签发者，为空时不校验
+	issuers []string
+	// audiences 允许的受众，为空时不校验
+	audiences []string
+	// clockSkew 校验exp、nbf时容忍的时钟偏差
+	clockSkew time.Duration
+	// requireExp 是否要求令牌包含exp
+	requireExp bool
+	// now 获取当前时间，便于测试
+	now func() time.Time
+}
+
+// JWTOption 定义JWT认证配置选项的函数类型
+type JWTOption func(*jwtOptions)
+
+// WithScheme 设置authorization头中的认证方案，默认为"Bearer"
+//
+// 参数:
+//   - scheme: 认证方案
+//
+// 返回值:
+//   - JWTOption: 设置认证方案选项的函数
+func WithScheme(scheme string) JWTOption {
+	return func(o *jwtOptions) {
+		o.scheme = scheme
+	}
+}
+
+// WithAlgorithms 设置允许的签名算法，默认允许HS256、RS256、ES256和EdDSA
+//
+// 参数:
+//   - algorithms: 签名算法
+//
+// 返回值:
+//   - JWTOption: 设置签名算法选项的函数
+func WithAlgorithms(algorithms ...string) JWTOption {
+	return func(o *jwtOptions) {
+		o.algorithms = algorithms
+	}
+}
+
+// WithIssuer 设置允许的签发者，令牌的iss须为其中之一
+//
+// 参数:
+//   - issuers: 签发者
+//
+// 返回值:
+//   - JWTOption: 设置签发者选项的函数
+func WithIssuer(issuers ...string) JWTOption {
+	return func(o *jwtOptions) {
+		o.issuers = append(o.issuers, issuers...)
+	}
+}
+
+// WithAudience 设置允许的受众，令牌的aud须包含其中之一
+//
+// 参数:
+//   - audiences: 受众
+//
+// 返回值:
+//   - JWTOption: 设置受众选项的函数
+func WithAudience(audiences ...string) JWTOption {
+	return func(o *jwtOptions) {
+		o.audiences = append(o.audiences, audiences...)
+	}
+}
+
+// WithClockSkew 设置校验exp、nbf时容忍的时钟偏差，默认1分钟
+//
+// 参数:
+//   - skew: 时钟偏差
+//
+// 返回值:
+//   - JWTOption: 设置时钟偏差选项的函数
+func WithClockSkew(skew time.Duration) JWTOption {
+	return func(o *jwtOptions) {
+		o.clockSkew = skew
+	}
+}
+
+// WithExpirationRequired 设置是否要求令牌包含exp，默认要求
+//
+// 参数:
+//   - required: 是否要求
+//
+// 返回值:
+//   - JWTOption: 设置exp要求选项的函数
+func WithExpirationRequired(required bool) JWTOption {
+	return func(o *jwtOptions) {
+		o.requireExp = required
+	}
+}
+
+// NewJWTAuthFunc 创建校验JWT的认证函数
+// 从authorization头提取令牌，校验签名、exp、nbf、iss和aud，
+// 校验通过后将声明放入上下文，可通过ClaimsFromContext获取
+//
+// 参数:
+//   - keys: 验证密钥集合，可使用StaticKeys或JWKS
+//   - opts: 可选的配置选项
+//
+// 返回值:
+//   - AuthFunc: 认证函数
+func NewJWTAuthFunc(keys KeySet, opts ...JWTOption) AuthFunc {
+	o := &jwtOptions{
+		scheme:     "Bearer",
+		algorithms: defaultJWTAlgorithms,
+		clockSkew:  time.Minute,
+		requireExp: true,
+		now:        time.Now,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods(o.algorithms),
+		jwt.WithLeeway(o.clockSkew),
+		jwt.WithTimeFunc(o.now),
+	}
+	if o.requireExp {
+		parserOpts = append(parserOpts, jwt.WithExpirationRequired())
+	}
+	if len(o.audiences) > 0 {
+		parserOpts = append(parserOpts, jwt.WithAudience(o.audiences...))
+	}
+	parser := jwt.NewParser(parserOpts...)
+
+	return func(ctx context.Context, fullMethodName string) (context.Context, error) {
+		raw, err := AuthFromMD(ctx, o.scheme)
+		if err != nil {
+			return nil, err
+		}
+		mapClaims := jwt.MapClaims{}
+		_, err = parser.ParseWithClaims(raw, mapClaims, func(token *jwt.Token) (any, error) {
+			kid, _ := token.Header["kid"].(string)
+			return keys.Key(ctx, kid, token.Method.Alg())
+		})
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, jwtErrorMessage(err))
+		}
+		claims := newClaims(mapClaims)
+		if len(o.issuers) > 0 && !slices.Contains(o.issuers, claims.Issuer) {
+			return nil, status.Error(codes.Unauthenticated, "invalid token issuer")
+		}
+		return context.WithValue(ctx, claimsKey{}, claims), nil
+	}
+}
+
+// jwtErrorMessage 将JWT校验错误转换为不泄露细节的错误信息
+func jwtErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return "token expired"
+	case errors.Is(err, jwt.ErrTokenNotValidYet):
+		return "token not valid yet"
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return "invalid token audience"
+	case errors.Is(err, jwt.ErrTokenRequiredClaimMissing):
+		return "token missing required claim"
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return "malformed token"
+	default:
+		return "invalid token"
+	}
+}
+
+// newClaims 从已校验的声明构造Claims
+func newClaims(m jwt.MapClaims) *Claims {
+	claims := &Claims{Raw: map[string]any(m)}
+	claims.Issuer, _ = m.GetIssuer()
+	claims.Subject, _ = m.GetSubject()
+	if aud, err := m.GetAudience(); err == nil {
+		claims.Audience = aud
+	}
+	if exp, err := m.GetExpirationTime(); err == nil && exp != nil {
+		claims.ExpiresAt = exp.Time
+	}
+	if nbf, err := m.GetNotBefore(); err == nil && nbf != nil {
+		claims.NotBefore = nbf.Time
+	}
+	if iat, err := m.GetIssuedAt(); err == nil && iat != nil {
+		claims.IssuedAt = iat.Time
+	}
+	claims.ID, _ = m["jti"].(string)
+	return claims
+}
@@ -0,0 +1,225 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// testKeys 测试用的各算法密钥
+type testKeys struct {
+	hmac    []byte
+	rsa     *rsa.PrivateKey
+	ecdsa   *ecdsa.PrivateKey
+	ed25519 ed25519.PrivateKey
+}
+
+func newTestKeys(t *testing.T) *testKeys {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return &testKeys{hmac: []byte("0123456789abcdef0123456789abcdef"), rsa: rsaKey, ecdsa: ecKey, ed25519: edKey}
+}
+
+// staticKeys 返回以算法名为kid的验证密钥集合
+func (k *testKeys) staticKeys() StaticKeys {
+	return StaticKeys{
+		"hs":    k.hmac,
+		"rs":    &k.rsa.PublicKey,
+		"es":    &k.ecdsa.PublicKey,
+		"eddsa": k.ed25519.Public(),
+	}
+}
+
+// sign 使用指定算法签发令牌
+func (k *testKeys) sign(t *testing.T, alg, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	var method jwt.SigningMethod
+	var key crypto.PrivateKey
+	switch alg {
+	case "HS256":
+		method, key = jwt.SigningMethodHS256, k.hmac
+	case "RS256":
+		method, key = jwt.SigningMethodRS256, k.rsa
+	case "ES256":
+		method, key = jwt.SigningMethodES256, k.ecdsa
+	case "EdDSA":
+		method, key = jwt.SigningMethodEdDSA, k.ed25519
+	}
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+// bearerContext 构造携带令牌的请求上下文
+func bearerContext(token string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+}
+
+func TestNewJWTAuthFunc_Algorithms(t *testing.T) {
+	keys := newTestKeys(t)
+	authFunc := NewJWTAuthFunc(keys.staticKeys())
+	exp := time.Now().Add(time.Hour).Unix()
+
+	tests := []struct {
+		alg string
+		kid string
+	}{
+		{"HS256", "hs"},
+		{"RS256", "rs"},
+		{"ES256", "es"},
+		{"EdDSA", "eddsa"},
+		// 没有kid时使用唯一与算法匹配的密钥
+		{"RS256", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.alg+"/"+tt.kid, func(t *testing.T) {
+			token := keys.sign(t, tt.alg, tt.kid, jwt.MapClaims{"sub": "alice", "exp": exp, "role": "admin"})
+			ctx, err := authFunc(bearerContext(token), "/test/method")
+			require.NoError(t, err)
+
+			claims, ok := ClaimsFromContext(ctx)
+			require.True(t, ok)
+			assert.Equal(t, "alice", claims.Subject)
+			assert.Equal(t, exp, claims.ExpiresAt.Unix())
+			assert.Equal(t, "admin", claims.Raw["role"])
+		})
+	}
+}
+
+func TestNewJWTAuthFunc_Validation(t *testing.T) {
+	keys := newTestKeys(t)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	authFunc := NewJWTAuthFunc(keys.staticKeys(),
+		WithIssuer("https://issuer.example.com", "https://other.example.com"),
+		WithAudience("api"),
+		WithClockSkew(30*time.Second),
+		func(o *jwtOptions) { o.now = func() time.Time { return now } },
+	)
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss": "https://issuer.example.com",
+			"aud": []string{"web", "api"},
+			"exp": now.Add(time.Minute).Unix(),
+			"nbf": now.Add(-time.Minute).Unix(),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(c jwt.MapClaims)
+		wantMsg string
+	}{
+		{"合法令牌", func(c jwt.MapClaims) {}, ""},
+		{"第二个签发者", func(c jwt.MapClaims) { c["iss"] = "https://other.example.com" }, ""},
+		{"过期时间在容忍范围内", func(c jwt.MapClaims) { c["exp"] = now.Add(-20 * time.Second).Unix() }, ""},
+		{"已过期", func(c jwt.MapClaims) { c["exp"] = now.Add(-time.Minute).Unix() }, "token expired"},
+		{"生效时间在容忍范围内", func(c jwt.MapClaims) { c["nbf"] = now.Add(20 * time.Second).Unix() }, ""},
+		{"尚未生效", func(c jwt.MapClaims) { c["nbf"] = now.Add(time.Minute).Unix() }, "token not valid yet"},
+		{"缺少exp", func(c jwt.MapClaims) { delete(c, "exp") }, "token missing required claim"},
+		{"签发者不匹配", func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }, "invalid token issuer"},
+		{"受众不匹配", func(c jwt.MapClaims) { c["aud"] = "web" }, "invalid token audience"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := valid()
+			tt.modify(claims)
+			_, err := authFunc(bearerContext(keys.sign(t, "ES256", "es", claims)), "/test/method")
+			if tt.wantMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+			assert.Equal(t, tt.wantMsg, status.Convert(err).Message())
+		})
+	}
+}
+
+func TestNewJWTAuthFunc_Rejects(t *testing.T) {
+	keys := newTestKeys(t)
+	other := newTestKeys(t)
+	exp := time.Now().Add(time.Hour).Unix()
+	claims := jwt.MapClaims{"exp": exp}
+
+	// 以RSA公钥模数作为HMAC密钥伪造令牌，验证不会发生算法混淆
+	confused := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	confused.Header["kid"] = "rs"
+	confusedToken, err := confused.SignedString(keys.rsa.PublicKey.N.Bytes())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		authFunc AuthFunc
+	}{
+		{"缺少authorization头", context.Background(), NewJWTAuthFunc(keys.staticKeys())},
+		{"格式错误的令牌", bearerContext("not-a-jwt"), NewJWTAuthFunc(keys.staticKeys())},
+		{"签名密钥不匹配", bearerContext(other.sign(t, "RS256", "rs", claims)), NewJWTAuthFunc(keys.staticKeys())},
+		{"未知kid", bearerContext(keys.sign(t, "RS256", "unknown", claims)), NewJWTAuthFunc(keys.staticKeys())},
+		{"算法混淆", bearerContext(confusedToken), NewJWTAuthFunc(keys.staticKeys())},
+		{"算法不在允许列表", bearerContext(keys.sign(t, "HS256", "hs", claims)), NewJWTAuthFunc(keys.staticKeys(), WithAlgorithms("RS256"))},
+		{"none算法", bearerContext(noneToken(t, claims)), NewJWTAuthFunc(keys.staticKeys())},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, err := tt.authFunc(tt.ctx, "/test/method")
+			assert.Nil(t, ctx)
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		})
+	}
+}
+
+// noneToken 签发alg为none的令牌
+func noneToken(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+	return token
+}
+
+func TestNewJWTAuthFunc_Interceptor(t *testing.T) {
+	keys := newTestKeys(t)
+	interceptor := UnaryServerInterceptor(NewJWTAuthFunc(keys.staticKeys()))
+	token := keys.sign(t, "EdDSA", "eddsa", jwt.MapClaims{"sub": "bob", "exp": time.Now().Add(time.Hour).Unix()})
+
+	var subject string
+	handler := func(ctx context.Context, req any) (any, error) {
+		claims, _ := ClaimsFromContext(ctx)
+		subject = claims.Subject
+		return "ok", nil
+	}
+	_, err := interceptor(bearerContext(token), nil, &grpc.UnaryServerInfo{FullMethod: "/test/method"}, handler)
+	require.NoError(t, err)
+	assert.Equal(t, "bob", subject)
+}
+
+func TestClaimsFromContext_Missing(t *testing.T) {
+	claims, ok := ClaimsFromContext(context.Background())
+	assert.False(t, ok)
+	assert.Nil(t, claims)
+}
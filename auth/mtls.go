@@ -0,0 +1,224 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"path"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// PeerIdentity 从已验证的客户端证书中提取的身份
+type PeerIdentity struct {
+	// SPIFFEID 证书URI SAN中的SPIFFE ID，例如"spiffe://example.org/ns/prod/sa/api"，没有时为空
+	SPIFFEID string
+	// TrustDomain SPIFFE ID的信任域，例如"example.org"
+	TrustDomain string
+	// DNSNames 证书的DNS SAN
+	DNSNames []string
+	// CommonName 证书主题的CN
+	CommonName string
+	// Certificate 客户端证书
+	Certificate *x509.Certificate
+}
+
+// peerIdentityKey 身份在上下文中的键
+type peerIdentityKey struct{}
+
+// PeerIdentityFromContext 从上下文中获取mTLS认证后的客户端身份
+//
+// 参数:
+//   - ctx: 请求上下文
+//
+// 返回值:
+//   - *PeerIdentity: 客户端身份
+//   - bool: 上下文中是否有客户端身份
+func PeerIdentityFromContext(ctx context.Context) (*PeerIdentity, bool) {
+	id, ok := ctx.Value(peerIdentityKey{}).(*PeerIdentity)
+	return id, ok
+}
+
+// peerRule 方法与允许的SPIFFE ID的对应规则
+type peerRule struct {
+	method string
+	ids    []string
+	// anyPeer 允许任何已验证的客户端
+	anyPeer bool
+}
+
+// mtlsOptions 存储mTLS认证的配置选项
+type mtlsOptions struct {
+	// rules 按方法配置的SPIFFE ID允许列表
+	rules []peerRule
+}
+
+// MTLSOption 定义mTLS认证配置选项的函数类型
+type MTLSOption func(*mtlsOptions)
+
+// WithAllowedPeers 为匹配的方法设置允许的SPIFFE ID
+// 方法模式按path.Match匹配完整的方法名，多条规则按添加顺序取第一条匹配的规则，
+// 例如"/*/*"可作为兜底规则；没有匹配规则的方法拒绝调用。
+// ID以"/*"结尾时按前缀匹配，"spiffe://example.org/*"允许整个信任域，
+// "spiffe://example.org/ns/prod/*"允许该路径下的所有ID；否则要求完全相同
+//
+// 参数:
+//   - method: 方法模式
+//   - ids: 允许的SPIFFE ID或前缀
+//
+// 返回值:
+//   - MTLSOption: 设置允许列表选项的函数
+func WithAllowedPeers(method string, ids ...string) MTLSOption {
+	return func(o *mtlsOptions) {
+		o.rules = append(o.rules, peerRule{method: method, ids: ids})
+	}
+}
+
+// WithAnyVerifiedPeer 允许任何已验证证书的客户端调用匹配的方法，不检查SPIFFE ID
+// 与WithAllowedPeers一起按添加顺序匹配，例如最后添加"/*/*"可以允许其余方法的所有已验证客户端
+//
+// 参数:
+//   - method: 方法模式
+//
+// 返回值:
+//   - MTLSOption: 设置允许列表选项的函数
+func WithAnyVerifiedPeer(method string) MTLSOption {
+	return func(o *mtlsOptions) {
+		o.rules = append(o.rules, peerRule{method: method, anyPeer: true})
+	}
+}
+
+// allowed 判断身份是否允许调用方法，没有匹配的规则时拒绝
+func (o *mtlsOptions) allowed(fullMethodName string, id *PeerIdentity) bool {
+	for _, rule := range o.rules {
+		if ok, _ := path.Match(rule.method, fullMethodName); !ok {
+			continue
+		}
+		if rule.anyPeer {
+			return true
+		}
+		if id.SPIFFEID == "" {
+			return false
+		}
+		for _, allowed := range rule.ids {
+			if matchSPIFFEID(allowed, id.SPIFFEID) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// matchSPIFFEID 按完全相同或"/*"前缀匹配SPIFFE ID
+func matchSPIFFEID(pattern, id string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasSuffix(prefix, "/") {
+		return strings.HasPrefix(id, prefix)
+	}
+	return pattern == id
+}
+
+// NewMTLSAuthFunc 创建基于双向TLS客户端证书的认证函数
+// 要求连接使用TLS且客户端证书已通过验证，从证书中提取SPIFFE ID、DNS SAN和CN，
+// 写入上下文后可通过PeerIdentityFromContext获取。
+// 未通过证书验证时返回Unauthenticated，不在方法允许列表中时返回PermissionDenied。
+// 默认拒绝所有方法，需要通过WithAllowedPeers或WithAnyVerifiedPeer配置允许列表
+//
+// 参数:
+//   - opts: 可选的配置选项
+//
+// 返回值:
+//   - AuthFunc: 认证函数
+func NewMTLSAuthFunc(opts ...MTLSOption) AuthFunc {
+	o := &mtlsOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return func(ctx context.Context, fullMethodName string) (context.Context, error) {
+		id, err := peerIdentity(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !o.allowed(fullMethodName, id) {
+			return nil, status.Error(codes.PermissionDenied, "peer not allowed")
+		}
+		return context.WithValue(ctx, peerIdentityKey{}, id), nil
+	}
+}
+
+// peerIdentity 从连接的TLS信息中提取客户端身份
+func peerIdentity(ctx context.Context) (*PeerIdentity, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "missing peer")
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "connection is not using tls")
+	}
+	chains := tlsInfo.State.VerifiedChains
+	if len(chains) == 0 || len(chains[0]) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "missing verified client certificate")
+	}
+	cert := chains[0][0]
+	id := &PeerIdentity{
+		DNSNames:    cert.DNSNames,
+		CommonName:  cert.Subject.CommonName,
+		Certificate: cert,
+	}
+	for _, uri := range cert.URIs {
+		if uri.Scheme != "spiffe" {
+			continue
+		}
+		// SPIFFE规范要求证书只包含一个SPIFFE ID
+		if id.SPIFFEID != "" {
+			return nil, status.Error(codes.Unauthenticated, "multiple spiffe ids in client certificate")
+		}
+		spiffeID := uri.String()
+		trustDomain, ok := parseSPIFFEID(spiffeID)
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "invalid spiffe id in client certificate")
+		}
+		id.SPIFFEID, id.TrustDomain = spiffeID, trustDomain
+	}
+	return id, nil
+}
+
+// parseSPIFFEID 按SPIFFE规范校验SPIFFE ID并返回信任域
+// 信任域只能包含小写字母、数字、"."、"-"和"_"，路径段只能包含字母、数字、"."、"-"和"_"，
+// 不允许空路径段、末尾的"/"、"."和".."路径段以及百分号编码，避免按前缀匹配时被绕过
+func parseSPIFFEID(id string) (string, bool) {
+	rest, ok := strings.CutPrefix(id, "spiffe://")
+	if !ok {
+		return "", false
+	}
+	trustDomain, path, hasPath := strings.Cut(rest, "/")
+	if trustDomain == "" || !validSPIFFEChars(trustDomain, false) {
+		return "", false
+	}
+	if !hasPath {
+		return trustDomain, true
+	}
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" || segment == "." || segment == ".." || !validSPIFFEChars(segment, true) {
+			return "", false
+		}
+	}
+	return trustDomain, true
+}
+
+// validSPIFFEChars 判断字符串是否只包含SPIFFE ID允许的字符，upper表示是否允许大写字母
+func validSPIFFEChars(s string, upper bool) bool {
+	for _, c := range []byte(s) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '.', c == '-', c == '_':
+		case upper && c >= 'A' && c <= 'Z':
+		default:
+			return false
+		}
+	}
+	return true
+}
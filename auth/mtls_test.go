@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// newClientCert 生成包含指定SAN的客户端证书
+func newClientCert(t *testing.T, cn string, dnsNames []string, uris ...string) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	for _, raw := range uris {
+		u, err := url.Parse(raw)
+		require.NoError(t, err)
+		tmpl.URIs = append(tmpl.URIs, u)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+// tlsPeerContext 构造客户端证书已验证的请求上下文
+func tlsPeerContext(cert *x509.Certificate) context.Context {
+	state := tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}, VerifiedChains: [][]*x509.Certificate{{cert}}}
+	return peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{State: state}})
+}
+
+func TestNewMTLSAuthFunc_Identity(t *testing.T) {
+	cert := newClientCert(t, "api-client", []string{"api.prod.svc", "api"}, "spiffe://example.org/ns/prod/sa/api")
+	ctx, err := NewMTLSAuthFunc(WithAnyVerifiedPeer("/*/*"))(tlsPeerContext(cert), "/test/method")
+	require.NoError(t, err)
+
+	id, ok := PeerIdentityFromContext(ctx)
+	require.True(t, ok)
+	assert.Equal(t, "spiffe://example.org/ns/prod/sa/api", id.SPIFFEID)
+	assert.Equal(t, "example.org", id.TrustDomain)
+	assert.Equal(t, []string{"api.prod.svc", "api"}, id.DNSNames)
+	assert.Equal(t, "api-client", id.CommonName)
+	assert.Same(t, cert, id.Certificate)
+}
+
+func TestNewMTLSAuthFunc_Unauthenticated(t *testing.T) {
+	tlsContext := func(state tls.ConnectionState) context.Context {
+		return peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{State: state}})
+	}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+	}{
+		{"没有peer", context.Background()},
+		{"非TLS连接", peer.NewContext(context.Background(), &peer.Peer{})},
+		{"没有客户端证书", tlsContext(tls.ConnectionState{})},
+		{"证书未验证", tlsContext(tls.ConnectionState{PeerCertificates: []*x509.Certificate{newClientCert(t, "a", nil)}})},
+		{"多个SPIFFE ID", tlsPeerContext(newClientCert(t, "a", nil, "spiffe://example.org/a", "spiffe://example.org/b"))},
+		{"SPIFFE ID带查询参数", tlsPeerContext(newClientCert(t, "a", nil, "spiffe://example.org/a?x=1"))},
+		{"SPIFFE ID带..路径段", tlsPeerContext(newClientCert(t, "a", nil, "spiffe://example.org/ns/prod/../admin"))},
+		{"SPIFFE ID带.路径段", tlsPeerContext(newClientCert(t, "a", nil, "spiffe://example.org/ns/./prod"))},
+		{"SPIFFE ID带空路径段", tlsPeerContext(newClientCert(t, "a", nil, "spiffe://example.org/ns//prod"))},
+		{"SPIFFE ID以/结尾", tlsPeerContext(newClientCert(t, "a", nil, "spiffe://example.org/ns/prod/"))},
+		{"SPIFFE ID带百分号编码", tlsPeerContext(newClientCert(t, "a", nil, "spiffe://example.org/ns/prod/%2e%2e/admin"))},
+		{"SPIFFE ID信任域含大写字母", tlsPeerContext(newClientCert(t, "a", nil, "spiffe://Example.org/a"))},
+		{"SPIFFE ID带端口", tlsPeerContext(newClientCert(t, "a", nil, "spiffe://example.org:8443/a"))},
+		{"SPIFFE ID带用户信息", tlsPeerContext(newClientCert(t, "a", nil, "spiffe://user@example.org/a"))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, err := NewMTLSAuthFunc()(tt.ctx, "/test/method")
+			assert.Nil(t, ctx)
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		})
+	}
+}
+
+func TestNewMTLSAuthFunc_AllowedPeers(t *testing.T) {
+	authFunc := NewMTLSAuthFunc(
+		WithAllowedPeers("/admin.Admin/*", "spiffe://example.org/ns/ops/sa/admin"),
+		WithAllowedPeers("/orders.Orders/*", "spiffe://example.org/ns/prod/*", "spiffe://partner.org/*"),
+		WithAllowedPeers("/internal.*/*", "spiffe://example.org/*"),
+		WithAnyVerifiedPeer("/public.*/*"),
+	)
+
+	tests := []struct {
+		name    string
+		method  string
+		id      string
+		wantErr bool
+	}{
+		{"完全匹配", "/admin.Admin/Delete", "spiffe://example.org/ns/ops/sa/admin", false},
+		{"不完全匹配", "/admin.Admin/Delete", "spiffe://example.org/ns/ops/sa/admin2", true},
+		{"路径前缀匹配", "/orders.Orders/Create", "spiffe://example.org/ns/prod/sa/web", false},
+		{"路径前缀按段匹配", "/orders.Orders/Create", "spiffe://example.org/ns/production/sa/web", true},
+		{"信任域匹配", "/orders.Orders/Create", "spiffe://partner.org/any/path", false},
+		{"信任域后缀不匹配", "/orders.Orders/Create", "spiffe://partner.org.evil/any", true},
+		{"其他信任域", "/internal.Cache/Get", "spiffe://partner.org/any", true},
+		{"没有SPIFFE ID", "/internal.Cache/Get", "", true},
+		{"显式允许任何已验证客户端", "/public.Echo/Echo", "", false},
+		{"没有规则的方法拒绝", "/other.Other/Get", "spiffe://example.org/ns/ops/sa/admin", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var uris []string
+			if tt.id != "" {
+				uris = append(uris, tt.id)
+			}
+			_, err := authFunc(tlsPeerContext(newClientCert(t, "client", nil, uris...)), tt.method)
+			if tt.wantErr {
+				assert.Equal(t, codes.PermissionDenied, status.Code(err))
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+// Package auth 提供gRPC认证拦截器的配置选项
+package auth
+
+import (
+	"path"
+	"time"
+)
+
+// options 存储认证拦截器的配置选项
+type options struct {
+	// publicMethods 跳过认证的方法模式
+	publicMethods []string
+	// refreshBefore 客户端在令牌过期前多久开始后台刷新
+	refreshBefore time.Duration
+	// streamReauth 是否在流的生命周期内重新认证
+	streamReauth bool
+	// reauthInterval 流重新认证的间隔
+	reauthInterval time.Duration
+}
+
+// apply 将给定的选项应用到选项结构体中
+//
+// 参数:
+//   - opts: 可变数量的选项函数
+//
+// 返回值:
+//   - *options: 指向更新后的选项结构体的指针
+func (o *options) apply(opts ...Option) *options {
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Option 定义用于配置认证拦截器选项的函数类型
+type Option func(o *options)
+
+// defaultOptions 返回默认的配置选项
+//
+// 返回值:
+//   - *options: 包含默认选项的结构体指针
+func defaultOptions() *options {
+	return &options{refreshBefore: defaultRefreshBefore}
+}
+
+// WithPublicMethods 设置跳过认证的公开方法
+// 模式按path.Match匹配完整的方法名，例如"/grpc.health.v1.Health/*"匹配健康检查服务的所有方法。
+// 公开方法既不执行全局认证函数，也不执行服务的AuthFuncOverride
+//
+// 参数:
+//   - patterns: 方法模式
+//
+// 返回值:
+//   - Option: 设置公开方法选项的函数
+func WithPublicMethods(patterns ...string) Option {
+	return func(o *options) {
+		o.publicMethods = append(o.publicMethods, patterns...)
+	}
+}
+
+// isPublic 判断方法是否为公开方法
+func (o *options) isPublic(fullMethodName string) bool {
+	for _, pattern := range o.publicMethods {
+		if ok, _ := path.Match(pattern, fullMethodName); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// WithRefreshBefore 设置客户端拦截器在令牌过期前多久开始后台刷新，默认1分钟
+// 令牌源已是*CachingTokenSource时该选项不生效
+//
+// 参数:
+//   - d: 提前刷新的时间
+//
+// 返回值:
+//   - Option: 设置提前刷新时间选项的函数
+func WithRefreshBefore(d time.Duration) Option {
+	return func(o *options) {
+		o.refreshBefore = d
+	}
+}
+
+// WithStreamReauth 设置流式服务器拦截器在流的生命周期内定期重新认证
+// 间隔到达或凭证过期时（以先到者为准）重新执行认证函数，interval小于等于0时只在凭证过期时重新认证；
+// 凭证过期时间默认读取JWT声明、令牌内省结果和API Key。重新认证失败或凭证已经过期时取消流的上下文并以Unauthenticated结束流。
+// 处理函数需要在流的上下文结束后返回。客户端可以通过实现AuthorizationMessage的消息在流中发送新的凭证
+//
+// 参数:
+//   - interval: 重新认证的间隔
+//
+// 返回值:
+//   - Option: 设置流重新认证选项的函数
+func WithStreamReauth(interval time.Duration) Option {
+	return func(o *options) {
+		o.streamReauth = true
+		o.reauthInterval = interval
+	}
+}
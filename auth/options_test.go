@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithPublicMethods(t *testing.T) {
+	o := defaultOptions().apply(
+		WithPublicMethods("/grpc.health.v1.Health/*"),
+		WithPublicMethods("/grpc.reflection.*/*", "/helloworld.Greeter/SayHello"),
+	)
+
+	tests := []struct {
+		name       string
+		fullMethod string
+		want       bool
+	}{
+		{"匹配服务下的所有方法", "/grpc.health.v1.Health/Check", true},
+		{"匹配服务名通配", "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo", true},
+		{"精确匹配", "/helloworld.Greeter/SayHello", true},
+		{"同服务的其他方法", "/helloworld.Greeter/SayGoodbye", false},
+		{"通配符不跨越斜杠", "/grpc.health.v1.Health/Check/Extra", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, o.isPublic(tt.fullMethod))
+		})
+	}
+}
+
+func TestDefaultOptions_NoPublicMethods(t *testing.T) {
+	assert.False(t, defaultOptions().isPublic("/grpc.health.v1.Health/Check"))
+}
@@ -0,0 +1,225 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// AuthorizationMessage 在流中携带新凭证的请求消息
+// 包含string authorization字段的proto消息生成的GetAuthorization方法即实现该接口，
+// 返回值为完整的authorization头，例如"Bearer <token>"，为空时表示消息不携带凭证。
+// 启用WithStreamReauth时，收到携带凭证的消息后立即用新凭证重新认证，消息仍会交给处理函数
+type AuthorizationMessage interface {
+	// GetAuthorization 返回新的authorization头
+	GetAuthorization() string
+}
+
+// reauthStream 在流的生命周期内重新认证的服务器流
+type reauthStream struct {
+	grpc.ServerStream
+	srv            any
+	fullMethodName string
+	authFunc       AuthFunc
+	o              *options
+	// base 可取消的流上下文，重新认证失败时取消
+	base   context.Context
+	cancel context.CancelCauseFunc
+	// reauthMu 串行执行重新认证
+	reauthMu sync.Mutex
+
+	mu      sync.Mutex
+	md      metadata.MD
+	ctx     context.Context
+	timer   *time.Timer
+	err     error
+	stopped bool
+}
+
+// minReauthDelay 两次重新认证之间的最小间隔，避免凭证即将过期时频繁重新认证
+const minReauthDelay = 10 * time.Millisecond
+
+// serveWithReauth 认证后执行处理函数，并在流的生命周期内重新认证
+// 重新认证失败时取消流的上下文，等待处理函数返回后以Unauthenticated结束流。
+// 处理函数需要在流的上下文结束后返回，重新认证失败后流的方法不再转发给底层的流
+func serveWithReauth(srv any, stream grpc.ServerStream, fullMethodName string, handler grpc.StreamHandler, authFunc AuthFunc, o *options) error {
+	base, cancel := context.WithCancelCause(stream.Context())
+	defer cancel(nil)
+	md, _ := metadata.FromIncomingContext(base)
+	newCtx, err := authenticate(base, srv, fullMethodName, authFunc, o)
+	if err != nil {
+		return err
+	}
+	s := &reauthStream{
+		ServerStream:   stream,
+		srv:            srv,
+		fullMethodName: fullMethodName,
+		authFunc:       authFunc,
+		o:              o,
+		base:           base,
+		cancel:         cancel,
+		md:             md,
+		ctx:            newCtx,
+	}
+	s.mu.Lock()
+	err = s.schedule(newCtx)
+	s.mu.Unlock()
+	defer s.stop()
+	if err != nil {
+		return err
+	}
+
+	err = handler(srv, s)
+	s.stop()
+	if failure := s.failure(); failure != nil {
+		return failure
+	}
+	return err
+}
+
+// Context 返回最近一次认证后的上下文
+func (s *reauthStream) Context() context.Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ctx
+}
+
+// SetHeader 重新认证失败后不再设置响应头
+func (s *reauthStream) SetHeader(md metadata.MD) error {
+	if err := s.failure(); err != nil {
+		return err
+	}
+	return s.ServerStream.SetHeader(md)
+}
+
+// SendHeader 重新认证失败后不再发送响应头
+func (s *reauthStream) SendHeader(md metadata.MD) error {
+	if err := s.failure(); err != nil {
+		return err
+	}
+	return s.ServerStream.SendHeader(md)
+}
+
+// SendMsg 重新认证失败后不再发送消息
+func (s *reauthStream) SendMsg(m any) error {
+	if err := s.failure(); err != nil {
+		return err
+	}
+	return s.ServerStream.SendMsg(m)
+}
+
+// RecvMsg 接收消息，消息携带新凭证时立即重新认证
+func (s *reauthStream) RecvMsg(m any) error {
+	if err := s.failure(); err != nil {
+		return err
+	}
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		if failure := s.failure(); failure != nil {
+			return failure
+		}
+		return err
+	}
+	if msg, ok := m.(AuthorizationMessage); ok && msg.GetAuthorization() != "" {
+		s.mu.Lock()
+		md := s.md.Copy()
+		md.Set(headerAuthorize, msg.GetAuthorization())
+		s.md = md
+		s.mu.Unlock()
+		return s.reauthenticate()
+	}
+	return nil
+}
+
+// reauthenticate 使用当前的元数据重新认证
+// 失败时取消流的上下文，错误不是Unauthenticated时转换为Unauthenticated
+func (s *reauthStream) reauthenticate() error {
+	s.reauthMu.Lock()
+	defer s.reauthMu.Unlock()
+	s.mu.Lock()
+	if s.err != nil || s.stopped {
+		err := s.err
+		s.mu.Unlock()
+		return err
+	}
+	ctx := metadata.NewIncomingContext(s.base, s.md)
+	s.mu.Unlock()
+
+	newCtx, err := authenticate(ctx, s.srv, s.fullMethodName, s.authFunc, s.o)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil || s.stopped {
+		return s.err
+	}
+	if err != nil {
+		if status.Code(err) != codes.Unauthenticated {
+			err = status.Errorf(codes.Unauthenticated, "re-authentication failed: %s", status.Convert(err).Message())
+		}
+		s.fail(err)
+		return err
+	}
+	s.ctx = newCtx
+	if err := s.schedule(newCtx); err != nil {
+		s.fail(err)
+		return err
+	}
+	return nil
+}
+
+// fail 记录重新认证失败的错误并取消流的上下文，调用方需持有mu
+func (s *reauthStream) fail(err error) {
+	s.err = err
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	s.cancel(err)
+}
+
+// schedule 按间隔和凭证过期时间安排下一次重新认证，调用方需持有mu
+// 凭证已经过期时返回Unauthenticated，认证函数允许时钟偏差时过期的凭证仍可能通过认证，但无法再续期
+func (s *reauthStream) schedule(ctx context.Context) error {
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	d, ok := s.o.reauthInterval, s.o.reauthInterval > 0
+	if expiry := defaultExpiry(ctx); !expiry.IsZero() {
+		until := time.Until(expiry)
+		if until <= 0 {
+			s.timer = nil
+			return status.Error(codes.Unauthenticated, "credentials expired")
+		}
+		if !ok || until < d {
+			d, ok = until, true
+		}
+	}
+	if !ok {
+		s.timer = nil
+		return nil
+	}
+	s.timer = time.AfterFunc(max(d, minReauthDelay), func() {
+		_ = s.reauthenticate()
+	})
+	return nil
+}
+
+// stop 停止重新认证
+func (s *reauthStream) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopped = true
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+}
+
+// failure 返回重新认证失败的错误
+func (s *reauthStream) failure() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
@@ -0,0 +1,234 @@
+package auth
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// refreshMessage 携带新凭证的测试消息
+type refreshMessage struct {
+	authorization string
+}
+
+func (m *refreshMessage) GetAuthorization() string { return m.authorization }
+
+// chanServerStream 从通道接收消息的测试服务器流
+type chanServerStream struct {
+	mockServerStream
+	recv chan string
+}
+
+func (s *chanServerStream) RecvMsg(m any) error {
+	select {
+	case authorization, ok := <-s.recv:
+		if !ok {
+			return io.EOF
+		}
+		m.(*refreshMessage).authorization = authorization
+		return nil
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+}
+
+// tokenAuthFunc 只接受valid开头的令牌，令牌revoked后失效
+func tokenAuthFunc(revoked *atomic.Bool, calls *atomic.Int32) AuthFunc {
+	return func(ctx context.Context, fullMethodName string) (context.Context, error) {
+		calls.Add(1)
+		md, _ := metadata.FromIncomingContext(ctx)
+		token := md.Get(headerAuthorize)
+		if len(token) == 0 || revoked.Load() && token[0] == "Bearer valid" {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		return context.WithValue(ctx, subjectKey{}, token[0]), nil
+	}
+}
+
+func TestStreamReauth_Periodic(t *testing.T) {
+	var revoked atomic.Bool
+	var calls atomic.Int32
+	interceptor := StreamServerInterceptor(tokenAuthFunc(&revoked, &calls), WithStreamReauth(20*time.Millisecond))
+	stream := &mockServerStream{ctx: tokenContext("valid")}
+	info := &grpc.StreamServerInfo{FullMethod: "/chat.Chat/Join"}
+
+	var handlerCtx context.Context
+	err := interceptor(nil, stream, info, func(srv any, stream grpc.ServerStream) error {
+		handlerCtx = stream.Context()
+		// 经过几次成功的重新认证后吊销令牌
+		time.Sleep(70 * time.Millisecond)
+		assert.NoError(t, handlerCtx.Err())
+		revoked.Store(true)
+		<-handlerCtx.Done()
+		return handlerCtx.Err()
+	})
+
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.GreaterOrEqual(t, calls.Load(), int32(3))
+	assert.Equal(t, err, context.Cause(handlerCtx))
+}
+
+func TestStreamReauth_AtExpiry(t *testing.T) {
+	var calls atomic.Int32
+	authFunc := func(ctx context.Context, fullMethodName string) (context.Context, error) {
+		if calls.Add(1) > 1 {
+			return nil, status.Error(codes.Unauthenticated, "token expired")
+		}
+		return context.WithValue(ctx, claimsKey{}, &Claims{ExpiresAt: time.Now().Add(30 * time.Millisecond)}), nil
+	}
+	// 间隔为0时只在凭证过期时重新认证
+	interceptor := StreamServerInterceptor(authFunc, WithStreamReauth(0))
+	stream := &mockServerStream{ctx: tokenContext("valid")}
+
+	start := time.Now()
+	err := interceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: "/chat.Chat/Join"}, func(srv any, stream grpc.ServerStream) error {
+		<-stream.Context().Done()
+		return nil
+	})
+	assert.Equal(t, "token expired", status.Convert(err).Message())
+	assert.GreaterOrEqual(t, time.Since(start), 25*time.Millisecond)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestStreamReauth_ExpiredWithinLeeway(t *testing.T) {
+	info := &grpc.StreamServerInfo{FullMethod: "/chat.Chat/Join"}
+
+	t.Run("认证时已经过期", func(t *testing.T) {
+		var calls atomic.Int32
+		// 认证函数允许时钟偏差，已经过期的凭证仍然通过认证
+		interceptor := StreamServerInterceptor(func(ctx context.Context, fullMethodName string) (context.Context, error) {
+			calls.Add(1)
+			return context.WithValue(ctx, claimsKey{}, &Claims{ExpiresAt: time.Now().Add(-time.Second)}), nil
+		}, WithStreamReauth(0))
+		handled := false
+		err := interceptor(nil, &mockServerStream{ctx: tokenContext("valid")}, info, func(srv any, stream grpc.ServerStream) error {
+			handled = true
+			return nil
+		})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		assert.Equal(t, "credentials expired", status.Convert(err).Message())
+		assert.False(t, handled)
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("重新认证时已经过期", func(t *testing.T) {
+		var calls atomic.Int32
+		expiresAt := time.Now().Add(20 * time.Millisecond)
+		interceptor := StreamServerInterceptor(func(ctx context.Context, fullMethodName string) (context.Context, error) {
+			calls.Add(1)
+			return context.WithValue(ctx, claimsKey{}, &Claims{ExpiresAt: expiresAt}), nil
+		}, WithStreamReauth(0))
+		err := interceptor(nil, &mockServerStream{ctx: tokenContext("valid")}, info, func(srv any, stream grpc.ServerStream) error {
+			<-stream.Context().Done()
+			return nil
+		})
+		assert.Equal(t, "credentials expired", status.Convert(err).Message())
+		assert.LessOrEqual(t, calls.Load(), int32(3))
+	})
+}
+
+func TestStreamReauth_RefreshMessage(t *testing.T) {
+	var revoked atomic.Bool
+	var calls atomic.Int32
+	interceptor := StreamServerInterceptor(tokenAuthFunc(&revoked, &calls), WithStreamReauth(time.Hour))
+	info := &grpc.StreamServerInfo{FullMethod: "/chat.Chat/Join"}
+
+	t.Run("刷新后使用新凭证", func(t *testing.T) {
+		stream := &chanServerStream{mockServerStream: mockServerStream{ctx: tokenContext("valid")}, recv: make(chan string, 2)}
+		stream.recv <- ""
+		stream.recv <- "Bearer refreshed"
+		close(stream.recv)
+		var subjects []any
+		err := interceptor(nil, stream, info, func(srv any, stream grpc.ServerStream) error {
+			for {
+				if err := stream.RecvMsg(&refreshMessage{}); err != nil {
+					if err == io.EOF {
+						return nil
+					}
+					return err
+				}
+				subjects = append(subjects, stream.Context().Value(subjectKey{}))
+			}
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []any{"Bearer valid", "Bearer refreshed"}, subjects)
+	})
+
+	t.Run("刷新的凭证无效时结束流", func(t *testing.T) {
+		stream := &chanServerStream{mockServerStream: mockServerStream{ctx: tokenContext("valid")}, recv: make(chan string, 1)}
+		stream.recv <- "Bearer stolen"
+		authFunc := func(ctx context.Context, fullMethodName string) (context.Context, error) {
+			md, _ := metadata.FromIncomingContext(ctx)
+			if md.Get(headerAuthorize)[0] != "Bearer valid" {
+				return nil, status.Error(codes.PermissionDenied, "token revoked")
+			}
+			return ctx, nil
+		}
+		interceptor := StreamServerInterceptor(authFunc, WithStreamReauth(time.Hour))
+		var recvErr, sendErr, headerErr error
+		err := interceptor(nil, stream, info, func(srv any, stream grpc.ServerStream) error {
+			recvErr = stream.RecvMsg(&refreshMessage{})
+			sendErr = stream.SendMsg(nil)
+			headerErr = stream.SendHeader(metadata.MD{})
+			return recvErr
+		})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		assert.Equal(t, "re-authentication failed: token revoked", status.Convert(err).Message())
+		assert.Equal(t, err, recvErr)
+		assert.Equal(t, err, sendErr)
+		assert.Equal(t, err, headerErr)
+		assert.Zero(t, stream.sendCount)
+	})
+}
+
+func TestStreamReauth_WaitsForHandler(t *testing.T) {
+	var calls atomic.Int32
+	// 重新认证失败时取消上下文，等待处理函数返回后结束流
+	interceptor := StreamServerInterceptor(func(ctx context.Context, fullMethodName string) (context.Context, error) {
+		if calls.Add(1) > 1 {
+			return nil, status.Error(codes.Unauthenticated, "token revoked")
+		}
+		return ctx, nil
+	}, WithStreamReauth(10*time.Millisecond))
+	var returned atomic.Bool
+	err := interceptor(nil, &mockServerStream{ctx: tokenContext("valid")}, &grpc.StreamServerInfo{FullMethod: "/chat.Chat/Join"}, func(srv any, stream grpc.ServerStream) error {
+		defer returned.Store(true)
+		<-stream.Context().Done()
+		time.Sleep(10 * time.Millisecond)
+		return stream.Context().Err()
+	})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.True(t, returned.Load())
+}
+
+func TestStreamReauth_InitialFailureAndPanic(t *testing.T) {
+	info := &grpc.StreamServerInfo{FullMethod: "/chat.Chat/Join"}
+
+	// 首次认证失败时返回原始错误
+	interceptor := StreamServerInterceptor(func(ctx context.Context, fullMethodName string) (context.Context, error) {
+		return nil, status.Error(codes.PermissionDenied, "peer not allowed")
+	}, WithStreamReauth(time.Minute))
+	err := interceptor(nil, &mockServerStream{ctx: context.Background()}, info, func(srv any, stream grpc.ServerStream) error {
+		return nil
+	})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// 处理函数的panic在拦截器所在的协程中重新抛出
+	interceptor = StreamServerInterceptor(func(ctx context.Context, fullMethodName string) (context.Context, error) {
+		return ctx, nil
+	}, WithStreamReauth(time.Minute))
+	assert.PanicsWithValue(t, "boom", func() {
+		_ = interceptor(nil, &mockServerStream{ctx: context.Background()}, info, func(srv any, stream grpc.ServerStream) error {
+			panic("boom")
+		})
+	})
+}
@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Token 客户端携带的访问令牌
+type Token struct {
+	// AccessToken 令牌内容
+	AccessToken string
+	// Scheme 认证方案，为空时使用"Bearer"
+	Scheme string
+	// Expiry 过期时间，零值表示不过期
+	Expiry time.Time
+}
+
+// authorization 返回authorization头的值
+func (t *Token) authorization() string {
+	scheme := t.Scheme
+	if scheme == "" {
+		scheme = "Bearer"
+	}
+	return scheme + " " + t.AccessToken
+}
+
+// TokenSource 提供客户端访问令牌
+type TokenSource interface {
+	// Token 返回访问令牌
+	//
+	// 参数:
+	//   - ctx: 上下文
+	//
+	// 返回值:
+	//   - *Token: 访问令牌
+	//   - error: 获取失败时返回错误
+	Token(ctx context.Context) (*Token, error)
+}
+
+// TokenSourceFunc 是TokenSource接口的函数适配器
+type TokenSourceFunc func(ctx context.Context) (*Token, error)
+
+// Token 实现TokenSource接口
+func (f TokenSourceFunc) Token(ctx context.Context) (*Token, error) {
+	return f(ctx)
+}
+
+// StaticTokenSource 创建始终返回同一令牌的TokenSource
+//
+// 参数:
+//   - token: 令牌内容
+//
+// 返回值:
+//   - TokenSource: 令牌源
+func StaticTokenSource(token string) TokenSource {
+	t := &Token{AccessToken: token}
+	return TokenSourceFunc(func(context.Context) (*Token, error) {
+		return t, nil
+	})
+}
+
+// errNilToken 令牌源返回nil令牌时的错误
+var errNilToken = errors.New("auth: token source returned nil token")
+
+// defaultRefreshBefore 默认在过期前多久刷新令牌
+const defaultRefreshBefore = time.Minute
+
+// CachingTokenSource 缓存令牌的TokenSource
+// 令牌在过期前refreshBefore时间内仍然使用，同时在后台刷新；已过期时同步刷新。
+// 并发的刷新请求通过singleflight合并为一次
+type CachingTokenSource struct {
+	source        TokenSource
+	refreshBefore time.Duration
+	now           func() time.Time
+
+	mu    sync.Mutex
+	token *Token
+	group singleflight.Group
+}
+
+// NewCachingTokenSource 创建缓存令牌的TokenSource
+//
+// 参数:
+//   - source: 底层令牌源
+//   - refreshBefore: 在过期前多久开始后台刷新，小于等于0时使用默认值1分钟
+//
+// 返回值:
+//   - *CachingTokenSource: 缓存令牌源
+func NewCachingTokenSource(source TokenSource, refreshBefore time.Duration) *CachingTokenSource {
+	if refreshBefore <= 0 {
+		refreshBefore = defaultRefreshBefore
+	}
+	return &CachingTokenSource{source: source, refreshBefore: refreshBefore, now: time.Now}
+}
+
+// Token 实现TokenSource接口
+func (c *CachingTokenSource) Token(ctx context.Context) (*Token, error) {
+	c.mu.Lock()
+	token := c.token
+	c.mu.Unlock()
+
+	now := c.now()
+	switch {
+	case token == nil || (!token.Expiry.IsZero() && !now.Before(token.Expiry)):
+		// 没有令牌或已过期，同步刷新
+		return c.refresh(ctx)
+	case !token.Expiry.IsZero() && !now.Before(token.Expiry.Add(-c.refreshBefore)):
+		// 即将过期，后台刷新并继续使用当前令牌
+		c.group.DoChan("refresh", func() (any, error) {
+			return c.fetch(context.Background())
+		})
+	}
+	return token, nil
+}
+
+// Refresh 在当前缓存的令牌仍为stale时强制刷新
+// 用于服务端拒绝令牌后获取新令牌，若其他调用方已完成刷新则直接返回新令牌
+//
+// 参数:
+//   - ctx: 上下文
+//   - stale: 被拒绝的令牌
+//
+// 返回值:
+//   - *Token: 新令牌
+//   - error: 刷新失败时返回错误
+func (c *CachingTokenSource) Refresh(ctx context.Context, stale *Token) (*Token, error) {
+	c.mu.Lock()
+	token := c.token
+	c.mu.Unlock()
+	if token != nil && token != stale {
+		return token, nil
+	}
+	return c.refresh(ctx)
+}
+
+// refresh 通过singleflight刷新令牌，等待期间可被ctx取消
+func (c *CachingTokenSource) refresh(ctx context.Context) (*Token, error) {
+	// 共享的刷新不随单个调用方取消
+	ch := c.group.DoChan("refresh", func() (any, error) {
+		return c.fetch(context.WithoutCancel(ctx))
+	})
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*Token), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// fetch 从底层令牌源获取令牌并缓存
+func (c *CachingTokenSource) fetch(ctx context.Context) (*Token, error) {
+	token, err := c.source.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, errNilToken
+	}
+	c.mu.Lock()
+	c.token = token
+	c.mu.Unlock()
+	return token, nil
+}
@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingSource 按调用次数签发令牌的令牌源
+type countingSource struct {
+	calls  atomic.Int32
+	ttl    time.Duration
+	now    func() time.Time
+	block  chan struct{}
+	failAt int32
+}
+
+func (s *countingSource) Token(ctx context.Context) (*Token, error) {
+	n := s.calls.Add(1)
+	if s.block != nil {
+		<-s.block
+	}
+	if n == s.failAt {
+		return nil, errors.New("issuer unavailable")
+	}
+	return &Token{AccessToken: "token-" + strconv.Itoa(int(n)), Expiry: s.now().Add(s.ttl)}, nil
+}
+
+// testClock 可手动推进的时钟
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestCachingSource(source *countingSource, clock *testClock) *CachingTokenSource {
+	source.now = clock.Now
+	c := NewCachingTokenSource(source, time.Minute)
+	c.now = clock.Now
+	return c
+}
+
+func TestCachingTokenSource_Cache(t *testing.T) {
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	source := &countingSource{ttl: 10 * time.Minute}
+	c := newTestCachingSource(source, clock)
+	ctx := context.Background()
+
+	token, err := c.Token(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", token.AccessToken)
+
+	// 有效期内使用缓存
+	clock.Add(5 * time.Minute)
+	token, err = c.Token(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", token.AccessToken)
+	assert.Equal(t, int32(1), source.calls.Load())
+
+	// 已过期时同步刷新
+	clock.Add(10 * time.Minute)
+	token, err = c.Token(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "token-2", token.AccessToken)
+	assert.Equal(t, int32(2), source.calls.Load())
+}
+
+func TestCachingTokenSource_BackgroundRefresh(t *testing.T) {
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	source := &countingSource{ttl: 10 * time.Minute}
+	c := newTestCachingSource(source, clock)
+	ctx := context.Background()
+
+	_, err := c.Token(ctx)
+	require.NoError(t, err)
+
+	// 进入刷新窗口后继续返回当前令牌，同时在后台刷新
+	clock.Add(9*time.Minute + 30*time.Second)
+	token, err := c.Token(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", token.AccessToken)
+
+	assert.Eventually(t, func() bool {
+		token, err := c.Token(ctx)
+		return err == nil && token.AccessToken == "token-2"
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, int32(2), source.calls.Load())
+}
+
+func TestCachingTokenSource_Singleflight(t *testing.T) {
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	source := &countingSource{ttl: 10 * time.Minute, block: make(chan struct{})}
+	c := newTestCachingSource(source, clock)
+
+	const callers = 10
+	var wg sync.WaitGroup
+	tokens := make([]*Token, callers)
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tokens[i], _ = c.Token(context.Background())
+		}()
+	}
+	assert.Eventually(t, func() bool { return source.calls.Load() == 1 }, time.Second, 5*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	close(source.block)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), source.calls.Load(), "并发的调用方共享一次获取")
+	for _, token := range tokens {
+		require.NotNil(t, token)
+		assert.Equal(t, "token-1", token.AccessToken)
+	}
+}
+
+func TestCachingTokenSource_Refresh(t *testing.T) {
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	source := &countingSource{ttl: 10 * time.Minute}
+	c := newTestCachingSource(source, clock)
+	ctx := context.Background()
+
+	stale, err := c.Token(ctx)
+	require.NoError(t, err)
+
+	fresh, err := c.Refresh(ctx, stale)
+	require.NoError(t, err)
+	assert.Equal(t, "token-2", fresh.AccessToken)
+
+	// 其他调用方已完成刷新时直接返回新令牌
+	again, err := c.Refresh(ctx, stale)
+	require.NoError(t, err)
+	assert.Same(t, fresh, again)
+	assert.Equal(t, int32(2), source.calls.Load())
+}
+
+func TestCachingTokenSource_Errors(t *testing.T) {
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	t.Run("令牌源失败", func(t *testing.T) {
+		c := newTestCachingSource(&countingSource{ttl: time.Hour, failAt: 1}, clock)
+		_, err := c.Token(context.Background())
+		assert.ErrorContains(t, err, "issuer unavailable")
+	})
+
+	t.Run("令牌源返回nil", func(t *testing.T) {
+		c := NewCachingTokenSource(TokenSourceFunc(func(context.Context) (*Token, error) { return nil, nil }), 0)
+		_, err := c.Token(context.Background())
+		assert.ErrorIs(t, err, errNilToken)
+	})
+
+	t.Run("等待时上下文取消", func(t *testing.T) {
+		source := &countingSource{ttl: time.Hour, block: make(chan struct{})}
+		defer close(source.block)
+		c := newTestCachingSource(source, clock)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := c.Token(ctx)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestToken_Authorization(t *testing.T) {
+	assert.Equal(t, "Bearer abc", (&Token{AccessToken: "abc"}).authorization())
+	assert.Equal(t, "Basic abc", (&Token{AccessToken: "abc", Scheme: "Basic"}).authorization())
+}
@@ -0,0 +1,64 @@
+package authz
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// UnaryServerInterceptor 创建按策略授权的一元服务器拦截器
+// 应放在认证拦截器之后，拒绝时返回带ErrorInfo详情的PermissionDenied错误
+//
+// 参数:
+//   - policy: 授权策略，为nil时拒绝所有请求
+//   - opts: 可选的配置选项
+//
+// 返回值:
+//   - grpc.UnaryServerInterceptor: gRPC一元服务器拦截器
+func UnaryServerInterceptor(policy *Policy, opts ...Option) grpc.UnaryServerInterceptor {
+	o := defaultOptions().apply(opts...)
+	policy = orDenyAll(policy)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := authorize(ctx, policy, info.FullMethod, o); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor 创建按策略授权的流式服务器拦截器
+// 应放在认证拦截器之后，拒绝时返回带ErrorInfo详情的PermissionDenied错误
+//
+// 参数:
+//   - policy: 授权策略，为nil时拒绝所有请求
+//   - opts: 可选的配置选项
+//
+// 返回值:
+//   - grpc.StreamServerInterceptor: gRPC流式服务器拦截器
+func StreamServerInterceptor(policy *Policy, opts ...Option) grpc.StreamServerInterceptor {
+	o := defaultOptions().apply(opts...)
+	policy = orDenyAll(policy)
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := authorize(stream.Context(), policy, info.FullMethod, o); err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
+
+// authorize 获取调用方身份并按策略判断
+func authorize(ctx context.Context, policy *Policy, fullMethodName string, o *options) error {
+	principal, ok := o.principalFunc(ctx)
+	if !ok {
+		principal = nil
+	}
+	return policy.Authorize(principal, fullMethodName)
+}
+
+// orDenyAll 策略为nil时返回没有规则、拒绝所有请求的空策略
+func orDenyAll(policy *Policy) *Policy {
+	if policy == nil {
+		return &Policy{}
+	}
+	return policy
+}
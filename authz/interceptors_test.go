@@ -0,0 +1,82 @@
+package authz
+
+import (
+	"context"
+	"testing"
+
+	"github.com/soyacen/grpc-middleware/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// mockServerStream 用于测试的服务端流
+type mockServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (m *mockServerStream) Context() context.Context {
+	return m.ctx
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	store, err := auth.NewMemoryAPIKeyStore(auth.APIKey{Hash: auth.HashAPIKey("reader"), Owner: "reports", Scopes: []string{"orders:read"}})
+	require.NoError(t, err)
+	authn := auth.UnaryServerInterceptor(auth.NewAPIKeyAuthFunc(store))
+	authz := UnaryServerInterceptor(&Policy{Rules: []Rule{
+		{Methods: []string{"/orders.Orders/Get"}, Scopes: []string{"orders:read"}},
+		{Methods: []string{"/orders.Orders/Delete"}, Scopes: []string{"orders:delete"}},
+	}})
+	chain := func(method string) error {
+		info := &grpc.UnaryServerInfo{FullMethod: method}
+		_, err := authn(apiKeyContext("reader"), nil, info, func(ctx context.Context, req any) (any, error) {
+			return authz(ctx, req, info, func(ctx context.Context, req any) (any, error) {
+				return "ok", nil
+			})
+		})
+		return err
+	}
+
+	assert.NoError(t, chain("/orders.Orders/Get"))
+	assert.Equal(t, codes.PermissionDenied, status.Code(chain("/orders.Orders/Delete")))
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	interceptor := StreamServerInterceptor(
+		&Policy{Rules: []Rule{{Methods: []string{"/chat.Chat/*"}, Roles: []string{"member"}}}},
+		WithPrincipalFunc(func(ctx context.Context) (*Principal, bool) {
+			return &Principal{Subject: "custom", Roles: []string{"member"}}, true
+		}),
+	)
+
+	called := false
+	handler := func(srv any, stream grpc.ServerStream) error {
+		called = true
+		return nil
+	}
+	stream := &mockServerStream{ctx: context.Background()}
+	require.NoError(t, interceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: "/chat.Chat/Join"}, handler))
+	assert.True(t, called)
+
+	called = false
+	err := interceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: "/admin.Admin/Join"}, handler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.False(t, called)
+}
+
+func TestInterceptors_NilPolicyDeniesAll(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/orders.Orders/Get"}
+	_, err := UnaryServerInterceptor(nil)(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) {
+		return "ok", nil
+	})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	stream := &mockServerStream{ctx: context.Background()}
+	err = StreamServerInterceptor(nil)(nil, stream, &grpc.StreamServerInfo{FullMethod: "/chat.Chat/Join"}, func(srv any, stream grpc.ServerStream) error {
+		return nil
+	})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
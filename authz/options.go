@@ -0,0 +1,49 @@
+// Package authz 提供基于声明式策略的gRPC授权中间件
+// 授权在认证之后执行，根据认证阶段写入上下文的调用方身份判断是否允许调用方法
+package authz
+
+// options 存储授权拦截器的配置选项
+type options struct {
+	// principalFunc 获取调用方身份的函数
+	principalFunc PrincipalFunc
+}
+
+// apply 将给定的选项应用到选项结构体中
+//
+// 参数:
+//   - opts: 可变数量的选项函数
+//
+// 返回值:
+//   - *options: 指向更新后的选项结构体的指针
+func (o *options) apply(opts ...Option) *options {
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Option 定义用于配置授权拦截器选项的函数类型
+type Option func(o *options)
+
+// defaultOptions 返回默认的配置选项
+//
+// 返回值:
+//   - *options: 包含默认选项的结构体指针
+func defaultOptions() *options {
+	return &options{principalFunc: DefaultPrincipal}
+}
+
+// WithPrincipalFunc 设置获取调用方身份的函数，默认为DefaultPrincipal
+//
+// 参数:
+//   - f: 获取调用方身份的函数
+//
+// 返回值:
+//   - Option: 设置身份获取函数选项的函数
+func WithPrincipalFunc(f PrincipalFunc) Option {
+	return func(o *options) {
+		if f != nil {
+			o.principalFunc = f
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package authz
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithPrincipalFunc(t *testing.T) {
+	custom := &Principal{Subject: "custom"}
+	o := defaultOptions().apply(WithPrincipalFunc(func(context.Context) (*Principal, bool) { return custom, true }))
+	got, ok := o.principalFunc(context.Background())
+	assert.True(t, ok)
+	assert.Same(t, custom, got)
+
+	// nil不覆盖默认值
+	o = defaultOptions().apply(WithPrincipalFunc(nil))
+	assert.NotNil(t, o.principalFunc)
+}
@@ -0,0 +1,241 @@
+package authz
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrorDomain 拒绝请求时ErrorInfo中的错误域
+const ErrorDomain = "authz.grpc-middleware"
+
+// 拒绝请求时ErrorInfo中的原因
+const (
+	// ReasonNoMatchingRule 没有与方法匹配的规则
+	ReasonNoMatchingRule = "NO_MATCHING_RULE"
+	// ReasonMissingPrincipal 上下文中没有调用方身份
+	ReasonMissingPrincipal = "MISSING_PRINCIPAL"
+	// ReasonInsufficientPermissions 调用方不满足规则要求的角色或权限范围
+	ReasonInsufficientPermissions = "INSUFFICIENT_PERMISSIONS"
+)
+
+// Match 规则中角色和权限范围的组合方式
+type Match string
+
+const (
+	// MatchAny 拥有任意一个要求的角色或权限范围即可，为默认值
+	MatchAny Match = "any"
+	// MatchAll 需要拥有全部要求的角色和权限范围
+	MatchAll Match = "all"
+)
+
+// Rule 授权规则
+type Rule struct {
+	// Methods 规则适用的方法模式，按path.Match匹配完整的方法名，例如"/orders.Orders/*"
+	Methods []string `json:"methods" yaml:"methods"`
+	// Roles 要求的角色
+	Roles []string `json:"roles,omitempty" yaml:"roles,omitempty"`
+	// Scopes 要求的权限范围
+	Scopes []string `json:"scopes,omitempty" yaml:"scopes,omitempty"`
+	// Match 角色和权限范围的组合方式，为空时为MatchAny
+	Match Match `json:"match,omitempty" yaml:"match,omitempty"`
+	// Public 为true时不要求调用方身份
+	Public bool `json:"public,omitempty" yaml:"public,omitempty"`
+}
+
+// matches 判断规则是否适用于方法
+func (r *Rule) matches(fullMethodName string) bool {
+	for _, pattern := range r.Methods {
+		if ok, _ := path.Match(pattern, fullMethodName); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// allows 判断调用方是否满足规则要求，没有要求时允许任何调用方
+func (r *Rule) allows(principal *Principal) bool {
+	if len(r.Roles) == 0 && len(r.Scopes) == 0 {
+		return true
+	}
+	if r.Match == MatchAll {
+		for _, role := range r.Roles {
+			if !principal.HasRole(role) {
+				return false
+			}
+		}
+		for _, scope := range r.Scopes {
+			if !principal.HasScope(scope) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, role := range r.Roles {
+		if principal.HasRole(role) {
+			return true
+		}
+	}
+	for _, scope := range r.Scopes {
+		if principal.HasScope(scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// Policy 授权策略
+// 规则按顺序取第一条与方法匹配的规则，没有匹配的规则时拒绝请求
+type Policy struct {
+	// Rules 授权规则
+	Rules []Rule `json:"rules" yaml:"rules"`
+}
+
+// Validate 校验策略
+//
+// 返回值:
+//   - error: 规则没有方法、方法模式错误或组合方式未知时返回错误
+func (p *Policy) Validate() error {
+	for i, rule := range p.Rules {
+		if len(rule.Methods) == 0 {
+			return fmt.Errorf("authz: rule %d: no methods", i)
+		}
+		for _, pattern := range rule.Methods {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("authz: rule %d: bad method pattern %q", i, pattern)
+			}
+		}
+		switch rule.Match {
+		case "", MatchAny, MatchAll:
+		default:
+			return fmt.Errorf("authz: rule %d: unknown match %q", i, rule.Match)
+		}
+	}
+	return nil
+}
+
+// Authorize 按策略判断调用方是否可以调用方法
+//
+// 参数:
+//   - principal: 调用方身份，没有时为nil
+//   - fullMethodName: 完整的方法名
+//
+// 返回值:
+//   - error: 拒绝时返回带ErrorInfo详情的PermissionDenied错误
+func (p *Policy) Authorize(principal *Principal, fullMethodName string) error {
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if !rule.matches(fullMethodName) {
+			continue
+		}
+		if rule.Public {
+			return nil
+		}
+		if principal == nil {
+			return denied(ReasonMissingPrincipal, "no authenticated principal", fullMethodName, nil)
+		}
+		if rule.allows(principal) {
+			return nil
+		}
+		match := rule.Match
+		if match == "" {
+			match = MatchAny
+		}
+		metadata := map[string]string{"match": string(match)}
+		if len(rule.Roles) > 0 {
+			metadata["roles"] = strings.Join(rule.Roles, ",")
+		}
+		if len(rule.Scopes) > 0 {
+			metadata["scopes"] = strings.Join(rule.Scopes, ",")
+		}
+		return denied(ReasonInsufficientPermissions, "insufficient permissions", fullMethodName, metadata)
+	}
+	return denied(ReasonNoMatchingRule, "no policy rule matches method", fullMethodName, nil)
+}
+
+// denied 创建带ErrorInfo详情的PermissionDenied错误
+func denied(reason, message, fullMethodName string, metadata map[string]string) error {
+	if metadata == nil {
+		metadata = make(map[string]string, 1)
+	}
+	metadata["method"] = fullMethodName
+	st := status.New(codes.PermissionDenied, message)
+	if detailed, err := st.WithDetails(&errdetails.ErrorInfo{Reason: reason, Domain: ErrorDomain, Metadata: metadata}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// ParsePolicyJSON 解析JSON格式的策略
+//
+// 参数:
+//   - data: JSON文档
+//
+// 返回值:
+//   - *Policy: 授权策略
+//   - error: 解析或校验失败时返回错误
+func ParsePolicyJSON(data []byte) (*Policy, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var policy Policy
+	if err := dec.Decode(&policy); err != nil {
+		return nil, fmt.Errorf("authz: parse policy: %w", err)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// ParsePolicyYAML 解析YAML格式的策略
+//
+// 参数:
+//   - data: YAML文档
+//
+// 返回值:
+//   - *Policy: 授权策略
+//   - error: 解析或校验失败时返回错误
+func ParsePolicyYAML(data []byte) (*Policy, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	var policy Policy
+	if err := dec.Decode(&policy); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("authz: parse policy: %w", err)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// LoadPolicyFile 从文件加载策略，扩展名为.yaml或.yml时按YAML解析，否则按JSON解析
+//
+// 参数:
+//   - filename: 策略文件路径
+//
+// 返回值:
+//   - *Policy: 授权策略
+//   - error: 读取、解析或校验失败时返回错误
+func LoadPolicyFile(filename string) (*Policy, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		return ParsePolicyYAML(data)
+	default:
+		return ParsePolicyJSON(data)
+	}
+}
@@ -0,0 +1,133 @@
+package authz
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorInfo 返回错误中的ErrorInfo详情
+func errorInfo(t *testing.T, err error) *errdetails.ErrorInfo {
+	t.Helper()
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info
+		}
+	}
+	t.Fatal("missing ErrorInfo detail")
+	return nil
+}
+
+func TestPolicy_Authorize(t *testing.T) {
+	policy := &Policy{Rules: []Rule{
+		{Methods: []string{"/grpc.health.v1.Health/*"}, Public: true},
+		{Methods: []string{"/orders.Orders/Delete*"}, Roles: []string{"admin"}, Scopes: []string{"orders:delete"}, Match: MatchAll},
+		{Methods: []string{"/orders.Orders/*"}, Roles: []string{"admin"}, Scopes: []string{"orders:read"}},
+		{Methods: []string{"/profile.Profile/Get"}},
+	}}
+	reader := &Principal{Subject: "alice", Scopes: []string{"orders:read"}}
+	admin := &Principal{Subject: "bob", Roles: []string{"admin"}}
+	superAdmin := &Principal{Subject: "carol", Roles: []string{"admin"}, Scopes: []string{"orders:delete"}}
+
+	tests := []struct {
+		name       string
+		principal  *Principal
+		method     string
+		wantReason string
+	}{
+		{"公开方法不要求身份", nil, "/grpc.health.v1.Health/Check", ""},
+		{"any拥有权限范围", reader, "/orders.Orders/Get", ""},
+		{"any拥有角色", admin, "/orders.Orders/Get", ""},
+		{"all全部满足", superAdmin, "/orders.Orders/DeleteOrder", ""},
+		{"all缺少权限范围", admin, "/orders.Orders/DeleteOrder", ReasonInsufficientPermissions},
+		{"any都不满足", &Principal{Subject: "dave"}, "/orders.Orders/Get", ReasonInsufficientPermissions},
+		{"没有要求时允许任何身份", &Principal{Subject: "dave"}, "/profile.Profile/Get", ""},
+		{"没有身份", nil, "/profile.Profile/Get", ReasonMissingPrincipal},
+		{"没有匹配的规则", superAdmin, "/admin.Admin/Reset", ReasonNoMatchingRule},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Authorize(tt.principal, tt.method)
+			if tt.wantReason == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Equal(t, codes.PermissionDenied, status.Code(err))
+			info := errorInfo(t, err)
+			assert.Equal(t, tt.wantReason, info.Reason)
+			assert.Equal(t, ErrorDomain, info.Domain)
+			assert.Equal(t, tt.method, info.Metadata["method"])
+		})
+	}
+}
+
+func TestPolicy_Authorize_Metadata(t *testing.T) {
+	policy := &Policy{Rules: []Rule{{Methods: []string{"/a.A/*"}, Roles: []string{"admin", "ops"}, Scopes: []string{"a:write"}}}}
+	info := errorInfo(t, policy.Authorize(&Principal{}, "/a.A/Put"))
+	assert.Equal(t, map[string]string{"method": "/a.A/Put", "match": "any", "roles": "admin,ops", "scopes": "a:write"}, info.Metadata)
+}
+
+func TestParsePolicy(t *testing.T) {
+	const jsonPolicy = `{"rules":[
+		{"methods":["/grpc.health.v1.Health/*"],"public":true},
+		{"methods":["/orders.Orders/*"],"roles":["admin"],"scopes":["orders:write"],"match":"all"}
+	]}`
+	const yamlPolicy = `
+rules:
+  - methods: ["/grpc.health.v1.Health/*"]
+    public: true
+  - methods:
+      - /orders.Orders/*
+    roles: [admin]
+    scopes: [orders:write]
+    match: all
+`
+	want := &Policy{Rules: []Rule{
+		{Methods: []string{"/grpc.health.v1.Health/*"}, Public: true},
+		{Methods: []string{"/orders.Orders/*"}, Roles: []string{"admin"}, Scopes: []string{"orders:write"}, Match: MatchAll},
+	}}
+
+	dir := t.TempDir()
+	files := map[string]string{"policy.json": jsonPolicy, "policy.yaml": yamlPolicy, "policy.yml": yamlPolicy}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			filename := filepath.Join(dir, name)
+			require.NoError(t, os.WriteFile(filename, []byte(content), 0o644))
+			policy, err := LoadPolicyFile(filename)
+			require.NoError(t, err)
+			assert.Equal(t, want, policy)
+		})
+	}
+}
+
+func TestParsePolicy_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		parse func([]byte) (*Policy, error)
+		doc   string
+	}{
+		{"JSON格式错误", ParsePolicyJSON, `{"rules":`},
+		{"JSON未知字段", ParsePolicyJSON, `{"rules":[{"method":["/a.A/*"]}]}`},
+		{"YAML未知字段", ParsePolicyYAML, "rules:\n  - method: [/a.A/*]\n"},
+		{"规则没有方法", ParsePolicyJSON, `{"rules":[{"roles":["admin"]}]}`},
+		{"方法模式错误", ParsePolicyYAML, "rules:\n  - methods: ['/a.A/[']\n"},
+		{"未知的组合方式", ParsePolicyJSON, `{"rules":[{"methods":["/a.A/*"],"match":"some"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.parse([]byte(tt.doc))
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := LoadPolicyFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,135 @@
+package authz
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/soyacen/grpc-middleware/auth"
+)
+
+// Principal 授权使用的调用方身份
+type Principal struct {
+	// Subject 调用方标识
+	Subject string
+	// Roles 调用方拥有的角色
+	Roles []string
+	// Scopes 调用方拥有的权限范围
+	Scopes []string
+}
+
+// HasRole 判断调用方是否拥有指定角色
+//
+// 参数:
+//   - role: 角色
+//
+// 返回值:
+//   - bool: 是否拥有该角色
+func (p *Principal) HasRole(role string) bool {
+	return slices.Contains(p.Roles, role)
+}
+
+// HasScope 判断调用方是否拥有指定权限范围
+//
+// 参数:
+//   - scope: 权限范围
+//
+// 返回值:
+//   - bool: 是否拥有该权限范围
+func (p *Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+// PrincipalFunc 从请求上下文中获取调用方身份
+//
+// 参数:
+//   - ctx: 请求上下文
+//
+// 返回值:
+//   - *Principal: 调用方身份
+//   - bool: 是否有调用方身份
+type PrincipalFunc func(ctx context.Context) (*Principal, bool)
+
+// principalKey 调用方身份在上下文中的键
+type principalKey struct{}
+
+// ContextWithPrincipal 将调用方身份写入上下文
+// 自定义的认证函数可以用它直接提供授权使用的身份
+//
+// 参数:
+//   - ctx: 请求上下文
+//   - principal: 调用方身份
+//
+// 返回值:
+//   - context.Context: 包含调用方身份的上下文
+func ContextWithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// DefaultPrincipal 默认的PrincipalFunc，按以下顺序获取调用方身份:
+//   - ContextWithPrincipal写入的身份
+//   - JWT声明: sub为标识，roles为角色，scope(空格分隔)或scp为权限范围
+//   - 令牌内省结果: sub为标识，没有时使用client_id，scope为权限范围
+//   - API Key: Owner为标识，Scopes为权限范围
+//   - mTLS客户端身份: SPIFFE ID为标识，没有时使用证书CN
+//
+// 参数:
+//   - ctx: 请求上下文
+//
+// 返回值:
+//   - *Principal: 调用方身份
+//   - bool: 是否有调用方身份
+func DefaultPrincipal(ctx context.Context) (*Principal, bool) {
+	if principal, ok := ctx.Value(principalKey{}).(*Principal); ok && principal != nil {
+		return principal, true
+	}
+	if claims, ok := auth.ClaimsFromContext(ctx); ok {
+		return &Principal{
+			Subject: claims.Subject,
+			Roles:   claimStrings(claims.Raw["roles"], false),
+			Scopes:  append(claimStrings(claims.Raw["scope"], true), claimStrings(claims.Raw["scp"], true)...),
+		}, true
+	}
+	if info, ok := auth.IntrospectionFromContext(ctx); ok {
+		subject := info.Subject
+		if subject == "" {
+			subject = info.ClientID
+		}
+		return &Principal{Subject: subject, Scopes: info.Scopes}, true
+	}
+	if key, ok := auth.APIKeyFromContext(ctx); ok {
+		return &Principal{Subject: key.Owner, Scopes: key.Scopes}, true
+	}
+	if id, ok := auth.PeerIdentityFromContext(ctx); ok {
+		subject := id.SPIFFEID
+		if subject == "" {
+			subject = id.CommonName
+		}
+		return &Principal{Subject: subject}, true
+	}
+	return nil, false
+}
+
+// claimStrings 将字符串或字符串数组形式的声明转换为字符串切片
+// split为true时字符串按空格分隔
+func claimStrings(value any, split bool) []string {
+	switch v := value.(type) {
+	case string:
+		if split {
+			return strings.Fields(v)
+		}
+		return []string{v}
+	case []string:
+		return v
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
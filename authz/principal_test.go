@@ -0,0 +1,115 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/soyacen/grpc-middleware/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+// bearerContext 构造携带HS256令牌的请求上下文
+func bearerContext(t *testing.T, key []byte, claimsJSON string) context.Context {
+	t.Helper()
+	claims := jwt.MapClaims{}
+	require.NoError(t, json.Unmarshal([]byte(claimsJSON), &claims))
+	claims["exp"] = time.Now().Add(time.Hour).Unix()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(key)
+	require.NoError(t, err)
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+}
+
+// apiKeyContext 构造携带API Key的请求上下文
+func apiKeyContext(key string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", key))
+}
+
+func TestDefaultPrincipal(t *testing.T) {
+	explicit := &Principal{Subject: "explicit", Roles: []string{"admin"}}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want *Principal
+	}{
+		{"没有身份", context.Background(), nil},
+		{"显式写入的身份", ContextWithPrincipal(context.Background(), explicit), explicit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := DefaultPrincipal(tt.ctx)
+			assert.Equal(t, tt.want != nil, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDefaultPrincipal_JWT(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	authFunc := auth.NewJWTAuthFunc(auth.StaticKeys{"k": key})
+
+	tests := []struct {
+		name       string
+		claims     string
+		wantRoles  []string
+		wantScopes []string
+	}{
+		{"scope按空格分隔", `{"sub":"alice","roles":["admin","dev"],"scope":"orders:read orders:write"}`, []string{"admin", "dev"}, []string{"orders:read", "orders:write"}},
+		{"scp数组", `{"sub":"alice","roles":"admin","scp":["orders:read"]}`, []string{"admin"}, []string{"orders:read"}},
+		{"没有角色和权限范围", `{"sub":"alice"}`, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, err := authFunc(bearerContext(t, key, tt.claims), "/test/method")
+			require.NoError(t, err)
+			principal, ok := DefaultPrincipal(ctx)
+			require.True(t, ok)
+			assert.Equal(t, "alice", principal.Subject)
+			assert.Equal(t, tt.wantRoles, principal.Roles)
+			assert.ElementsMatch(t, tt.wantScopes, principal.Scopes)
+		})
+	}
+}
+
+func TestDefaultPrincipal_APIKey(t *testing.T) {
+	store, err := auth.NewMemoryAPIKeyStore(auth.APIKey{Hash: auth.HashAPIKey("secret"), Owner: "billing", Scopes: []string{"invoices:read"}})
+	require.NoError(t, err)
+	ctx, err := auth.NewAPIKeyAuthFunc(store)(apiKeyContext("secret"), "/test/method")
+	require.NoError(t, err)
+
+	principal, ok := DefaultPrincipal(ctx)
+	require.True(t, ok)
+	assert.Equal(t, &Principal{Subject: "billing", Scopes: []string{"invoices:read"}}, principal)
+}
+
+func TestDefaultPrincipal_Introspection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"active":true,"client_id":"reporting","scope":"reports:read reports:export"}`))
+	}))
+	defer server.Close()
+	ctx, err := auth.NewIntrospectionAuthFunc(server.URL, "gateway", "secret")(
+		metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer opaque")), "/test/method")
+	require.NoError(t, err)
+
+	// 没有sub时使用client_id
+	principal, ok := DefaultPrincipal(ctx)
+	require.True(t, ok)
+	assert.Equal(t, &Principal{Subject: "reporting", Scopes: []string{"reports:read", "reports:export"}}, principal)
+}
+
+func TestPrincipal_Has(t *testing.T) {
+	p := &Principal{Roles: []string{"admin"}, Scopes: []string{"read"}}
+	assert.True(t, p.HasRole("admin"))
+	assert.False(t, p.HasRole("read"))
+	assert.True(t, p.HasScope("read"))
+	assert.False(t, p.HasScope("admin"))
+}
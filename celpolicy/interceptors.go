@@ -0,0 +1,77 @@
+package celpolicy
+
+import (
+	"context"
+
+	"github.com/soyacen/grpc-middleware/authz"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 拒绝请求时ErrorInfo中的原因，错误域为authz.ErrorDomain
+const (
+	// ReasonPolicyDenied 表达式结果为false
+	ReasonPolicyDenied = "CEL_POLICY_DENIED"
+	// ReasonEvaluationError 表达式求值失败
+	ReasonEvaluationError = "CEL_EVALUATION_ERROR"
+)
+
+// UnaryServerInterceptor 创建按CEL表达式授权的一元服务器拦截器
+// 表达式结果为true时放行，为false或求值失败时返回带ErrorInfo详情的PermissionDenied错误。
+// 应放在认证拦截器之后，以便表达式使用auth变量
+//
+// 参数:
+//   - program: 编译后的表达式
+//
+// 返回值:
+//   - grpc.UnaryServerInterceptor: gRPC一元服务器拦截器
+func UnaryServerInterceptor(program *Program) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := authorize(ctx, program, Input{FullMethod: info.FullMethod, Request: req}); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor 创建按CEL表达式授权的流式服务器拦截器
+// 授权在流建立时执行，request变量为null
+//
+// 参数:
+//   - program: 编译后的表达式
+//
+// 返回值:
+//   - grpc.StreamServerInterceptor: gRPC流式服务器拦截器
+func StreamServerInterceptor(program *Program) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := authorize(stream.Context(), program, Input{FullMethod: info.FullMethod}); err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
+
+// authorize 对请求求值，拒绝时返回PermissionDenied错误
+func authorize(ctx context.Context, program *Program, in Input) error {
+	ok, err := program.Eval(ctx, in)
+	switch {
+	case err != nil:
+		return denied(ReasonEvaluationError, "policy evaluation failed", in.FullMethod)
+	case !ok:
+		return denied(ReasonPolicyDenied, "denied by policy", in.FullMethod)
+	default:
+		return nil
+	}
+}
+
+// denied 创建带ErrorInfo详情的PermissionDenied错误
+func denied(reason, message, fullMethodName string) error {
+	st := status.New(codes.PermissionDenied, message)
+	info := &errdetails.ErrorInfo{Reason: reason, Domain: authz.ErrorDomain, Metadata: map[string]string{"method": fullMethodName}}
+	if detailed, err := st.WithDetails(info); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
@@ -0,0 +1,102 @@
+package celpolicy
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/soyacen/grpc-middleware/auth"
+	"github.com/soyacen/grpc-middleware/authz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// mockServerStream 用于测试的服务端流
+type mockServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (m *mockServerStream) Context() context.Context {
+	return m.ctx
+}
+
+// jwtContext 构造经过JWT认证的请求上下文
+func jwtContext(t *testing.T, claimsJSON string) context.Context {
+	t.Helper()
+	key := []byte("0123456789abcdef0123456789abcdef")
+	claims := jwt.MapClaims{}
+	require.NoError(t, json.Unmarshal([]byte(claimsJSON), &claims))
+	claims["exp"] = time.Now().Add(time.Hour).Unix()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(key)
+	require.NoError(t, err)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+	ctx, err = auth.NewJWTAuthFunc(auth.StaticKeys{"k": key})(ctx, "")
+	require.NoError(t, err)
+	return ctx
+}
+
+// errorReason 返回错误中ErrorInfo的原因
+func errorReason(t *testing.T, err error) string {
+	t.Helper()
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			assert.Equal(t, authz.ErrorDomain, info.Domain)
+			return info.Reason
+		}
+	}
+	t.Fatal("missing ErrorInfo detail")
+	return ""
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := UnaryServerInterceptor(MustCompile(`request.service == auth.claims.tenant && method.startsWith("/grpc.health.")`))
+	info := &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return &grpc_health_v1.HealthCheckResponse{}, nil
+	}
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		service    string
+		wantReason string
+	}{
+		{"租户匹配", jwtContext(t, `{"sub":"alice","tenant":"acme"}`), "acme", ""},
+		{"租户不匹配", jwtContext(t, `{"sub":"alice","tenant":"acme"}`), "other", ReasonPolicyDenied},
+		{"缺少租户声明", jwtContext(t, `{"sub":"alice"}`), "acme", ReasonEvaluationError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := interceptor(tt.ctx, &grpc_health_v1.HealthCheckRequest{Service: tt.service}, info, handler)
+			if tt.wantReason == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Equal(t, codes.PermissionDenied, status.Code(err))
+			assert.Equal(t, tt.wantReason, errorReason(t, err))
+		})
+	}
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	interceptor := StreamServerInterceptor(MustCompile(`request == null && "admin" in auth.roles`))
+	handler := func(srv any, stream grpc.ServerStream) error { return nil }
+	info := &grpc.StreamServerInfo{FullMethod: "/grpc.health.v1.Health/Watch"}
+
+	admin := jwtContext(t, `{"sub":"alice","roles":["admin"]}`)
+	require.NoError(t, interceptor(nil, &mockServerStream{ctx: admin}, info, handler))
+
+	err := interceptor(nil, &mockServerStream{ctx: context.Background()}, info, handler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Equal(t, ReasonPolicyDenied, errorReason(t, err))
+}
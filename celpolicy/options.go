@@ -0,0 +1,81 @@
+// Package celpolicy 提供基于CEL表达式的gRPC策略
+// 表达式在启动时编译一次，可作为授权拦截器使用，也可作为ratelimiter和accesslog的跳过函数。
+//
+// 表达式中可以使用以下变量:
+//   - method: 完整的方法名，例如"/billing.Billing/Charge"
+//   - metadata: 请求元数据，map(string, string)，键为小写，多个值以","连接
+//   - peer: 对端信息，map(string, string)，包含address、auth_type、spiffe_id、common_name
+//   - auth: 认证信息，包含subject、roles、scopes和JWT声明claims
+//   - request: 一元调用的请求消息，流式调用时为null
+//   - code: 调用结果的状态码名称，例如"OK"、"NotFound"，只在accesslog跳过函数中有意义
+//
+// 例如:
+//
+//	request.tenant_id == auth.claims.tenant && method.startsWith("/billing.")
+package celpolicy
+
+import (
+	"github.com/google/cel-go/cel"
+	"github.com/soyacen/grpc-middleware/authz"
+)
+
+// options 存储CEL策略的配置选项
+type options struct {
+	// principalFunc 获取调用方身份的函数
+	principalFunc authz.PrincipalFunc
+	// envOptions 额外的CEL环境选项
+	envOptions []cel.EnvOption
+}
+
+// apply 将给定的选项应用到选项结构体中
+//
+// 参数:
+//   - opts: 可变数量的选项函数
+//
+// 返回值:
+//   - *options: 指向更新后的选项结构体的指针
+func (o *options) apply(opts ...Option) *options {
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Option 定义用于配置CEL策略选项的函数类型
+type Option func(o *options)
+
+// defaultOptions 返回默认的配置选项
+//
+// 返回值:
+//   - *options: 包含默认选项的结构体指针
+func defaultOptions() *options {
+	return &options{principalFunc: authz.DefaultPrincipal}
+}
+
+// WithPrincipalFunc 设置获取auth变量中调用方身份的函数，默认为authz.DefaultPrincipal
+//
+// 参数:
+//   - f: 获取调用方身份的函数
+//
+// 返回值:
+//   - Option: 设置身份获取函数选项的函数
+func WithPrincipalFunc(f authz.PrincipalFunc) Option {
+	return func(o *options) {
+		if f != nil {
+			o.principalFunc = f
+		}
+	}
+}
+
+// WithEnvOptions 添加额外的CEL环境选项，例如自定义函数或变量
+//
+// 参数:
+//   - opts: CEL环境选项
+//
+// 返回值:
+//   - Option: 设置环境选项的函数
+func WithEnvOptions(opts ...cel.EnvOption) Option {
+	return func(o *options) {
+		o.envOptions = append(o.envOptions, opts...)
+	}
+}
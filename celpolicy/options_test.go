@@ -0,0 +1,37 @@
+package celpolicy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/cel-go/ext"
+	"github.com/soyacen/grpc-middleware/authz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestWithPrincipalFunc(t *testing.T) {
+	program := MustCompile(`auth.subject == "custom"`, WithPrincipalFunc(func(context.Context) (*authz.Principal, bool) {
+		return &authz.Principal{Subject: "custom"}, true
+	}))
+	got, err := program.Eval(context.Background(), Input{})
+	require.NoError(t, err)
+	assert.True(t, got)
+
+	// nil不覆盖默认值
+	assert.NotNil(t, defaultOptions().apply(WithPrincipalFunc(nil)).principalFunc)
+}
+
+func TestWithEnvOptions(t *testing.T) {
+	const expr = `metadata["x-tenant"].upperAscii() == "ACME"`
+	_, err := Compile(expr)
+	assert.Error(t, err)
+
+	program, err := Compile(expr, WithEnvOptions(ext.Strings()))
+	require.NoError(t, err)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-tenant", "acme"))
+	got, err := program.Eval(ctx, Input{})
+	require.NoError(t, err)
+	assert.True(t, got)
+}
@@ -0,0 +1,215 @@
+package celpolicy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/soyacen/grpc-middleware/auth"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// Input 表达式求值的输入
+type Input struct {
+	// FullMethod 完整的方法名
+	FullMethod string
+	// Request 请求消息，没有时为nil
+	Request any
+	// Err 调用结果，只在调用结束后求值时设置
+	Err error
+}
+
+// Program 编译后的CEL表达式，可并发使用
+type Program struct {
+	expr    string
+	program cel.Program
+	opts    *options
+}
+
+// Compile 编译CEL表达式
+// 请求消息的类型从protoregistry.GlobalFiles中查找，表达式的结果必须为bool
+//
+// 参数:
+//   - expr: CEL表达式
+//   - opts: 可选的配置选项
+//
+// 返回值:
+//   - *Program: 编译后的表达式
+//   - error: 表达式语法、类型错误或结果不是bool时返回错误
+func Compile(expr string, opts ...Option) (*Program, error) {
+	o := defaultOptions().apply(opts...)
+	envOptions := append([]cel.EnvOption{
+		cel.TypeDescs(protoregistry.GlobalFiles),
+		cel.Variable("method", cel.StringType),
+		cel.Variable("metadata", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("peer", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("auth", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("request", cel.DynType),
+		cel.Variable("code", cel.StringType),
+	}, o.envOptions...)
+	env, err := cel.NewEnv(envOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("celpolicy: create env: %w", err)
+	}
+	ast, issues := env.Compile(expr)
+	if issues.Err() != nil {
+		return nil, fmt.Errorf("celpolicy: compile %q: %w", expr, issues.Err())
+	}
+	if !ast.OutputType().IsExactType(cel.BoolType) {
+		return nil, fmt.Errorf("celpolicy: expression %q must return bool, got %s", expr, ast.OutputType())
+	}
+	program, err := env.Program(ast, cel.EvalOptions(cel.OptOptimize))
+	if err != nil {
+		return nil, fmt.Errorf("celpolicy: program %q: %w", expr, err)
+	}
+	return &Program{expr: expr, program: program, opts: o}, nil
+}
+
+// MustCompile 编译CEL表达式，失败时panic，适用于固定的表达式
+//
+// 参数:
+//   - expr: CEL表达式
+//   - opts: 可选的配置选项
+//
+// 返回值:
+//   - *Program: 编译后的表达式
+func MustCompile(expr string, opts ...Option) *Program {
+	p, err := Compile(expr, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// String 返回表达式原文
+func (p *Program) String() string {
+	return p.expr
+}
+
+// Eval 对请求求值
+// 变量在表达式用到时才从上下文中提取
+//
+// 参数:
+//   - ctx: 请求上下文
+//   - in: 求值的输入
+//
+// 返回值:
+//   - bool: 表达式的结果
+//   - error: 求值失败时返回错误，例如访问不存在的键或字段
+func (p *Program) Eval(ctx context.Context, in Input) (bool, error) {
+	vars := map[string]any{
+		"method": in.FullMethod,
+		"metadata": func() any {
+			return metadataVariable(ctx)
+		},
+		"peer": func() any {
+			return peerVariable(ctx)
+		},
+		"auth": func() any {
+			return authVariable(ctx, p.opts)
+		},
+		"request": func() any {
+			if msg, ok := in.Request.(proto.Message); ok && msg != nil {
+				return msg
+			}
+			return types.NullValue
+		},
+		"code": func() any {
+			return status.Code(in.Err).String()
+		},
+	}
+	out, _, err := p.program.ContextEval(ctx, vars)
+	if err != nil {
+		return false, err
+	}
+	result, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("celpolicy: expression %q returned %s", p.expr, out.Type())
+	}
+	return result, nil
+}
+
+// Skip 判断是否跳过，求值失败时不跳过
+// 签名与ratelimiter.WithSkip一致，可直接作为其参数
+//
+// 参数:
+//   - ctx: 请求上下文
+//   - fullMethod: 完整的方法名
+//
+// 返回值:
+//   - bool: 是否跳过
+func (p *Program) Skip(ctx context.Context, fullMethod string) bool {
+	ok, err := p.Eval(ctx, Input{FullMethod: fullMethod})
+	return err == nil && ok
+}
+
+// SkipAccessLog 判断是否跳过访问日志，表达式中可使用code变量，求值失败时不跳过
+// 签名与accesslog.WithSkipContext一致，可直接作为其参数
+//
+// 参数:
+//   - ctx: 请求上下文
+//   - fullMethod: 完整的方法名
+//   - err: 调用结果
+//
+// 返回值:
+//   - bool: 是否跳过
+func (p *Program) SkipAccessLog(ctx context.Context, fullMethod string, err error) bool {
+	ok, evalErr := p.Eval(ctx, Input{FullMethod: fullMethod, Err: err})
+	return evalErr == nil && ok
+}
+
+// metadataVariable 将请求元数据转换为metadata变量
+func metadataVariable(ctx context.Context) map[string]string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := make(map[string]string, len(md))
+	for key, vals := range md {
+		values[key] = strings.Join(vals, ",")
+	}
+	return values
+}
+
+// peerVariable 将对端信息转换为peer变量
+func peerVariable(ctx context.Context) map[string]string {
+	values := map[string]string{"address": "", "auth_type": "", "spiffe_id": "", "common_name": ""}
+	if p, ok := peer.FromContext(ctx); ok {
+		if p.Addr != nil {
+			values["address"] = p.Addr.String()
+		}
+		if p.AuthInfo != nil {
+			values["auth_type"] = p.AuthInfo.AuthType()
+		}
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok && tlsInfo.SPIFFEID != nil {
+			values["spiffe_id"] = tlsInfo.SPIFFEID.String()
+		}
+	}
+	// mTLS认证函数提取的身份优先
+	if id, ok := auth.PeerIdentityFromContext(ctx); ok {
+		values["spiffe_id"], values["common_name"] = id.SPIFFEID, id.CommonName
+	}
+	return values
+}
+
+// authVariable 将调用方身份和JWT声明转换为auth变量
+func authVariable(ctx context.Context, o *options) map[string]any {
+	values := map[string]any{"subject": "", "roles": []string{}, "scopes": []string{}, "claims": map[string]any{}}
+	if principal, ok := o.principalFunc(ctx); ok && principal != nil {
+		values["subject"] = principal.Subject
+		if principal.Roles != nil {
+			values["roles"] = principal.Roles
+		}
+		if principal.Scopes != nil {
+			values["scopes"] = principal.Scopes
+		}
+	}
+	if claims, found := auth.ClaimsFromContext(ctx); found && claims.Raw != nil {
+		values["claims"] = claims.Raw
+	}
+	return values
+}
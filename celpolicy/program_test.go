@@ -0,0 +1,122 @@
+package celpolicy
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/url"
+	"testing"
+
+	"github.com/soyacen/grpc-middleware/accesslog"
+	"github.com/soyacen/grpc-middleware/authz"
+	"github.com/soyacen/grpc-middleware/ratelimiter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestCompile_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+	}{
+		{"语法错误", `method ==`},
+		{"未知变量", `tenant == "a"`},
+		{"结果不是bool", `method + "x"`},
+		{"类型错误", `method == 1`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile(tt.expr)
+			assert.Error(t, err)
+		})
+	}
+
+	assert.Panics(t, func() { MustCompile(`method ==`) })
+}
+
+func TestProgram_Eval(t *testing.T) {
+	tlsPeer := &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 4321},
+		AuthInfo: credentials.TLSInfo{
+			State:    tls.ConnectionState{PeerCertificates: []*x509.Certificate{{}}},
+			SPIFFEID: &url.URL{Scheme: "spiffe", Host: "example.org", Path: "/ns/prod/sa/web"},
+		},
+	}
+	ctx := peer.NewContext(context.Background(), tlsPeer)
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-tenant", "acme", "x-tag", "a", "x-tag", "b"))
+	ctx = authz.ContextWithPrincipal(ctx, &authz.Principal{Subject: "alice", Roles: []string{"admin"}, Scopes: []string{"billing:read"}})
+	req := &grpc_health_v1.HealthCheckRequest{Service: "acme"}
+
+	tests := []struct {
+		name    string
+		expr    string
+		in      Input
+		want    bool
+		wantErr bool
+	}{
+		{"方法名", `method.startsWith("/grpc.health.")`, Input{FullMethod: "/grpc.health.v1.Health/Check"}, true, false},
+		{"元数据", `metadata["x-tenant"] == "acme"`, Input{}, true, false},
+		{"多值元数据", `metadata["x-tag"] == "a,b"`, Input{}, true, false},
+		{"对端地址", `peer.address == "10.0.0.1:4321" && peer.auth_type == "tls"`, Input{}, true, false},
+		{"对端SPIFFE ID", `peer.spiffe_id.startsWith("spiffe://example.org/")`, Input{}, true, false},
+		{"调用方身份", `auth.subject == "alice" && "admin" in auth.roles && "billing:read" in auth.scopes`, Input{}, true, false},
+		{"请求消息", `request.service == metadata["x-tenant"]`, Input{Request: req}, true, false},
+		{"没有请求消息", `request == null`, Input{}, true, false},
+		{"状态码", `code == "NotFound"`, Input{Err: status.Error(codes.NotFound, "missing")}, true, false},
+		{"没有错误时状态码为OK", `code == "OK"`, Input{}, true, false},
+		{"不存在的键", `metadata["x-missing"] == "a"`, Input{}, false, true},
+		{"流式调用访问请求字段", `request.service == "acme"`, Input{}, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MustCompile(tt.expr).Eval(ctx, tt.in)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestProgram_EvalWithoutAuth(t *testing.T) {
+	// 没有调用方身份时auth变量包含空值，表达式不会因缺少键而失败
+	got, err := MustCompile(`auth.subject == "" && size(auth.roles) == 0 && size(auth.claims) == 0`).Eval(context.Background(), Input{})
+	require.NoError(t, err)
+	assert.True(t, got)
+
+	got, err = MustCompile(`peer.address == ""`).Eval(context.Background(), Input{})
+	require.NoError(t, err)
+	assert.True(t, got)
+}
+
+func TestProgram_Skip(t *testing.T) {
+	program := MustCompile(`method.startsWith("/grpc.health.") || metadata["x-internal"] == "true"`)
+	internal := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-internal", "true"))
+
+	assert.True(t, program.Skip(context.Background(), "/grpc.health.v1.Health/Check"))
+	assert.True(t, program.Skip(internal, "/orders.Orders/Get"))
+	// 求值失败时不跳过
+	assert.False(t, program.Skip(context.Background(), "/orders.Orders/Get"))
+
+	// 可直接作为ratelimiter和accesslog的跳过函数
+	_ = ratelimiter.WithSkip(program.Skip)
+	_ = accesslog.WithSkipContext(program.SkipAccessLog)
+}
+
+func TestProgram_SkipAccessLog(t *testing.T) {
+	program := MustCompile(`code == "OK" && method.startsWith("/grpc.health.")`)
+	assert.True(t, program.SkipAccessLog(context.Background(), "/grpc.health.v1.Health/Check", nil))
+	assert.False(t, program.SkipAccessLog(context.Background(), "/grpc.health.v1.Health/Check", status.Error(codes.Unavailable, "down")))
+	assert.False(t, program.SkipAccessLog(context.Background(), "/orders.Orders/Get", nil))
+}
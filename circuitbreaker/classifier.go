@@ -0,0 +1,151 @@
+package circuitbreaker
+
+import (
+	"slices"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Outcome 调用结果在熔断统计中的分类
+type Outcome int
+
+const (
+	// OutcomeSuccess 计为成功
+	OutcomeSuccess Outcome = iota
+	// OutcomeFailure 计为失败
+	OutcomeFailure
+	// OutcomeIgnore 不计入统计
+	OutcomeIgnore
+)
+
+// String 返回分类名称
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeSuccess:
+		return "success"
+	case OutcomeFailure:
+		return "failure"
+	case OutcomeIgnore:
+		return "ignore"
+	default:
+		return "unknown"
+	}
+}
+
+// Classifier 将调用结果分类，err为nil表示调用成功，流正常结束时也为nil
+type Classifier func(err error) Outcome
+
+// defaultFailureCodes 默认计为失败的状态码
+var defaultFailureCodes = []codes.Code{
+	codes.DeadlineExceeded,
+	codes.Internal,
+	codes.Unavailable,
+	codes.ResourceExhausted,
+}
+
+// DefaultClassifier 默认的分类器
+// DeadlineExceeded、Internal、Unavailable、ResourceExhausted和非gRPC状态的错误计为失败，其余计为成功
+func DefaultClassifier(err error) Outcome {
+	return classifyCodes(err, defaultFailureCodes, nil)
+}
+
+// CodeClassifier 按状态码创建分类器
+// 状态码在ignored中时不计入统计，在failures中时计为失败，非gRPC状态的错误计为失败，其余计为成功
+func CodeClassifier(failures []codes.Code, ignored []codes.Code) Classifier {
+	return func(err error) Outcome {
+		return classifyCodes(err, failures, ignored)
+	}
+}
+
+// classifyCodes 按状态码分类
+func classifyCodes(err error, failures, ignored []codes.Code) Outcome {
+	if err == nil {
+		return OutcomeSuccess
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return OutcomeFailure
+	}
+	switch {
+	case slices.Contains(ignored, st.Code()):
+		return OutcomeIgnore
+	case slices.Contains(failures, st.Code()):
+		return OutcomeFailure
+	default:
+		return OutcomeSuccess
+	}
+}
+
+// ReasonClassifier 按错误详情中ErrorInfo的原因创建分类器
+// 原因在ignored中时不计入统计，在failures中时计为失败，没有匹配的原因时交给next分类，next为nil时使用DefaultClassifier
+func ReasonClassifier(next Classifier, failures []string, ignored []string) Classifier {
+	if next == nil {
+		next = DefaultClassifier
+	}
+	return func(err error) Outcome {
+		if err == nil {
+			return next(err)
+		}
+		for _, detail := range status.Convert(err).Details() {
+			info, ok := detail.(*errdetails.ErrorInfo)
+			if !ok {
+				continue
+			}
+			switch {
+			case slices.Contains(ignored, info.GetReason()):
+				return OutcomeIgnore
+			case slices.Contains(failures, info.GetReason()):
+				return OutcomeFailure
+			}
+		}
+		return next(err)
+	}
+}
+
+// ignoreMarker 熔断器可实现该接口，在调用不计入统计时释放调用占用的名额
+type ignoreMarker interface {
+	MarkIgnore()
+}
+
+// classify 按分类器对调用结果分类，分类器为nil时使用DefaultClassifier
+func classify(classifier Classifier, err error) Outcome {
+	if classifier == nil {
+		classifier = DefaultClassifier
+	}
+	return classifier(err)
+}
+
+// mark 按分类结果标记熔断器
+func mark(breaker CircuitBreaker, o Outcome) {
+	switch o {
+	case OutcomeSuccess:
+		breaker.MarkSuccess()
+	case OutcomeFailure:
+		breaker.MarkFailure()
+	default:
+		if m, ok := breaker.(ignoreMarker); ok {
+			m.MarkIgnore()
+		}
+	}
+}
+
+// admitter 熔断器可实现该接口，为每个放行的请求返回记录结果的函数，
+// 以便丢弃状态变化前放行的请求的结果
+type admitter interface {
+	allow() (func(Outcome), bool)
+}
+
+// admit 判断请求是否被允许，允许时返回记录调用结果的函数
+func admit(breaker CircuitBreaker) (func(Outcome), bool) {
+	if a, ok := breaker.(admitter); ok {
+		return a.allow()
+	}
+	if !breaker.Allow() {
+		return nil, false
+	}
+	return func(o Outcome) {
+		mark(breaker, o)
+	}, true
+}
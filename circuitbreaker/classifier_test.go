@@ -0,0 +1,142 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// countingBreaker 记录标记次数的熔断器
+type countingBreaker struct {
+	successes, failures, ignores int
+}
+
+func (b *countingBreaker) Allow() bool  { return true }
+func (b *countingBreaker) MarkSuccess() { b.successes++ }
+func (b *countingBreaker) MarkFailure() { b.failures++ }
+func (b *countingBreaker) MarkIgnore()  { b.ignores++ }
+
+// reasonError 创建带有ErrorInfo的错误
+func reasonError(code codes.Code, reason string) error {
+	st, _ := status.New(code, "error").WithDetails(&errdetails.ErrorInfo{Reason: reason, Domain: "test"})
+	return st.Err()
+}
+
+func TestOutcome_String(t *testing.T) {
+	assert.Equal(t, "success", OutcomeSuccess.String())
+	assert.Equal(t, "failure", OutcomeFailure.String())
+	assert.Equal(t, "ignore", OutcomeIgnore.String())
+	assert.Equal(t, "unknown", Outcome(99).String())
+}
+
+func TestClassifiers(t *testing.T) {
+	codeClassifier := CodeClassifier([]codes.Code{codes.Unavailable}, []codes.Code{codes.Canceled})
+	reasonClassifier := ReasonClassifier(codeClassifier, []string{"QUOTA_EXCEEDED"}, []string{"CLIENT_CANCELLED"})
+
+	tests := []struct {
+		name       string
+		classifier Classifier
+		err        error
+		want       Outcome
+	}{
+		{"default_nil_success", DefaultClassifier, nil, OutcomeSuccess},
+		{"default_unavailable_failure", DefaultClassifier, status.Error(codes.Unavailable, ""), OutcomeFailure},
+		{"default_deadline_failure", DefaultClassifier, status.Error(codes.DeadlineExceeded, ""), OutcomeFailure},
+		{"default_not_found_success", DefaultClassifier, status.Error(codes.NotFound, ""), OutcomeSuccess},
+		{"default_non_status_failure", DefaultClassifier, errors.New("boom"), OutcomeFailure},
+		{"codes_canceled_ignore", codeClassifier, status.Error(codes.Canceled, ""), OutcomeIgnore},
+		{"codes_internal_success", codeClassifier, status.Error(codes.Internal, ""), OutcomeSuccess},
+		{"codes_unavailable_failure", codeClassifier, status.Error(codes.Unavailable, ""), OutcomeFailure},
+		{"reason_failure", reasonClassifier, reasonError(codes.FailedPrecondition, "QUOTA_EXCEEDED"), OutcomeFailure},
+		{"reason_ignore", reasonClassifier, reasonError(codes.Unavailable, "CLIENT_CANCELLED"), OutcomeIgnore},
+		{"reason_fallback", reasonClassifier, reasonError(codes.Unavailable, "OTHER"), OutcomeFailure},
+		{"reason_nil_success", reasonClassifier, nil, OutcomeSuccess},
+		{"reason_default_next", ReasonClassifier(nil, nil, nil), status.Error(codes.Internal, ""), OutcomeFailure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.classifier(tt.err))
+		})
+	}
+}
+
+func TestMark(t *testing.T) {
+	breaker := &countingBreaker{}
+	classifier := CodeClassifier([]codes.Code{codes.Unavailable}, []codes.Code{codes.Canceled})
+
+	mark(breaker, classify(classifier, nil))
+	mark(breaker, classify(classifier, status.Error(codes.Unavailable, "")))
+	mark(breaker, classify(classifier, status.Error(codes.Canceled, "")))
+	mark(breaker, classify(nil, status.Error(codes.Internal, "")))
+
+	assert.Equal(t, &countingBreaker{successes: 1, failures: 2, ignores: 1}, breaker)
+}
+
+func TestUnary_ClassifierIgnore(t *testing.T) {
+	interceptor := UnaryClientInterceptor(
+		WithThreeState(),
+		WithConsecutiveFailures(2),
+		WithClassifier(CodeClassifier(defaultFailureCodes, []codes.Code{codes.Canceled})),
+	)
+	canceled := &mockInvoker{err: status.Error(codes.Canceled, "context canceled")}
+
+	for i := 0; i < 10; i++ {
+		_ = interceptor(context.Background(), "/test/method", nil, nil, nil, canceled.invoke)
+	}
+
+	assert.Equal(t, 10, canceled.callCount, "ignored calls should not trip the breaker")
+}
+
+func TestStream_Classifier(t *testing.T) {
+	breaker := &countingBreaker{}
+	classifier := CodeClassifier(nil, []codes.Code{codes.Canceled})
+
+	for _, err := range []error{io.EOF, status.Error(codes.Canceled, ""), errors.New("boom")} {
+		w := &wrappedClientStream{ClientStream: &mockClientStream{recvErr: err}, breaker: breaker, classifier: classifier}
+		_ = w.RecvMsg(nil)
+	}
+
+	assert.Equal(t, &countingBreaker{successes: 1, failures: 1, ignores: 1}, breaker)
+}
+
+func TestStreamClientInterceptor_ClassifierOnStreamerError(t *testing.T) {
+	interceptor := StreamClientInterceptor(
+		WithThreeState(),
+		WithConsecutiveFailures(1),
+		WithClassifier(CodeClassifier([]codes.Code{codes.Aborted}, nil)),
+	)
+	calls := 0
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		calls++
+		return nil, status.Error(codes.Aborted, "aborted")
+	}
+
+	_, err := interceptor(context.Background(), &grpc.StreamDesc{}, nil, "/test/stream", streamer)
+	assert.Equal(t, codes.Aborted, status.Code(err))
+	_, err = interceptor(context.Background(), &grpc.StreamDesc{}, nil, "/test/stream", streamer)
+	assert.Equal(t, ErrCircuitBreakerOpen, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestThreeState_MarkIgnoreReleasesProbe(t *testing.T) {
+	now := time.Now()
+	b := newTestThreeState(&now, WithConsecutiveFailures(1), WithOpenTimeout(time.Second, 0))
+
+	b.MarkFailure()
+	now = now.Add(time.Second)
+	assert.True(t, b.Allow())
+	assert.False(t, b.Allow())
+
+	b.MarkIgnore()
+	assert.True(t, b.Allow())
+	assert.Equal(t, StateHalfOpen, b.State())
+}
@@ -6,14 +6,15 @@ import (
 	"sync"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 // wrappedClientStream 包装 grpc.ClientStream 以跟踪流错误
 type wrappedClientStream struct {
 	grpc.ClientStream
-	breaker  CircuitBreaker
+	breaker    CircuitBreaker
+	classifier Classifier
+	// done 记录流的结果，为nil时直接标记breaker
+	done     func(Outcome)
 	markOnce sync.Once
 }
 
@@ -28,32 +29,28 @@ func (w *wrappedClientStream) RecvMsg(m interface{}) error {
 	w.markOnce.Do(func() {
 		if err == io.EOF {
 			// 流正常结束
-			w.breaker.MarkSuccess()
+			w.finish(classify(w.classifier, nil))
 			return
 		}
-
-		st, ok := status.FromError(err)
-		if ok {
-			switch st.Code() {
-			case codes.DeadlineExceeded,
-				codes.Internal,
-				codes.Unavailable,
-				codes.ResourceExhausted:
-				w.breaker.MarkFailure()
-			default:
-				w.breaker.MarkSuccess()
-			}
-		} else {
-			w.breaker.MarkFailure()
-		}
+		w.finish(classify(w.classifier, err))
 	})
 
 	return err
 }
 
+// finish 记录流的结果
+func (w *wrappedClientStream) finish(o Outcome) {
+	if w.done != nil {
+		w.done(o)
+		return
+	}
+	mark(w.breaker, o)
+}
+
 // StreamClientInterceptor 创建流式调用的客户端熔断拦截器
 func StreamClientInterceptor(opts ...Option) grpc.StreamClientInterceptor {
-	breaker := defaultOptions().apply(opts...).init().newCircuitBreaker()
+	o := defaultOptions().apply(opts...).init()
+	breakers := newBreakerGroup(o)
 
 	return func(
 		ctx context.Context,
@@ -63,39 +60,31 @@ func StreamClientInterceptor(opts ...Option) grpc.StreamClientInterceptor {
 		streamer grpc.Streamer,
 		opts ...grpc.CallOption,
 	) (grpc.ClientStream, error) {
-		if !breaker.Allow() {
+		breaker := breakers.get(ctx, cc, method)
+		done, ok := admit(breaker)
+		if !ok {
 			return nil, ErrCircuitBreakerOpen
 		}
 
 		stream, err := streamer(ctx, desc, cc, method, opts...)
 		if err != nil {
-			st, ok := status.FromError(err)
-			if ok {
-				switch st.Code() {
-				case codes.DeadlineExceeded,
-					codes.Internal,
-					codes.Unavailable,
-					codes.ResourceExhausted:
-					breaker.MarkFailure()
-				default:
-					breaker.MarkSuccess()
-				}
-			} else {
-				breaker.MarkFailure()
-			}
+			done(classify(o.Classifier, err))
 			return nil, err
 		}
 
 		return &wrappedClientStream{
 			ClientStream: stream,
 			breaker:      breaker,
+			classifier:   o.Classifier,
+			done:         done,
 		}, nil
 	}
 }
 
 // UnaryClientInterceptor 创建一元调用的客户端熔断拦截器
 func UnaryClientInterceptor(opts ...Option) grpc.UnaryClientInterceptor {
-	breaker := defaultOptions().apply(opts...).init().newCircuitBreaker()
+	o := defaultOptions().apply(opts...).init()
+	breakers := newBreakerGroup(o)
 	return func(
 		ctx context.Context,
 		method string,
@@ -105,32 +94,13 @@ func UnaryClientInterceptor(opts ...Option) grpc.UnaryClientInterceptor {
 		invoker grpc.UnaryInvoker,
 		grpcOpts ...grpc.CallOption,
 	) error {
-		if !breaker.Allow() {
+		done, ok := admit(breakers.get(ctx, cc, method))
+		if !ok {
 			return ErrCircuitBreakerOpen
 		}
 
 		err := invoker(ctx, method, req, reply, cc, grpcOpts...)
-
-		if err == nil {
-			breaker.MarkSuccess()
-			return nil
-		}
-
-		st, ok := status.FromError(err)
-		if ok {
-			switch st.Code() {
-			case codes.DeadlineExceeded,
-				codes.Internal,
-				codes.Unavailable,
-				codes.ResourceExhausted:
-				breaker.MarkFailure()
-			default:
-				breaker.MarkSuccess()
-			}
-		} else {
-			breaker.MarkFailure()
-		}
-
+		done(classify(o.Classifier, err))
 		return err
 	}
 }
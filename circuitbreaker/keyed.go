@@ -0,0 +1,117 @@
+package circuitbreaker
+
+import (
+	"context"
+	"path"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// KeyFunc 返回调用使用的熔断器键，相同键的调用共享一个熔断器
+type KeyFunc func(ctx context.Context, cc *grpc.ClientConn, method string) string
+
+// MethodKey 按方法名区分熔断器
+func MethodKey(_ context.Context, _ *grpc.ClientConn, method string) string {
+	return method
+}
+
+// TargetKey 按连接的目标地址区分熔断器
+func TargetKey(_ context.Context, cc *grpc.ClientConn, _ string) string {
+	if cc == nil {
+		return ""
+	}
+	return cc.Target()
+}
+
+// keyOverride 匹配键的熔断器参数覆盖
+type keyOverride struct {
+	pattern string
+	opts    []Option
+}
+
+// forKey 返回键使用的熔断器参数，按添加顺序使用第一个匹配的覆盖
+func (o *options) forKey(key string) *options {
+	for _, override := range o.overrides {
+		if ok, _ := path.Match(override.pattern, key); ok {
+			c := *o
+			return c.apply(override.opts...).init()
+		}
+	}
+	return o
+}
+
+// breakerEntry 按键创建的熔断器
+type breakerEntry struct {
+	breaker  CircuitBreaker
+	lastUsed time.Time
+}
+
+// breakerGroup 按键管理熔断器，未设置KeyFunc时所有调用共享一个熔断器
+type breakerGroup struct {
+	opts   *options
+	shared CircuitBreaker
+	now    func() time.Time
+	// overflow 键的数量达到上限后新的键共享的熔断器
+	overflow CircuitBreaker
+
+	mu        sync.Mutex
+	entries   map[string]*breakerEntry
+	lastSweep time.Time
+}
+
+// newBreakerGroup 创建熔断器组
+func newBreakerGroup(o *options) *breakerGroup {
+	g := &breakerGroup{opts: o, now: time.Now}
+	if o.KeyFunc == nil {
+		g.shared = o.newCircuitBreaker()
+		return g
+	}
+	g.entries = make(map[string]*breakerEntry)
+	g.lastSweep = g.now()
+	return g
+}
+
+// get 返回调用使用的熔断器，首次使用时创建，并清理空闲的熔断器
+func (g *breakerGroup) get(ctx context.Context, cc *grpc.ClientConn, method string) CircuitBreaker {
+	if g.shared != nil {
+		return g.shared
+	}
+	key := g.opts.KeyFunc(ctx, cc, method)
+	now := g.now()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if now.Sub(g.lastSweep) >= g.opts.IdleTimeout {
+		g.sweep(now)
+	}
+	entry, ok := g.entries[key]
+	if !ok {
+		if len(g.entries) >= g.opts.MaxKeys {
+			if g.overflow == nil {
+				g.overflow = g.opts.newCircuitBreaker()
+			}
+			return g.overflow
+		}
+		entry = &breakerEntry{breaker: g.opts.forKey(key).newKeyedCircuitBreaker(key)}
+		g.entries[key] = entry
+	}
+	entry.lastUsed = now
+	return entry.breaker
+}
+
+// sweep 清理超过空闲时间未使用的熔断器，调用方需持有锁
+// 未关闭的三态熔断器不清理，避免冷却时间长于空闲时间时以关闭状态重新创建
+func (g *breakerGroup) sweep(now time.Time) {
+	for key, entry := range g.entries {
+		if now.Sub(entry.lastUsed) < g.opts.IdleTimeout {
+			continue
+		}
+		if s, ok := entry.breaker.(interface{ State() State }); ok && s.State() != StateClosed {
+			continue
+		}
+		delete(g.entries, key)
+	}
+	g.lastSweep = now
+}
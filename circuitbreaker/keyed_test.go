@@ -0,0 +1,165 @@
+package circuitbreaker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnary_KeyedByMethod_IsolatesFailures(t *testing.T) {
+	interceptor := UnaryClientInterceptor(WithKeyFunc(MethodKey), WithK(1.0), WithWindow(time.Second), WithBuckets(10))
+	failMock := &mockInvoker{err: status.Error(codes.Unavailable, "unavailable")}
+	successMock := &mockInvoker{err: nil}
+
+	for i := 0; i < 100; i++ {
+		_ = interceptor(context.Background(), "/test/failing", nil, nil, nil, failMock.invoke)
+	}
+
+	droppedFailing, droppedHealthy := 0, 0
+	for i := 0; i < 100; i++ {
+		if status.Code(interceptor(context.Background(), "/test/failing", nil, nil, nil, successMock.invoke)) == codes.ResourceExhausted {
+			droppedFailing++
+		}
+		if status.Code(interceptor(context.Background(), "/test/healthy", nil, nil, nil, successMock.invoke)) == codes.ResourceExhausted {
+			droppedHealthy++
+		}
+	}
+
+	assert.Greater(t, droppedFailing, 30, "failing method should be throttled")
+	assert.Equal(t, 0, droppedHealthy, "healthy method should not be throttled")
+}
+
+func TestBreakerGroup_Shared(t *testing.T) {
+	g := newBreakerGroup(defaultOptions().init())
+
+	assert.Same(t, g.get(context.Background(), nil, "/a/a"), g.get(context.Background(), nil, "/b/b"))
+}
+
+func TestBreakerGroup_Keyed(t *testing.T) {
+	tests := []struct {
+		name     string
+		keyFunc  KeyFunc
+		methodA  string
+		methodB  string
+		wantSame bool
+	}{
+		{"method_key_different_methods", MethodKey, "/a/a", "/b/b", false},
+		{"method_key_same_method", MethodKey, "/a/a", "/a/a", true},
+		{"target_key_nil_conn", TargetKey, "/a/a", "/b/b", true},
+		{"custom_key_by_service", func(_ context.Context, _ *grpc.ClientConn, method string) string {
+			return method[:2]
+		}, "/a/x", "/a/y", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newBreakerGroup(defaultOptions().apply(WithKeyFunc(tt.keyFunc)).init())
+			a := g.get(context.Background(), nil, tt.methodA)
+			b := g.get(context.Background(), nil, tt.methodB)
+			if tt.wantSame {
+				assert.Same(t, a, b)
+			} else {
+				assert.NotSame(t, a, b)
+			}
+		})
+	}
+}
+
+func TestBreakerGroup_IdleEviction(t *testing.T) {
+	now := time.Now()
+	g := newBreakerGroup(defaultOptions().apply(WithKeyFunc(MethodKey), WithIdleTimeout(time.Minute)).init())
+	g.now = func() time.Time { return now }
+
+	idle := g.get(context.Background(), nil, "/test/idle")
+	active := g.get(context.Background(), nil, "/test/active")
+
+	now = now.Add(40 * time.Second)
+	assert.Same(t, active, g.get(context.Background(), nil, "/test/active"))
+
+	now = now.Add(40 * time.Second)
+	assert.Same(t, active, g.get(context.Background(), nil, "/test/active"))
+	assert.Len(t, g.entries, 1)
+	assert.NotSame(t, idle, g.get(context.Background(), nil, "/test/idle"))
+}
+
+func TestBreakerGroup_IdleEvictionKeepsOpenBreakers(t *testing.T) {
+	now := time.Now()
+	g := newBreakerGroup(defaultOptions().apply(
+		WithKeyFunc(MethodKey),
+		WithIdleTimeout(time.Minute),
+		WithThreeState(),
+		WithConsecutiveFailures(1),
+		WithOpenTimeout(time.Hour, 0),
+	).init())
+	g.now = func() time.Time { return now }
+
+	open := g.get(context.Background(), nil, "/test/open")
+	open.MarkFailure()
+	g.get(context.Background(), nil, "/test/closed")
+
+	now = now.Add(2 * time.Minute)
+	g.get(context.Background(), nil, "/test/other")
+	assert.Len(t, g.entries, 2)
+	assert.Same(t, open, g.get(context.Background(), nil, "/test/open"))
+	assert.Equal(t, StateOpen, open.(*ThreeStateCircuitBreaker).State())
+}
+
+func TestBreakerGroup_MaxKeys(t *testing.T) {
+	g := newBreakerGroup(defaultOptions().apply(WithKeyFunc(MethodKey), WithMaxKeys(2)).init())
+
+	a := g.get(context.Background(), nil, "/test/a")
+	b := g.get(context.Background(), nil, "/test/b")
+	c := g.get(context.Background(), nil, "/test/c")
+	d := g.get(context.Background(), nil, "/test/d")
+
+	assert.NotSame(t, a, b)
+	assert.Same(t, c, d, "超出上限的键共享一个熔断器")
+	assert.NotSame(t, a, c)
+	assert.Len(t, g.entries, 2)
+	assert.Same(t, a, g.get(context.Background(), nil, "/test/a"))
+}
+
+func TestWithKeyOptions_InvalidPattern(t *testing.T) {
+	o := defaultOptions().apply(
+		WithKeyFunc(MethodKey),
+		WithKeyOptions("/batch[", WithK(3.0)),
+		WithKeyOptions("/*/*", WithK(2.5)),
+	).init()
+
+	assert.Equal(t, 2.5, o.forKey("/batch.Jobs/Run").K, "格式错误的模式不匹配任何键")
+}
+
+func TestBreakerGroup_KeyOptions(t *testing.T) {
+	o := defaultOptions().apply(
+		WithKeyFunc(MethodKey),
+		WithK(1.5),
+		WithKeyOptions("/batch.*/*", WithK(3.0), WithWindow(time.Minute)),
+		WithKeyOptions("/*/*", WithK(2.5)),
+	).init()
+	g := newBreakerGroup(o)
+
+	tests := []struct {
+		name       string
+		method     string
+		wantK      float64
+		wantWindow time.Duration
+	}{
+		{"batch_override", "/batch.Jobs/Run", 3.0, time.Minute},
+		{"fallback_override", "/orders.Orders/Get", 2.5, time.Second * 10},
+		{"no_override", "not-a-method", 1.5, time.Second * 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breaker := g.get(context.Background(), nil, tt.method).(*sreCircuitBreaker)
+			assert.Equal(t, tt.wantK, breaker.k)
+			assert.Equal(t, tt.wantWindow, breaker.window.windowSize)
+		})
+	}
+	assert.Equal(t, 1.5, o.K, "overrides should not modify base options")
+}
@@ -11,6 +11,32 @@ type options struct {
 	K       float64
 	Window  time.Duration
 	Buckets int
+	// KeyFunc 熔断器键函数，为nil时所有调用共享一个熔断器
+	KeyFunc KeyFunc
+	// IdleTimeout 按键创建的熔断器空闲多久后被清理
+	IdleTimeout time.Duration
+	// MaxKeys 按键创建的熔断器的最大数量，超出后新的键共享一个熔断器
+	MaxKeys int
+	// overrides 按键覆盖的熔断器参数
+	overrides []keyOverride
+	// ThreeState 是否使用三态熔断器，默认使用SRE自适应熔断器
+	ThreeState bool
+	// ConsecutiveFailures 三态熔断器连续失败多少次后打开，为0时不按连续失败打开
+	ConsecutiveFailures int
+	// FailureRatio 三态熔断器统计窗口内失败率达到多少后打开，为0时不按失败率打开
+	FailureRatio float64
+	// MinRequests 按失败率打开要求的统计窗口内最少请求数
+	MinRequests int
+	// OpenTimeout 三态熔断器打开后的冷却时间
+	OpenTimeout time.Duration
+	// MaxOpenTimeout 半开探测失败后冷却时间按指数增长的上限
+	MaxOpenTimeout time.Duration
+	// HalfOpenRequests 半开时允许的探测请求数，全部成功后关闭
+	HalfOpenRequests int
+	// OnStateChange 三态熔断器状态变化时的回调，key为熔断器键
+	OnStateChange func(key string, from, to State)
+	// Classifier 调用结果分类器
+	Classifier Classifier
 }
 
 // Option 配置选项函数类型
@@ -37,11 +63,101 @@ func WithBuckets(buckets int) Option {
 	}
 }
 
+// WithKeyFunc 设置熔断器键函数，每个键使用独立的熔断器和统计窗口
+// 熔断器在首次使用时创建，空闲超过IdleTimeout后被清理，数量不超过MaxKeys，例如MethodKey、TargetKey
+func WithKeyFunc(f KeyFunc) Option {
+	return func(o *options) {
+		o.KeyFunc = f
+	}
+}
+
+// WithIdleTimeout 设置按键创建的熔断器的空闲清理时间
+func WithIdleTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.IdleTimeout = d
+	}
+}
+
+// WithMaxKeys 设置按键创建的熔断器的最大数量，超出后新的键共享一个熔断器，直到空闲的熔断器被清理
+func WithMaxKeys(n int) Option {
+	return func(o *options) {
+		o.MaxKeys = n
+	}
+}
+
+// WithKeyOptions 为匹配的键覆盖熔断器参数，键按path.Match匹配，多条覆盖取第一条匹配的
+// 例如 WithKeyOptions("/batch.*/*", WithK(3)) 为批处理方法使用更宽松的熔断因子，模式格式错误时不匹配任何键
+func WithKeyOptions(pattern string, opts ...Option) Option {
+	return func(o *options) {
+		o.overrides = append(o.overrides, keyOverride{pattern: pattern, opts: opts})
+	}
+}
+
+// WithThreeState 使用关闭、打开、半开三态熔断器代替SRE自适应熔断器
+func WithThreeState() Option {
+	return func(o *options) {
+		o.ThreeState = true
+	}
+}
+
+// WithConsecutiveFailures 设置三态熔断器连续失败多少次后打开
+func WithConsecutiveFailures(n int) Option {
+	return func(o *options) {
+		o.ConsecutiveFailures = n
+	}
+}
+
+// WithFailureRatio 设置三态熔断器在统计窗口内请求数不少于minRequests且失败率达到ratio时打开
+func WithFailureRatio(ratio float64, minRequests int) Option {
+	return func(o *options) {
+		o.FailureRatio = ratio
+		o.MinRequests = minRequests
+	}
+}
+
+// WithOpenTimeout 设置三态熔断器打开后的冷却时间，半开探测失败后冷却时间翻倍，不超过maxTimeout
+// maxTimeout小于timeout时冷却时间不增长
+func WithOpenTimeout(timeout, maxTimeout time.Duration) Option {
+	return func(o *options) {
+		o.OpenTimeout = timeout
+		o.MaxOpenTimeout = maxTimeout
+	}
+}
+
+// WithHalfOpenRequests 设置三态熔断器半开时允许的探测请求数
+func WithHalfOpenRequests(n int) Option {
+	return func(o *options) {
+		o.HalfOpenRequests = n
+	}
+}
+
+// WithOnStateChange 设置三态熔断器状态变化时的回调
+func WithOnStateChange(f func(key string, from, to State)) Option {
+	return func(o *options) {
+		o.OnStateChange = f
+	}
+}
+
+// WithClassifier 设置调用结果分类器，一元和流式调用使用相同的分类器，默认为DefaultClassifier
+// 例如 CodeClassifier(failureCodes, []codes.Code{codes.Canceled}) 不统计被调用方自己取消的调用
+func WithClassifier(c Classifier) Option {
+	return func(o *options) {
+		o.Classifier = c
+	}
+}
+
 func defaultOptions() *options {
 	return &options{
-		K:       2.0,
-		Window:  time.Second * 10,
-		Buckets: 40,
+		K:           2.0,
+		Window:      time.Second * 10,
+		Buckets:     40,
+		IdleTimeout: time.Minute * 10,
+		MaxKeys:     10000,
+
+		ConsecutiveFailures: 5,
+		OpenTimeout:         time.Second * 30,
+		HalfOpenRequests:    1,
+		Classifier:          DefaultClassifier,
 	}
 }
 
@@ -55,6 +171,24 @@ func (o *options) init() *options {
 	if o.Buckets <= 0 {
 		o.Buckets = 40
 	}
+	if o.IdleTimeout <= 0 {
+		o.IdleTimeout = time.Minute * 10
+	}
+	if o.MaxKeys <= 0 {
+		o.MaxKeys = 10000
+	}
+	if o.OpenTimeout <= 0 {
+		o.OpenTimeout = time.Second * 30
+	}
+	if o.MaxOpenTimeout < o.OpenTimeout {
+		o.MaxOpenTimeout = o.OpenTimeout
+	}
+	if o.HalfOpenRequests <= 0 {
+		o.HalfOpenRequests = 1
+	}
+	if o.Classifier == nil {
+		o.Classifier = DefaultClassifier
+	}
 	return o
 }
 
@@ -66,6 +200,14 @@ func (o *options) apply(opts ...Option) *options {
 }
 
 func (o *options) newCircuitBreaker() CircuitBreaker {
+	return o.newKeyedCircuitBreaker("")
+}
+
+// newKeyedCircuitBreaker 按选项创建指定键的熔断器
+func (o *options) newKeyedCircuitBreaker(key string) CircuitBreaker {
+	if o.ThreeState {
+		return o.newThreeStateCircuitBreaker(key)
+	}
 	rnd, err := randx.NewPCG() // Create a new PCG generator if none available.
 	if err != nil {
 		panic(err) // Panic on failure to initialize due to crypto/rand issues.
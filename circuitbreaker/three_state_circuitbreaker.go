@@ -0,0 +1,256 @@
+package circuitbreaker
+
+import (
+	"sync"
+	"time"
+)
+
+// State 三态熔断器的状态
+type State int
+
+const (
+	// StateClosed 关闭状态，请求正常通过
+	StateClosed State = iota
+	// StateOpen 打开状态，请求全部拒绝
+	StateOpen
+	// StateHalfOpen 半开状态，允许少量探测请求
+	StateHalfOpen
+)
+
+// String 返回状态名称
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// ThreeStateCircuitBreaker 关闭、打开、半开三态熔断器
+// 关闭时连续失败次数或统计窗口内的失败率达到阈值后打开；打开持续冷却时间后进入半开，
+// 半开时允许有限的探测请求，探测全部成功后关闭，任一失败则重新打开并按指数增长冷却时间
+type ThreeStateCircuitBreaker struct {
+	key    string
+	opts   *options
+	window *rollingCounter
+	now    func() time.Time
+
+	mu          sync.Mutex
+	state       State
+	consecutive int
+	openedAt    time.Time
+	openTimeout time.Duration
+	probes      int
+	successes   int
+	probeSince  time.Time
+	// generation 每次状态变化或替换探测请求时递增，用于丢弃之前放行的请求的结果
+	generation uint64
+}
+
+// NewThreeStateCircuitBreaker 创建三态熔断器，可直接使用或通过WithThreeState在拦截器中使用
+func NewThreeStateCircuitBreaker(opts ...Option) *ThreeStateCircuitBreaker {
+	return defaultOptions().apply(opts...).init().newThreeStateCircuitBreaker("")
+}
+
+// newThreeStateCircuitBreaker 创建指定键的三态熔断器
+func (o *options) newThreeStateCircuitBreaker(key string) *ThreeStateCircuitBreaker {
+	return &ThreeStateCircuitBreaker{
+		key:         key,
+		opts:        o,
+		window:      newRollingCounter(o.Window, o.Buckets),
+		now:         time.Now,
+		openTimeout: o.OpenTimeout,
+	}
+}
+
+// State 返回当前状态，冷却时间结束后在下一次Allow时进入半开
+func (b *ThreeStateCircuitBreaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Allow 判断请求是否被允许
+func (b *ThreeStateCircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	from := b.state
+	allowed := b.allowLocked()
+	to := b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+	return allowed
+}
+
+// allow 判断请求是否被允许，允许时返回记录调用结果的函数
+// 调用结果只在放行时的状态仍然有效时计入，例如关闭时放行、半开后才返回的请求不会被当作探测结果
+func (b *ThreeStateCircuitBreaker) allow() (func(Outcome), bool) {
+	b.mu.Lock()
+	from := b.state
+	allowed := b.allowLocked()
+	generation := b.generation
+	to := b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+	if !allowed {
+		return nil, false
+	}
+	return func(o Outcome) {
+		b.finish(generation, o)
+	}, true
+}
+
+// finish 记录放行时处于generation的请求的结果，状态已经变化时丢弃
+func (b *ThreeStateCircuitBreaker) finish(generation uint64, o Outcome) {
+	b.mu.Lock()
+	from := b.state
+	if generation == b.generation {
+		switch o {
+		case OutcomeSuccess:
+			b.successLocked()
+		case OutcomeFailure:
+			b.failureLocked()
+		default:
+			b.ignoreLocked()
+		}
+	}
+	to := b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+}
+
+// allowLocked 判断请求是否被允许，调用方需持有锁
+func (b *ThreeStateCircuitBreaker) allowLocked() bool {
+	now := b.now()
+	switch b.state {
+	case StateOpen:
+		if now.Sub(b.openedAt) < b.openTimeout {
+			return false
+		}
+		b.state = StateHalfOpen
+		b.probes, b.successes, b.probeSince = 1, 0, now
+		b.generation++
+		return true
+	case StateHalfOpen:
+		if b.probes < b.opts.HalfOpenRequests {
+			b.probes++
+			return true
+		}
+		// 探测请求长时间没有结果时允许新的探测，避免停留在半开状态
+		if now.Sub(b.probeSince) >= b.openTimeout {
+			b.probes, b.successes, b.probeSince = 1, 0, now
+			b.generation++
+			return true
+		}
+		return false
+	default:
+		return true
+	}
+}
+
+// MarkSuccess 标记请求成功
+func (b *ThreeStateCircuitBreaker) MarkSuccess() {
+	b.mu.Lock()
+	from := b.state
+	b.successLocked()
+	to := b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+}
+
+// MarkFailure 标记请求失败
+func (b *ThreeStateCircuitBreaker) MarkFailure() {
+	b.mu.Lock()
+	from := b.state
+	b.failureLocked()
+	to := b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+}
+
+// MarkIgnore 标记请求不计入统计，半开时释放探测名额
+func (b *ThreeStateCircuitBreaker) MarkIgnore() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.ignoreLocked()
+}
+
+// successLocked 记录成功，调用方需持有锁
+func (b *ThreeStateCircuitBreaker) successLocked() {
+	switch b.state {
+	case StateClosed:
+		b.consecutive = 0
+		b.window.Add(1, 1)
+	case StateHalfOpen:
+		b.successes++
+		if b.probes > 0 {
+			b.probes--
+		}
+		if b.successes >= b.opts.HalfOpenRequests {
+			b.state = StateClosed
+			b.consecutive = 0
+			b.openTimeout = b.opts.OpenTimeout
+			b.window = newRollingCounter(b.opts.Window, b.opts.Buckets)
+			b.generation++
+		}
+	}
+}
+
+// failureLocked 记录失败，调用方需持有锁
+func (b *ThreeStateCircuitBreaker) failureLocked() {
+	switch b.state {
+	case StateClosed:
+		b.consecutive++
+		b.window.Add(1, 0)
+		if b.shouldTrip() {
+			b.trip()
+		}
+	case StateHalfOpen:
+		// 探测失败时按指数增长冷却时间
+		b.openTimeout *= 2
+		if b.openTimeout > b.opts.MaxOpenTimeout {
+			b.openTimeout = b.opts.MaxOpenTimeout
+		}
+		b.trip()
+	}
+}
+
+// ignoreLocked 释放半开时的探测名额，调用方需持有锁
+func (b *ThreeStateCircuitBreaker) ignoreLocked() {
+	if b.state == StateHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+// shouldTrip 判断关闭状态下是否达到打开阈值，调用方需持有锁
+func (b *ThreeStateCircuitBreaker) shouldTrip() bool {
+	if b.opts.ConsecutiveFailures > 0 && b.consecutive >= b.opts.ConsecutiveFailures {
+		return true
+	}
+	if b.opts.FailureRatio > 0 {
+		requests, accepts := b.window.Summary()
+		if requests >= int64(b.opts.MinRequests) && float64(requests-accepts)/float64(requests) >= b.opts.FailureRatio {
+			return true
+		}
+	}
+	return false
+}
+
+// trip 打开熔断器，调用方需持有锁
+func (b *ThreeStateCircuitBreaker) trip() {
+	b.state = StateOpen
+	b.openedAt = b.now()
+	b.probes, b.successes = 0, 0
+	b.generation++
+}
+
+// notify 状态变化时调用回调
+func (b *ThreeStateCircuitBreaker) notify(from, to State) {
+	if from != to && b.opts.OnStateChange != nil {
+		b.opts.OnStateChange(b.key, from, to)
+	}
+}
@@ -0,0 +1,225 @@
+package circuitbreaker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newTestThreeState 创建使用可控时钟的三态熔断器
+func newTestThreeState(now *time.Time, opts ...Option) *ThreeStateCircuitBreaker {
+	b := NewThreeStateCircuitBreaker(opts...)
+	b.now = func() time.Time { return *now }
+	return b
+}
+
+func TestState_String(t *testing.T) {
+	tests := []struct {
+		name  string
+		state State
+		want  string
+	}{
+		{"关闭", StateClosed, "closed"},
+		{"打开", StateOpen, "open"},
+		{"半开", StateHalfOpen, "half-open"},
+		{"未知状态", State(99), "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.state.String())
+		})
+	}
+}
+
+func TestThreeState_TripOnConsecutiveFailures(t *testing.T) {
+	now := time.Now()
+	b := newTestThreeState(&now, WithConsecutiveFailures(3))
+
+	b.MarkFailure()
+	b.MarkFailure()
+	b.MarkSuccess()
+	b.MarkFailure()
+	b.MarkFailure()
+	assert.Equal(t, StateClosed, b.State(), "成功后重置连续失败次数")
+
+	b.MarkFailure()
+	assert.Equal(t, StateOpen, b.State())
+	assert.False(t, b.Allow())
+}
+
+func TestThreeState_TripOnFailureRatio(t *testing.T) {
+	now := time.Now()
+	b := newTestThreeState(&now, WithConsecutiveFailures(0), WithFailureRatio(0.5, 10))
+
+	for i := 0; i < 4; i++ {
+		b.MarkFailure()
+		b.MarkSuccess()
+	}
+	b.MarkFailure()
+	assert.Equal(t, StateClosed, b.State(), "请求数未达到最小值时不打开")
+
+	b.MarkSuccess()
+	b.MarkFailure()
+	assert.Equal(t, StateOpen, b.State())
+}
+
+func TestThreeState_HalfOpenProbes(t *testing.T) {
+	now := time.Now()
+	var transitions []string
+	b := newTestThreeState(&now,
+		WithConsecutiveFailures(1),
+		WithOpenTimeout(time.Second, time.Second*3),
+		WithHalfOpenRequests(2),
+		WithOnStateChange(func(key string, from, to State) {
+			transitions = append(transitions, from.String()+"->"+to.String())
+		}),
+	)
+
+	b.MarkFailure()
+	now = now.Add(time.Millisecond * 999)
+	assert.False(t, b.Allow(), "冷却时间内保持打开")
+
+	now = now.Add(time.Millisecond)
+	assert.True(t, b.Allow())
+	assert.Equal(t, StateHalfOpen, b.State())
+	assert.True(t, b.Allow())
+	assert.False(t, b.Allow(), "半开时限制探测请求数")
+
+	b.MarkSuccess()
+	assert.Equal(t, StateHalfOpen, b.State())
+	b.MarkSuccess()
+	assert.Equal(t, StateClosed, b.State())
+	assert.True(t, b.Allow())
+
+	assert.Equal(t, []string{"closed->open", "open->half-open", "half-open->closed"}, transitions)
+}
+
+func TestThreeState_ExponentialCoolDown(t *testing.T) {
+	now := time.Now()
+	b := newTestThreeState(&now, WithConsecutiveFailures(1), WithOpenTimeout(time.Second, time.Second*3))
+
+	b.MarkFailure()
+	for _, coolDown := range []time.Duration{time.Second * 2, time.Second * 3, time.Second * 3} {
+		now = now.Add(b.openTimeout)
+		require.True(t, b.Allow())
+		b.MarkFailure()
+		assert.Equal(t, coolDown, b.openTimeout)
+	}
+
+	// 关闭后冷却时间恢复初始值
+	now = now.Add(b.openTimeout)
+	require.True(t, b.Allow())
+	b.MarkSuccess()
+	assert.Equal(t, StateClosed, b.State())
+	assert.Equal(t, time.Second, b.openTimeout)
+}
+
+func TestThreeState_StuckProbeIsReplaced(t *testing.T) {
+	now := time.Now()
+	b := newTestThreeState(&now, WithConsecutiveFailures(1), WithOpenTimeout(time.Second, 0))
+
+	b.MarkFailure()
+	now = now.Add(time.Second)
+	assert.True(t, b.Allow())
+	assert.False(t, b.Allow())
+
+	// 探测请求一直没有结果时，超过冷却时间后允许新的探测
+	now = now.Add(time.Second)
+	assert.True(t, b.Allow())
+}
+
+func TestThreeState_StaleResultsAreDropped(t *testing.T) {
+	tests := []struct {
+		name    string
+		outcome Outcome
+	}{
+		{"关闭时放行的请求成功", OutcomeSuccess},
+		{"关闭时放行的请求失败", OutcomeFailure},
+		{"关闭时放行的请求不计入统计", OutcomeIgnore},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			b := newTestThreeState(&now, WithConsecutiveFailures(1), WithOpenTimeout(time.Second, time.Second*4))
+
+			slow, ok := b.allow()
+			require.True(t, ok)
+			fail, ok := b.allow()
+			require.True(t, ok)
+			fail(OutcomeFailure)
+			require.Equal(t, StateOpen, b.State())
+
+			now = now.Add(time.Second)
+			probe, ok := b.allow()
+			require.True(t, ok)
+			require.Equal(t, StateHalfOpen, b.State())
+
+			// 打开前放行的请求在半开时才返回，不作为探测结果
+			slow(tt.outcome)
+			assert.Equal(t, StateHalfOpen, b.State())
+			assert.Equal(t, time.Second, b.openTimeout)
+			_, ok = b.allow()
+			assert.False(t, ok, "探测名额仍被真正的探测请求占用")
+
+			probe(OutcomeSuccess)
+			assert.Equal(t, StateClosed, b.State())
+		})
+	}
+}
+
+func TestThreeState_ReplacedProbeResultIsDropped(t *testing.T) {
+	now := time.Now()
+	b := newTestThreeState(&now, WithConsecutiveFailures(1), WithOpenTimeout(time.Second, 0))
+
+	b.MarkFailure()
+	now = now.Add(time.Second)
+	stuck, ok := b.allow()
+	require.True(t, ok)
+	now = now.Add(time.Second)
+	probe, ok := b.allow()
+	require.True(t, ok)
+
+	// 被替换的探测请求最终返回时不影响新的探测
+	stuck(OutcomeFailure)
+	assert.Equal(t, StateHalfOpen, b.State())
+	probe(OutcomeSuccess)
+	assert.Equal(t, StateClosed, b.State())
+}
+
+func TestUnary_ThreeState(t *testing.T) {
+	var opened []string
+	interceptor := UnaryClientInterceptor(
+		WithThreeState(),
+		WithKeyFunc(MethodKey),
+		WithConsecutiveFailures(2),
+		WithOnStateChange(func(key string, from, to State) {
+			if to == StateOpen {
+				opened = append(opened, key)
+			}
+		}),
+	)
+	failMock := &mockInvoker{err: status.Error(codes.Unavailable, "unavailable")}
+
+	for i := 0; i < 5; i++ {
+		_ = interceptor(context.Background(), "/test/failing", nil, nil, nil, failMock.invoke)
+	}
+
+	assert.Equal(t, 2, failMock.callCount, "打开后不再调用")
+	assert.Equal(t, []string{"/test/failing"}, opened)
+	err := interceptor(context.Background(), "/test/healthy", nil, nil, nil, (&mockInvoker{}).invoke)
+	assert.NoError(t, err)
+}
+
+func TestNewKeyedCircuitBreaker_Selectable(t *testing.T) {
+	_, ok := defaultOptions().init().newCircuitBreaker().(*sreCircuitBreaker)
+	assert.True(t, ok)
+	_, ok = defaultOptions().apply(WithThreeState()).init().newCircuitBreaker().(*ThreeStateCircuitBreaker)
+	assert.True(t, ok)
+}
@@ -6,6 +6,7 @@ import (
 	"context"
 	"log/slog"
 
+	"github.com/soyacen/grpc-middleware/logging"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/status"
 )
@@ -28,6 +29,8 @@ func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
 		info *grpc.UnaryServerInfo,
 		handler grpc.UnaryHandler,
 	) (interface{}, error) {
+		// 在上下文中放入字段集合，供处理器添加业务字段
+		ctx, _ = logging.ContextWithFields(ctx)
 		// 执行原始处理器
 		resp, err := handler(ctx, req)
 		// 如果发生错误，记录错误日志
@@ -56,6 +59,8 @@ func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
 		info *grpc.StreamServerInfo,
 		handler grpc.StreamHandler,
 	) error {
+		// 在流的上下文中放入字段集合，供处理器添加业务字段
+		stream, _ = logging.ServerStreamWithFields(stream)
 		// 获取流的上下文
 		ctx := stream.Context()
 		// 执行原始处理器
@@ -155,16 +160,19 @@ func logError(ctx context.Context, rpcType string, system string, method string,
 		attrs = append(attrs, slog.String("error", err.Error()))
 	}
 
-	// 如果配置为打印请求，添加请求内容
+	// 添加处理器设置的业务字段
+	attrs = logging.FieldsFromContext(ctx).AppendAttrs(attrs)
+
+	// 如果配置为打印请求，添加脱敏后的请求内容
 	if opts.PrintRequest && req != nil {
-		attrs = append(attrs, slog.Any("request", req))
+		attrs = opts.payloadRenderer().AppendAttrs(attrs, "request", req)
 	}
 
-	// 如果配置为打印响应，添加响应内容
+	// 如果配置为打印响应，添加脱敏后的响应内容
 	if opts.PrintResponse && resp != nil {
-		attrs = append(attrs, slog.Any("response", resp))
+		attrs = opts.payloadRenderer().AppendAttrs(attrs, "response", resp)
 	}
 
 	// 记录错误日志
-	slog.LogAttrs(ctx, slog.LevelError, "gRPC call error", attrs...)
+	opts.getLogger().LogAttrs(ctx, slog.LevelError, "gRPC call error", attrs...)
 }
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/soyacen/grpc-middleware/logging"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
@@ -1095,3 +1096,64 @@ func TestLogError_NilError(t *testing.T) {
 	assert.Nil(t, handler.records[0]["error"])
 	assert.Equal(t, "OK", handler.records[0]["code"])
 }
+
+func TestUnaryServerInterceptor_WithLogger(t *testing.T) {
+	defaultHandler, cleanup := setupMockLogger()
+	defer cleanup()
+
+	handler := &mockLogHandler{records: make([]map[string]interface{}, 0)}
+	interceptor := UnaryServerInterceptor(WithLogger(slog.New(handler)))
+	mockHandler := &mockUnaryHandler{err: status.Error(codes.Internal, "boom")}
+
+	_, err := interceptor(context.Background(), "request", &grpc.UnaryServerInfo{FullMethod: "/test/Method"}, mockHandler.handle)
+	require.Error(t, err)
+
+	require.Len(t, handler.records, 1)
+	assert.Equal(t, "/test/Method", handler.records[0]["method"])
+	assert.Empty(t, defaultHandler.records)
+}
+
+func TestLogError_RedactsPayload(t *testing.T) {
+	handler, restore := setupMockLogger()
+	defer restore()
+
+	opts := &options{
+		PrintRequest:    true,
+		PayloadRenderer: logging.NewPayloadRenderer(logging.WithDenylist("password")),
+	}
+	req := map[string]string{"user": "alice", "password": "secret"}
+	logError(context.Background(), "unary", "server", "/test/Method", errors.New("error"), req, nil, opts)
+
+	require.Len(t, handler.records, 1)
+	assert.Equal(t, `{"password":"[REDACTED]","user":"alice"}`, handler.records[0]["request"])
+}
+
+func TestInterceptors_ContextFields(t *testing.T) {
+	t.Run("一元服务端记录处理器设置的字段", func(t *testing.T) {
+		handler := &mockLogHandler{records: make([]map[string]interface{}, 0)}
+		interceptor := UnaryServerInterceptor(WithLogger(slog.New(handler)))
+		unaryHandler := func(ctx context.Context, req any) (any, error) {
+			logging.FieldsFromContext(ctx).Set("order_id", "o-1")
+			return nil, status.Error(codes.Internal, "boom")
+		}
+
+		_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test/Method"}, unaryHandler)
+		require.Error(t, err)
+		require.Len(t, handler.records, 1)
+		assert.Equal(t, "o-1", handler.records[0]["order_id"])
+	})
+
+	t.Run("流式服务端记录处理器设置的字段", func(t *testing.T) {
+		handler := &mockLogHandler{records: make([]map[string]interface{}, 0)}
+		interceptor := StreamServerInterceptor(WithLogger(slog.New(handler)))
+		streamHandler := func(srv any, stream grpc.ServerStream) error {
+			logging.AddFields(stream.Context(), slog.String("user_id", "u-1"))
+			return status.Error(codes.Internal, "boom")
+		}
+
+		err := interceptor(nil, &mockServerStream{ctx: context.Background()}, &grpc.StreamServerInfo{FullMethod: "/test/Method"}, streamHandler)
+		require.Error(t, err)
+		require.Len(t, handler.records, 1)
+		assert.Equal(t, "u-1", handler.records[0]["user_id"])
+	})
+}
@@ -2,12 +2,25 @@
 // 用于记录发生错误的gRPC请求，支持配置是否打印请求和响应
 package errorlog
 
+import (
+	"log/slog"
+
+	"github.com/soyacen/grpc-middleware/logging"
+)
+
+// defaultPayloadRenderer 默认的消息渲染器
+var defaultPayloadRenderer = logging.NewPayloadRenderer()
+
 // options 存储错误日志的配置选项
 type options struct {
 	// PrintRequest 是否打印请求内容
 	PrintRequest bool
 	// PrintResponse 是否打印响应内容
 	PrintResponse bool
+	// Logger 日志记录器，为nil时使用slog.Default()
+	Logger *slog.Logger
+	// PayloadRenderer 请求和响应的渲染器，为nil时使用默认渲染器
+	PayloadRenderer *logging.PayloadRenderer
 }
 
 // apply 应用所有配置选项
@@ -55,3 +68,47 @@ func WithPrintResponse(enable bool) Option {
 		o.PrintResponse = enable
 	}
 }
+
+// WithLogger 设置错误日志使用的日志记录器
+// 未设置或设置为nil时使用slog.Default()
+//
+// 参数:
+//   - logger: 日志记录器
+//
+// 返回值:
+//   - Option: 配置选项函数
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.Logger = logger
+	}
+}
+
+// getLogger 返回配置的日志记录器，未配置时返回slog.Default()
+func (o *options) getLogger() *slog.Logger {
+	if o.Logger != nil {
+		return o.Logger
+	}
+	return slog.Default()
+}
+
+// WithPayloadRenderer 设置请求和响应的渲染器
+// 用于配置脱敏字段和字节上限，未设置时使用logging.NewPayloadRenderer()的默认配置
+//
+// 参数:
+//   - renderer: 消息渲染器
+//
+// 返回值:
+//   - Option: 配置选项函数
+func WithPayloadRenderer(renderer *logging.PayloadRenderer) Option {
+	return func(o *options) {
+		o.PayloadRenderer = renderer
+	}
+}
+
+// payloadRenderer 返回配置的消息渲染器，未配置时返回默认渲染器
+func (o *options) payloadRenderer() *logging.PayloadRenderer {
+	if o.PayloadRenderer != nil {
+		return o.PayloadRenderer
+	}
+	return defaultPayloadRenderer
+}
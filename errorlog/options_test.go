@@ -1,8 +1,11 @@
 package errorlog
 
 import (
+	"io"
+	"log/slog"
 	"testing"
 
+	"github.com/soyacen/grpc-middleware/logging"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -280,3 +283,43 @@ func BenchmarkWithPrintResponse(b *testing.B) {
 		_ = o
 	}
 }
+
+func TestWithLogger(t *testing.T) {
+	custom := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tests := []struct {
+		name   string
+		logger *slog.Logger
+		want   *slog.Logger
+	}{
+		{"custom_logger", custom, custom},
+		{"nil_logger_falls_back_to_default", nil, slog.Default()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := defaultOptions()
+			o.apply(WithLogger(tt.logger))
+			assert.Same(t, tt.want, o.getLogger())
+		})
+	}
+}
+
+func TestWithPayloadRenderer(t *testing.T) {
+	custom := logging.NewPayloadRenderer(logging.WithMaxBytes(1))
+	tests := []struct {
+		name     string
+		renderer *logging.PayloadRenderer
+		want     *logging.PayloadRenderer
+	}{
+		{"custom_renderer", custom, custom},
+		{"nil_renderer_falls_back_to_default", nil, defaultPayloadRenderer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := defaultOptions()
+			o.apply(WithPayloadRenderer(tt.renderer))
+			assert.Same(t, tt.want, o.payloadRenderer())
+		})
+	}
+}
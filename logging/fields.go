@@ -0,0 +1,161 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+// Fields 可并发修改的日志字段集合
+// 日志拦截器在调用处理器前将其放入上下文，处理器通过FieldsFromContext取出并添加业务字段，
+// accesslog、errorlog和slowlog记录日志时会追加这些字段。
+// nil的*Fields上的方法均为空操作，因此上下文中没有字段集合时也可以安全调用
+type Fields struct {
+	mu    sync.Mutex
+	attrs []slog.Attr
+}
+
+// fieldsKey 字段集合在上下文中的键
+type fieldsKey struct{}
+
+// Set 设置字段，同名字段被覆盖
+//
+// 参数:
+//   - key: 字段名
+//   - value: 字段值
+func (f *Fields) Set(key string, value any) {
+	f.Add(slog.Any(key, value))
+}
+
+// Add 添加字段，同名字段被覆盖
+//
+// 参数:
+//   - attrs: 日志字段
+func (f *Fields) Add(attrs ...slog.Attr) {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, attr := range attrs {
+		if i := f.index(attr.Key); i >= 0 {
+			f.attrs[i] = attr
+			continue
+		}
+		f.attrs = append(f.attrs, attr)
+	}
+}
+
+// Delete 删除字段
+//
+// 参数:
+//   - key: 字段名
+func (f *Fields) Delete(key string) {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if i := f.index(key); i >= 0 {
+		f.attrs = append(f.attrs[:i], f.attrs[i+1:]...)
+	}
+}
+
+// Attrs 返回所有字段的副本，按首次添加的顺序排列
+func (f *Fields) Attrs() []slog.Attr {
+	return f.AppendAttrs(nil)
+}
+
+// AppendAttrs 将所有字段追加到dst中
+//
+// 参数:
+//   - dst: 已有的日志字段
+//
+// 返回值:
+//   - []slog.Attr: 追加后的日志字段
+func (f *Fields) AppendAttrs(dst []slog.Attr) []slog.Attr {
+	if f == nil {
+		return dst
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append(dst, f.attrs...)
+}
+
+// index 返回字段的位置，不存在时返回-1，调用方需持有锁
+func (f *Fields) index(key string) int {
+	for i, attr := range f.attrs {
+		if attr.Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// ContextWithFields 返回带有字段集合的上下文
+// 上下文中已有字段集合时原样返回，使多个日志拦截器共享同一个集合
+//
+// 参数:
+//   - ctx: 请求上下文
+//
+// 返回值:
+//   - context.Context: 带有字段集合的上下文
+//   - *Fields: 字段集合
+func ContextWithFields(ctx context.Context) (context.Context, *Fields) {
+	if fields := FieldsFromContext(ctx); fields != nil {
+		return ctx, fields
+	}
+	fields := &Fields{}
+	return context.WithValue(ctx, fieldsKey{}, fields), fields
+}
+
+// FieldsFromContext 从上下文中获取字段集合
+//
+// 参数:
+//   - ctx: 请求上下文
+//
+// 返回值:
+//   - *Fields: 字段集合，上下文中没有时返回nil
+func FieldsFromContext(ctx context.Context) *Fields {
+	fields, _ := ctx.Value(fieldsKey{}).(*Fields)
+	return fields
+}
+
+// AddFields 向上下文中的字段集合添加字段，上下文中没有字段集合时忽略
+//
+// 参数:
+//   - ctx: 请求上下文
+//   - attrs: 日志字段
+func AddFields(ctx context.Context, attrs ...slog.Attr) {
+	FieldsFromContext(ctx).Add(attrs...)
+}
+
+// fieldsServerStream 替换上下文的服务端流
+type fieldsServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context 返回带有字段集合的上下文
+func (s *fieldsServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// ServerStreamWithFields 确保服务端流的上下文带有字段集合
+// 流的上下文中已有字段集合时原样返回流
+//
+// 参数:
+//   - stream: 服务端流
+//
+// 返回值:
+//   - grpc.ServerStream: 上下文带有字段集合的流
+//   - *Fields: 字段集合
+func ServerStreamWithFields(stream grpc.ServerStream) (grpc.ServerStream, *Fields) {
+	if fields := FieldsFromContext(stream.Context()); fields != nil {
+		return stream, fields
+	}
+	ctx, fields := ContextWithFields(stream.Context())
+	return &fieldsServerStream{ServerStream: stream, ctx: ctx}, fields
+}
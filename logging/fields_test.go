@@ -0,0 +1,112 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func TestFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		apply func(f *Fields)
+		want  []slog.Attr
+	}{
+		{
+			name:  "按添加顺序排列",
+			apply: func(f *Fields) { f.Set("order_id", "o-1"); f.Set("user_id", "u-1") },
+			want:  []slog.Attr{slog.Any("order_id", "o-1"), slog.Any("user_id", "u-1")},
+		},
+		{
+			name:  "同名字段被覆盖",
+			apply: func(f *Fields) { f.Set("order_id", "o-1"); f.Add(slog.String("order_id", "o-2")) },
+			want:  []slog.Attr{slog.String("order_id", "o-2")},
+		},
+		{
+			name:  "删除字段",
+			apply: func(f *Fields) { f.Set("a", 1); f.Set("b", 2); f.Delete("a"); f.Delete("missing") },
+			want:  []slog.Attr{slog.Any("b", 2)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &Fields{}
+			tt.apply(f)
+			assert.Equal(t, tt.want, f.Attrs())
+		})
+	}
+}
+
+func TestFields_Nil(t *testing.T) {
+	var f *Fields
+	assert.NotPanics(t, func() {
+		f.Set("a", 1)
+		f.Add(slog.Int("b", 2))
+		f.Delete("a")
+	})
+	assert.Nil(t, f.Attrs())
+	dst := []slog.Attr{slog.Int("x", 1)}
+	assert.Equal(t, dst, f.AppendAttrs(dst))
+	// 上下文中没有字段集合时忽略
+	assert.NotPanics(t, func() { AddFields(context.Background(), slog.Int("a", 1)) })
+}
+
+func TestFields_Concurrent(t *testing.T) {
+	f := &Fields{}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				f.Set("shared", j)
+				f.Add(slog.Int("k"+string(rune('a'+i)), j))
+				_ = f.Attrs()
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Len(t, f.Attrs(), 11)
+}
+
+func TestContextWithFields(t *testing.T) {
+	assert.Nil(t, FieldsFromContext(context.Background()))
+
+	ctx, fields := ContextWithFields(context.Background())
+	require.NotNil(t, fields)
+	assert.Same(t, fields, FieldsFromContext(ctx))
+
+	// 已有字段集合时复用
+	ctx2, fields2 := ContextWithFields(ctx)
+	assert.Equal(t, ctx, ctx2)
+	assert.Same(t, fields, fields2)
+
+	AddFields(ctx, slog.String("order_id", "o-1"))
+	assert.Equal(t, []slog.Attr{slog.String("order_id", "o-1")}, fields.Attrs())
+}
+
+type testServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *testServerStream) Context() context.Context { return s.ctx }
+
+func TestServerStreamWithFields(t *testing.T) {
+	stream := &testServerStream{ctx: context.Background()}
+	wrapped, fields := ServerStreamWithFields(stream)
+	require.NotNil(t, fields)
+	assert.NotSame(t, stream, wrapped)
+	assert.Same(t, fields, FieldsFromContext(wrapped.Context()))
+
+	// 已有字段集合时原样返回流
+	again, fields2 := ServerStreamWithFields(wrapped)
+	assert.Same(t, wrapped, again)
+	assert.Same(t, fields, fields2)
+}
@@ -0,0 +1,162 @@
+// Package logging 提供日志类中间件共享的日志适配功能
+// 用于将logrus、zap等第三方日志库接入accesslog、errorlog、slowlog和recovery
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// Logger 定义最小化的日志接口
+// 第三方日志库只需实现该接口，即可通过NewHandler接入各日志中间件
+type Logger interface {
+	// Log 记录一条日志
+	//
+	// 参数:
+	//   - ctx: 请求上下文
+	//   - level: 日志级别
+	//   - msg: 日志消息
+	//   - fields: 日志字段，分组字段的键以"."连接
+	Log(ctx context.Context, level slog.Level, msg string, fields map[string]any)
+}
+
+// LoggerFunc 是Logger接口的函数适配器
+type LoggerFunc func(ctx context.Context, level slog.Level, msg string, fields map[string]any)
+
+// Log 实现Logger接口
+func (f LoggerFunc) Log(ctx context.Context, level slog.Level, msg string, fields map[string]any) {
+	f(ctx, level, msg, fields)
+}
+
+// Printer 定义格式化输出接口
+// 标准库的*log.Logger以及logrus等日志库均实现了该接口
+type Printer interface {
+	Printf(format string, args ...any)
+}
+
+// NewHandler 将Logger适配为slog.Handler
+// 返回的Handler可通过slog.New创建*slog.Logger，并传给各日志中间件的WithLogger选项
+//
+// 参数:
+//   - logger: 第三方日志适配实现
+//   - level: 最低日志级别，低于该级别的日志被丢弃
+//
+// 返回值:
+//   - slog.Handler: slog日志处理器
+func NewHandler(logger Logger, level slog.Leveler) slog.Handler {
+	if level == nil {
+		level = slog.LevelInfo
+	}
+	return &handler{logger: logger, level: level}
+}
+
+// NewPrinterHandler 将Printer适配为slog.Handler
+// 每条日志输出为一行: "LEVEL msg key=value ..."，字段按键名排序
+//
+// 参数:
+//   - printer: 格式化输出实现
+//   - level: 最低日志级别，低于该级别的日志被丢弃
+//
+// 返回值:
+//   - slog.Handler: slog日志处理器
+func NewPrinterHandler(printer Printer, level slog.Leveler) slog.Handler {
+	return NewHandler(LoggerFunc(func(ctx context.Context, lvl slog.Level, msg string, fields map[string]any) {
+		var b strings.Builder
+		b.WriteString(lvl.String())
+		b.WriteByte(' ')
+		b.WriteString(msg)
+		for _, key := range sortedKeys(fields) {
+			fmt.Fprintf(&b, " %s=%v", key, fields[key])
+		}
+		printer.Printf("%s", b.String())
+	}), level)
+}
+
+// handler 是基于Logger的slog.Handler实现
+type handler struct {
+	logger Logger
+	level  slog.Leveler
+	// preset 通过WithAttrs预设并已展开的字段
+	preset map[string]any
+	// prefix 通过WithGroup设置的分组前缀
+	prefix string
+}
+
+// Enabled 判断指定级别的日志是否需要记录
+func (h *handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle 将slog记录转换为字段映射并交给Logger处理
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	fields := make(map[string]any, len(h.preset)+r.NumAttrs())
+	for k, v := range h.preset {
+		fields[k] = v
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addField(fields, h.prefix, a)
+		return true
+	})
+	h.logger.Log(ctx, r.Level, r.Message, fields)
+	return nil
+}
+
+// WithAttrs 返回预设了字段的Handler
+// 预设字段在此时按当前分组前缀展开
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.preset = make(map[string]any, len(h.preset)+len(attrs))
+	for k, v := range h.preset {
+		h2.preset[k] = v
+	}
+	for _, a := range attrs {
+		addField(h2.preset, h.prefix, a)
+	}
+	return &h2
+}
+
+// WithGroup 返回设置了分组前缀的Handler
+func (h *handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+// addField 将slog字段展开到字段映射中，分组字段的键以"."连接
+func addField(fields map[string]any, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		groupPrefix := prefix
+		// 空键的分组直接内联
+		if a.Key != "" {
+			groupPrefix = prefix + a.Key + "."
+		}
+		for _, ga := range v.Group() {
+			addField(fields, groupPrefix, ga)
+		}
+		return
+	}
+	if a.Key == "" {
+		return
+	}
+	fields[prefix+a.Key] = v.Any()
+}
+
+// sortedKeys 返回按字典序排序的键
+func sortedKeys(fields map[string]any) []string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
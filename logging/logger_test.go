@@ -0,0 +1,119 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type logEntry struct {
+	level  slog.Level
+	msg    string
+	fields map[string]any
+}
+
+type mockLogger struct {
+	entries []logEntry
+}
+
+func (m *mockLogger) Log(ctx context.Context, level slog.Level, msg string, fields map[string]any) {
+	m.entries = append(m.entries, logEntry{level: level, msg: msg, fields: fields})
+}
+
+func TestNewHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		level      slog.Leveler
+		log        func(l *slog.Logger)
+		wantCount  int
+		wantMsg    string
+		wantFields map[string]any
+	}{
+		{
+			name:       "普通字段",
+			level:      nil,
+			log:        func(l *slog.Logger) { l.Info("hello", slog.String("k", "v"), slog.Int("n", 1)) },
+			wantCount:  1,
+			wantMsg:    "hello",
+			wantFields: map[string]any{"k": "v", "n": int64(1)},
+		},
+		{
+			name:      "低于级别的日志被丢弃",
+			level:     slog.LevelWarn,
+			log:       func(l *slog.Logger) { l.Info("hello") },
+			wantCount: 0,
+		},
+		{
+			name:       "分组字段展开",
+			level:      slog.LevelDebug,
+			log:        func(l *slog.Logger) { l.Debug("grouped", slog.Group("req", slog.String("id", "1"))) },
+			wantCount:  1,
+			wantMsg:    "grouped",
+			wantFields: map[string]any{"req.id": "1"},
+		},
+		{
+			name:       "预设字段与分组前缀",
+			level:      nil,
+			log:        func(l *slog.Logger) { l.With("svc", "a").WithGroup("g").With("x", 1).Info("m", "y", 2) },
+			wantCount:  1,
+			wantMsg:    "m",
+			wantFields: map[string]any{"svc": "a", "g.x": int64(1), "g.y": int64(2)},
+		},
+		{
+			name:       "空键分组内联",
+			level:      nil,
+			log:        func(l *slog.Logger) { l.Info("m", slog.Group("", slog.String("a", "b"))) },
+			wantCount:  1,
+			wantMsg:    "m",
+			wantFields: map[string]any{"a": "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ml := &mockLogger{}
+			tt.log(slog.New(NewHandler(ml, tt.level)))
+
+			require.Len(t, ml.entries, tt.wantCount)
+			if tt.wantCount == 0 {
+				return
+			}
+			assert.Equal(t, tt.wantMsg, ml.entries[0].msg)
+			assert.Equal(t, tt.wantFields, ml.entries[0].fields)
+		})
+	}
+}
+
+func TestNewHandler_WithAttrsDoesNotMutateParent(t *testing.T) {
+	ml := &mockLogger{}
+	parent := slog.New(NewHandler(ml, nil)).With("a", 1)
+	_ = parent.With("b", 2)
+	parent.Info("m")
+
+	require.Len(t, ml.entries, 1)
+	assert.Equal(t, map[string]any{"a": int64(1)}, ml.entries[0].fields)
+}
+
+func TestLoggerFunc(t *testing.T) {
+	var got string
+	f := LoggerFunc(func(ctx context.Context, level slog.Level, msg string, fields map[string]any) {
+		got = fmt.Sprintf("%s %s %v", level, msg, fields["k"])
+	})
+	slog.New(NewHandler(f, nil)).Warn("msg", "k", "v")
+	assert.Equal(t, "WARN msg v", got)
+}
+
+func TestNewPrinterHandler(t *testing.T) {
+	buf := &bytes.Buffer{}
+	printer := log.New(buf, "", 0)
+
+	slog.New(NewPrinterHandler(printer, nil)).Error("failed", "b", 2, "a", "x")
+
+	assert.Equal(t, "ERROR failed a=x b=2\n", buf.String())
+}
@@ -0,0 +1,318 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	// RedactedValue 被脱敏字段的替换值
+	RedactedValue = "[REDACTED]"
+
+	// defaultMaxPayloadBytes 默认的消息渲染字节上限
+	defaultMaxPayloadBytes = 4096
+)
+
+// PayloadRenderer 将请求和响应消息渲染为可安全写入日志的字符串
+// protobuf消息使用protojson序列化，并对标记了debug_redact选项的字段以及拒绝列表中的字段脱敏，
+// 超过字节上限的内容会被截断并标记
+type PayloadRenderer struct {
+	// denylist 需要脱敏的字段路径集合，如"user.password"
+	denylist map[string]struct{}
+	// maxBytes 渲染结果的字节上限，小于等于0表示不限制
+	maxBytes int
+}
+
+// PayloadOption 定义用于配置PayloadRenderer的函数类型
+type PayloadOption func(r *PayloadRenderer)
+
+// WithDenylist 设置需要脱敏的字段路径
+// 路径由protobuf字段名（非JSON名）以"."连接，重复字段和map的值不占用路径段，
+// 例如"user.password"匹配顶层user字段中的password字段；
+// Any内嵌消息的字段与Any字段处于同一层级，Struct的键同样作为路径段
+//
+// 参数:
+//   - paths: 字段路径列表
+//
+// 返回值:
+//   - PayloadOption: 配置选项函数
+func WithDenylist(paths ...string) PayloadOption {
+	return func(r *PayloadRenderer) {
+		for _, path := range paths {
+			r.denylist[path] = struct{}{}
+		}
+	}
+}
+
+// WithMaxBytes 设置渲染结果的字节上限
+// 小于等于0表示不限制
+//
+// 参数:
+//   - n: 字节上限
+//
+// 返回值:
+//   - PayloadOption: 配置选项函数
+func WithMaxBytes(n int) PayloadOption {
+	return func(r *PayloadRenderer) {
+		r.maxBytes = n
+	}
+}
+
+// NewPayloadRenderer 创建消息渲染器
+// 默认字节上限为4096，不设置拒绝列表
+//
+// 参数:
+//   - opts: 可选的配置选项
+//
+// 返回值:
+//   - *PayloadRenderer: 消息渲染器
+func NewPayloadRenderer(opts ...PayloadOption) *PayloadRenderer {
+	r := &PayloadRenderer{
+		denylist: make(map[string]struct{}),
+		maxBytes: defaultMaxPayloadBytes,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Render 渲染消息
+//
+// 参数:
+//   - v: 待渲染的消息
+//
+// 返回值:
+//   - string: 渲染结果
+//   - bool: 结果是否被截断
+func (r *PayloadRenderer) Render(v any) (string, bool) {
+	var b []byte
+	switch m := v.(type) {
+	case proto.Message:
+		b = r.renderProto(m)
+	case string:
+		b = []byte(m)
+	case []byte:
+		b = m
+	default:
+		b = r.renderValue(v)
+	}
+	return r.truncate(b)
+}
+
+// AppendAttrs 渲染消息并追加到日志字段中
+// 结果被截断时额外追加"<key>_truncated"字段
+//
+// 参数:
+//   - attrs: 已有的日志字段
+//   - key: 字段名
+//   - v: 待渲染的消息
+//
+// 返回值:
+//   - []slog.Attr: 追加后的日志字段
+func (r *PayloadRenderer) AppendAttrs(attrs []slog.Attr, key string, v any) []slog.Attr {
+	payload, truncated := r.Render(v)
+	attrs = append(attrs, slog.String(key, payload))
+	if truncated {
+		attrs = append(attrs, slog.Bool(key+"_truncated", true))
+	}
+	return attrs
+}
+
+// renderProto 使用protojson渲染protobuf消息并脱敏
+func (r *PayloadRenderer) renderProto(m proto.Message) []byte {
+	b, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(m)
+	if err != nil {
+		return []byte(fmt.Sprintf("<unrenderable %T: %v>", m, err))
+	}
+	obj, ok := decodeJSON(b)
+	if !ok {
+		return b
+	}
+	// 未脱敏任何字段时保留protojson的字段顺序
+	if !r.redactValue(m.ProtoReflect().Descriptor(), obj, "") {
+		return b
+	}
+	out, err := json.Marshal(obj)
+	if err != nil {
+		return []byte(fmt.Sprintf("<unrenderable %T: %v>", m, err))
+	}
+	return out
+}
+
+// renderValue 使用encoding/json渲染非protobuf消息，并按拒绝列表脱敏
+func (r *PayloadRenderer) renderValue(v any) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return []byte(fmt.Sprintf("<unrenderable %T>", v))
+	}
+	if len(r.denylist) == 0 {
+		return b
+	}
+	obj, ok := decodeJSON(b)
+	if !ok || !r.redactPaths(obj, "") {
+		return b
+	}
+	out, err := json.Marshal(obj)
+	if err != nil {
+		return b
+	}
+	return out
+}
+
+// redactMessage 按消息描述对JSON对象脱敏
+//
+// 参数:
+//   - md: 消息描述
+//   - v: 消息对应的JSON值
+//   - prefix: 当前字段路径前缀
+//
+// 返回值:
+//   - bool: 是否有字段被脱敏
+func (r *PayloadRenderer) redactMessage(md protoreflect.MessageDescriptor, v any, prefix string) bool {
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return false
+	}
+	changed := false
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		name := string(fd.Name())
+		val, ok := obj[name]
+		if !ok {
+			continue
+		}
+		path := prefix + name
+		if r.isRedacted(fd, path) {
+			obj[name] = RedactedValue
+			changed = true
+			continue
+		}
+		switch {
+		case fd.IsMap():
+			entries, _ := val.(map[string]any)
+			for _, ev := range entries {
+				changed = r.redactValue(fd.MapValue().Message(), ev, path+".") || changed
+			}
+		case fd.IsList():
+			elems, _ := val.([]any)
+			for _, ev := range elems {
+				changed = r.redactValue(fd.Message(), ev, path+".") || changed
+			}
+		default:
+			changed = r.redactValue(fd.Message(), val, path+".") || changed
+		}
+	}
+	return changed
+}
+
+// redactValue 按消息类型对JSON值脱敏
+// google.protobuf包下的知名类型有特殊的JSON映射：Any按类型URL解析后对内嵌消息脱敏，
+// Struct、Value和ListValue按拒绝列表匹配对象的键，其余知名类型不参与脱敏
+//
+// 参数:
+//   - md: 消息描述，字段不是消息类型时为nil
+//   - v: 消息对应的JSON值
+//   - prefix: 当前字段路径前缀
+//
+// 返回值:
+//   - bool: 是否有字段被脱敏
+func (r *PayloadRenderer) redactValue(md protoreflect.MessageDescriptor, v any, prefix string) bool {
+	if md == nil {
+		return false
+	}
+	switch md.FullName() {
+	case "google.protobuf.Any":
+		return r.redactAny(v, prefix)
+	case "google.protobuf.Struct", "google.protobuf.Value", "google.protobuf.ListValue":
+		return r.redactPaths(v, prefix)
+	}
+	if strings.HasPrefix(string(md.FullName()), "google.protobuf.") {
+		return false
+	}
+	return r.redactMessage(md, v, prefix)
+}
+
+// redactAny 对Any中的内嵌消息脱敏，内嵌消息的字段路径与Any字段处于同一层级
+// 内嵌消息为知名类型时，其JSON表示位于"value"键中
+func (r *PayloadRenderer) redactAny(v any, prefix string) bool {
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return false
+	}
+	url, _ := obj["@type"].(string)
+	mt, err := protoregistry.GlobalTypes.FindMessageByURL(url)
+	if err != nil {
+		return false
+	}
+	md := mt.Descriptor()
+	if strings.HasPrefix(string(md.FullName()), "google.protobuf.") {
+		return r.redactValue(md, obj["value"], prefix)
+	}
+	return r.redactMessage(md, obj, prefix)
+}
+
+// redactPaths 按拒绝列表对任意JSON值脱敏
+func (r *PayloadRenderer) redactPaths(v any, prefix string) bool {
+	changed := false
+	switch t := v.(type) {
+	case map[string]any:
+		for k, val := range t {
+			path := prefix + k
+			if _, ok := r.denylist[path]; ok {
+				t[k] = RedactedValue
+				changed = true
+				continue
+			}
+			changed = r.redactPaths(val, path+".") || changed
+		}
+	case []any:
+		for _, val := range t {
+			changed = r.redactPaths(val, prefix) || changed
+		}
+	}
+	return changed
+}
+
+// isRedacted 判断字段是否需要脱敏
+func (r *PayloadRenderer) isRedacted(fd protoreflect.FieldDescriptor, path string) bool {
+	if _, ok := r.denylist[path]; ok {
+		return true
+	}
+	opts, ok := fd.Options().(*descriptorpb.FieldOptions)
+	return ok && opts.GetDebugRedact()
+}
+
+// truncate 将渲染结果截断到字节上限，截断位置不会拆分UTF-8字符
+func (r *PayloadRenderer) truncate(b []byte) (string, bool) {
+	if r.maxBytes <= 0 || len(b) <= r.maxBytes {
+		return string(b), false
+	}
+	n := r.maxBytes
+	for n > 0 && !utf8.RuneStart(b[n]) {
+		n--
+	}
+	return string(b[:n]), true
+}
+
+// decodeJSON 解码JSON，数字保留原始精度
+func decodeJSON(b []byte) (any, bool) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, false
+	}
+	return v, true
+}
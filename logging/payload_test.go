@@ -0,0 +1,328 @@
+package logging
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// newTestMessages 构造测试用的动态消息描述
+// message User { string name = 1; string password = 2 [debug_redact = true]; string email = 3; }
+// message Request { User user = 1; repeated User members = 2; map<string, User> by_id = 3; string token = 4; }
+func newTestMessages(t *testing.T) (user, request protoreflect.MessageDescriptor) {
+	t.Helper()
+	str := descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()
+	msg := descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	repeated := descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("logging_test.proto"),
+		Package: proto.String("loggingtest"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("User"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{Name: proto.String("name"), Number: proto.Int32(1), Type: str, Label: optional, JsonName: proto.String("name")},
+					{Name: proto.String("password"), Number: proto.Int32(2), Type: str, Label: optional, JsonName: proto.String("password"),
+						Options: &descriptorpb.FieldOptions{DebugRedact: proto.Bool(true)}},
+					{Name: proto.String("email"), Number: proto.Int32(3), Type: str, Label: optional, JsonName: proto.String("email")},
+				},
+			},
+			{
+				Name: proto.String("Request"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{Name: proto.String("user"), Number: proto.Int32(1), Type: msg, Label: optional, TypeName: proto.String(".loggingtest.User"), JsonName: proto.String("user")},
+					{Name: proto.String("members"), Number: proto.Int32(2), Type: msg, Label: repeated, TypeName: proto.String(".loggingtest.User"), JsonName: proto.String("members")},
+					{Name: proto.String("by_id"), Number: proto.Int32(3), Type: msg, Label: repeated, TypeName: proto.String(".loggingtest.Request.ByIdEntry"), JsonName: proto.String("byId")},
+					{Name: proto.String("token"), Number: proto.Int32(4), Type: str, Label: optional, JsonName: proto.String("token")},
+				},
+				NestedType: []*descriptorpb.DescriptorProto{
+					{
+						Name: proto.String("ByIdEntry"),
+						Field: []*descriptorpb.FieldDescriptorProto{
+							{Name: proto.String("key"), Number: proto.Int32(1), Type: str, Label: optional, JsonName: proto.String("key")},
+							{Name: proto.String("value"), Number: proto.Int32(2), Type: msg, Label: optional, TypeName: proto.String(".loggingtest.User"), JsonName: proto.String("value")},
+						},
+						Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+					},
+				},
+			},
+		},
+	}
+	fd, err := protodesc.NewFile(fdp, nil)
+	require.NoError(t, err)
+	return fd.Messages().ByName("User"), fd.Messages().ByName("Request")
+}
+
+// newTestRequest 构造包含敏感字段的请求消息
+func newTestRequest(t *testing.T) proto.Message {
+	t.Helper()
+	userMD, reqMD := newTestMessages(t)
+	newUser := func(name string) protoreflect.Message {
+		u := dynamicpb.NewMessage(userMD)
+		u.Set(userMD.Fields().ByName("name"), protoreflect.ValueOfString(name))
+		u.Set(userMD.Fields().ByName("password"), protoreflect.ValueOfString("secret-"+name))
+		u.Set(userMD.Fields().ByName("email"), protoreflect.ValueOfString(name+"@example.com"))
+		return u
+	}
+
+	req := dynamicpb.NewMessage(reqMD)
+	req.Set(reqMD.Fields().ByName("user"), protoreflect.ValueOfMessage(newUser("alice")))
+	members := req.Mutable(reqMD.Fields().ByName("members")).List()
+	members.Append(protoreflect.ValueOfMessage(newUser("bob")))
+	byID := req.Mutable(reqMD.Fields().ByName("by_id")).Map()
+	byID.Set(protoreflect.ValueOfString("c").MapKey(), protoreflect.ValueOfMessage(newUser("carol")))
+	req.Set(reqMD.Fields().ByName("token"), protoreflect.ValueOfString("tok"))
+	return req
+}
+
+func TestPayloadRenderer_Proto(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        []PayloadOption
+		wantContain []string
+		wantAbsent  []string
+	}{
+		{
+			name:        "debug_redact字段被脱敏",
+			opts:        nil,
+			wantContain: []string{`"name":"alice"`, `"name":"bob"`, `"name":"carol"`, `"password":"[REDACTED]"`, `"token":"tok"`},
+			wantAbsent:  []string{"secret-alice", "secret-bob", "secret-carol"},
+		},
+		{
+			name:        "拒绝列表字段被脱敏",
+			opts:        []PayloadOption{WithDenylist("token", "user.email")},
+			wantContain: []string{`"token":"[REDACTED]"`, `"email":"bob@example.com"`},
+			wantAbsent:  []string{`"tok"`, "alice@example.com", "secret-alice"},
+		},
+		{
+			name:        "拒绝列表匹配重复字段和map的值",
+			opts:        []PayloadOption{WithDenylist("members.email", "by_id.email")},
+			wantContain: []string{`"email":"alice@example.com"`},
+			wantAbsent:  []string{"bob@example.com", "carol@example.com"},
+		},
+		{
+			name:        "拒绝列表脱敏整个消息字段",
+			opts:        []PayloadOption{WithDenylist("user")},
+			wantContain: []string{`"user":"[REDACTED]"`},
+			wantAbsent:  []string{"alice"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewPayloadRenderer(tt.opts...)
+			got, truncated := r.Render(newTestRequest(t))
+
+			assert.False(t, truncated)
+			assert.True(t, json.Valid([]byte(got)), got)
+			for _, s := range tt.wantContain {
+				assert.Contains(t, got, s)
+			}
+			for _, s := range tt.wantAbsent {
+				assert.NotContains(t, got, s)
+			}
+		})
+	}
+}
+
+func TestPayloadRenderer_WellKnownType(t *testing.T) {
+	r := NewPayloadRenderer()
+	got, truncated := r.Render(timestamppb.New(timestamppb.Now().AsTime()))
+	assert.False(t, truncated)
+	assert.True(t, strings.HasPrefix(got, `"`), "Timestamp应渲染为RFC3339字符串: %s", got)
+}
+
+// secretType 注册到全局类型表的测试消息，Any按类型URL解析内嵌消息时使用
+// message Secret { string token = 1 [debug_redact = true]; string note = 2; }
+var secretType = sync.OnceValue(func() protoreflect.MessageType {
+	str := descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("logging_any_test.proto"),
+		Package: proto.String("loggingtest.any"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Secret"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("token"), Number: proto.Int32(1), Type: str, Label: optional, JsonName: proto.String("token"),
+					Options: &descriptorpb.FieldOptions{DebugRedact: proto.Bool(true)}},
+				{Name: proto.String("note"), Number: proto.Int32(2), Type: str, Label: optional, JsonName: proto.String("note")},
+			},
+		}},
+	}
+	fd, err := protodesc.NewFile(fdp, nil)
+	if err != nil {
+		panic(err)
+	}
+	mt := dynamicpb.NewMessageType(fd.Messages().ByName("Secret"))
+	if err := protoregistry.GlobalTypes.RegisterMessage(mt); err != nil {
+		panic(err)
+	}
+	return mt
+})
+
+func TestPayloadRenderer_Any(t *testing.T) {
+	secret := secretType().New()
+	fields := secret.Descriptor().Fields()
+	secret.Set(fields.ByName("token"), protoreflect.ValueOfString("s3cr3t"))
+	secret.Set(fields.ByName("note"), protoreflect.ValueOfString("hello"))
+	packed, err := anypb.New(secret.Interface())
+	require.NoError(t, err)
+	meta, err := structpb.NewStruct(map[string]any{"password": "hunter2"})
+	require.NoError(t, err)
+	packedStruct, err := anypb.New(meta)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		msg     proto.Message
+		opts    []PayloadOption
+		want    []string
+		notWant []string
+	}{
+		{"Any内嵌消息按debug_redact脱敏", packed, nil, []string{RedactedValue, "hello"}, []string{"s3cr3t"}},
+		{"Any内嵌消息按拒绝列表脱敏", packed, []PayloadOption{WithDenylist("note")}, []string{RedactedValue}, []string{"s3cr3t", "hello"}},
+		{"Any内嵌Struct按拒绝列表脱敏", packedStruct, []PayloadOption{WithDenylist("password")}, []string{RedactedValue}, []string{"hunter2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := NewPayloadRenderer(tt.opts...).Render(tt.msg)
+			for _, s := range tt.want {
+				assert.Contains(t, got, s)
+			}
+			for _, s := range tt.notWant {
+				assert.NotContains(t, got, s)
+			}
+		})
+	}
+}
+
+func TestPayloadRenderer_Struct(t *testing.T) {
+	meta, err := structpb.NewStruct(map[string]any{
+		"user":  map[string]any{"name": "alice", "password": "hunter2"},
+		"items": []any{map[string]any{"password": "nested"}},
+	})
+	require.NoError(t, err)
+
+	got, _ := NewPayloadRenderer(WithDenylist("user.password", "items.password")).Render(meta)
+	assert.Contains(t, got, "alice")
+	assert.NotContains(t, got, "hunter2")
+	assert.NotContains(t, got, "nested")
+}
+
+func TestPayloadRenderer_NonProto(t *testing.T) {
+	type credentials struct {
+		User     string `json:"user"`
+		Password string `json:"password"`
+	}
+
+	tests := []struct {
+		name string
+		opts []PayloadOption
+		v    any
+		want string
+	}{
+		{
+			name: "字符串原样输出",
+			v:    "plain request",
+			want: "plain request",
+		},
+		{
+			name: "结构体使用JSON渲染",
+			v:    credentials{User: "u", Password: "p"},
+			want: `{"user":"u","password":"p"}`,
+		},
+		{
+			name: "结构体按拒绝列表脱敏",
+			opts: []PayloadOption{WithDenylist("password")},
+			v:    credentials{User: "u", Password: "p"},
+			want: `{"password":"[REDACTED]","user":"u"}`,
+		},
+		{
+			name: "嵌套map按拒绝列表脱敏",
+			opts: []PayloadOption{WithDenylist("auth.key")},
+			v:    map[string]any{"auth": []any{map[string]any{"key": "k", "id": 1}}},
+			want: `{"auth":[{"id":1,"key":"[REDACTED]"}]}`,
+		},
+		{
+			name: "无法JSON序列化的值",
+			v:    make(chan int),
+			want: "<unrenderable chan int>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := NewPayloadRenderer(tt.opts...).Render(tt.v)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPayloadRenderer_Truncate(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxBytes      int
+		v             string
+		want          string
+		wantTruncated bool
+	}{
+		{"未超过上限", 10, "hello", "hello", false},
+		{"正好等于上限", 5, "hello", "hello", false},
+		{"超过上限被截断", 3, "hello", "hel", true},
+		{"不拆分UTF-8字符", 4, "你好", "你", true},
+		{"不限制", 0, strings.Repeat("a", 10000), strings.Repeat("a", 10000), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, truncated := NewPayloadRenderer(WithMaxBytes(tt.maxBytes)).Render(tt.v)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantTruncated, truncated)
+		})
+	}
+}
+
+func TestPayloadRenderer_AppendAttrs(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxBytes  int
+		wantAttrs []slog.Attr
+	}{
+		{
+			name:      "未截断只追加内容",
+			maxBytes:  100,
+			wantAttrs: []slog.Attr{slog.String("request", "hello")},
+		},
+		{
+			name:      "截断时追加标记字段",
+			maxBytes:  2,
+			wantAttrs: []slog.Attr{slog.String("request", "he"), slog.Bool("request_truncated", true)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewPayloadRenderer(WithMaxBytes(tt.maxBytes)).AppendAttrs(nil, "request", "hello")
+			assert.Equal(t, tt.wantAttrs, got)
+		})
+	}
+}
@@ -5,6 +5,8 @@ package recovery
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"runtime/debug"
 
 	"google.golang.org/grpc"
 )
@@ -27,6 +29,8 @@ func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
 			if p == nil {
 				return
 			}
+			// 记录panic日志
+			logPanic(ctx, opt.getLogger(), info.FullMethod, p)
 			// 使用配置的处理函数处理panic
 			err = opt.handler(ctx, info.FullMethod, p)
 		}()
@@ -54,6 +58,8 @@ func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
 			if p == nil {
 				return
 			}
+			// 记录panic日志
+			logPanic(stream.Context(), opt.getLogger(), info.FullMethod, p)
 			// 使用配置的处理函数处理panic
 			err = opt.handler(stream.Context(), info.FullMethod, p)
 		}()
@@ -63,6 +69,21 @@ func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
 	}
 }
 
+// logPanic 记录捕获到的panic
+//
+// 参数:
+//   - ctx: 请求上下文
+//   - logger: 日志记录器
+//   - method: 方法名
+//   - p: panic的值
+func logPanic(ctx context.Context, logger *slog.Logger, method string, p any) {
+	logger.ErrorContext(ctx, "gRPC panic recovered",
+		slog.String("method", method),
+		slog.Any("panic", p),
+		slog.String("stack", string(debug.Stack())),
+	)
+}
+
 // PanicError 表示捕获到的panic错误
 // 包含方法名、panic值和堆栈跟踪信息
 type PanicError struct {
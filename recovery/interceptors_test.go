@@ -1,8 +1,10 @@
 package recovery
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"log/slog"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -206,3 +208,36 @@ func TestPanicError_Error(t *testing.T) {
 	want := "panic caught: /test.Service/Method\n\nsomething went wrong\n\nstack trace"
 	assert.Equal(t, want, err.Error())
 }
+
+func TestInterceptors_WithLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(buf, nil))
+	handler := func(ctx context.Context, req any) (any, error) {
+		panic("boom")
+	}
+
+	interceptor := UnaryServerInterceptor(WithLogger(logger))
+	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}, handler)
+
+	assert.Error(t, err)
+	assert.Contains(t, buf.String(), `"msg":"gRPC panic recovered"`)
+	assert.Contains(t, buf.String(), `"method":"/test.Service/Method"`)
+	assert.Contains(t, buf.String(), `"panic":"boom"`)
+	assert.Contains(t, buf.String(), `"stack":`)
+}
+
+func TestInterceptors_DefaultLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	handler := func(ctx context.Context, req any) (any, error) {
+		panic("boom")
+	}
+
+	interceptor := UnaryServerInterceptor()
+	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}, handler)
+
+	assert.Error(t, err)
+	assert.Contains(t, buf.String(), `"msg":"gRPC panic recovered"`)
+}
@@ -2,6 +2,7 @@ package recovery
 
 import (
 	"context"
+	"log/slog"
 	"runtime/debug"
 )
 
@@ -9,6 +10,8 @@ import (
 type options struct {
 	// handler panic处理函数
 	handler HandlerFunc
+	// logger 记录panic的日志记录器，为nil时使用slog.Default()
+	logger *slog.Logger
 }
 
 // Option 定义用于配置恢复中间件选项的函数类型
@@ -54,6 +57,28 @@ func RecoveryHandler(f HandlerFunc) Option {
 	}
 }
 
+// WithLogger 设置记录panic的日志记录器
+// 未设置或设置为nil时使用slog.Default()
+//
+// 参数:
+//   - logger: 日志记录器
+//
+// 返回值:
+//   - Option: 配置选项函数
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// getLogger 返回配置的日志记录器，未配置时返回slog.Default()
+func (o *options) getLogger() *slog.Logger {
+	if o.logger != nil {
+		return o.logger
+	}
+	return slog.Default()
+}
+
 // defaultHandler 默认的panic处理函数
 // 创建包含完整信息的PanicError并返回
 func defaultHandler(ctx context.Context, method string, p any) error {
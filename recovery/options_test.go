@@ -3,6 +3,8 @@ package recovery
 import (
 	"context"
 	"errors"
+	"io"
+	"log/slog"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -87,3 +89,22 @@ func TestDefaultHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestWithLogger(t *testing.T) {
+	custom := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tests := []struct {
+		name   string
+		logger *slog.Logger
+		want   *slog.Logger
+	}{
+		{"custom_logger", custom, custom},
+		{"nil_logger_uses_default", nil, slog.Default()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := defaultOptions().apply(WithLogger(tt.logger))
+			assert.Same(t, tt.want, o.getLogger())
+		})
+	}
+}
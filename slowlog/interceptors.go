@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"time"
 
+	"github.com/soyacen/grpc-middleware/logging"
 	"google.golang.org/grpc"
 )
 
@@ -28,12 +29,14 @@ func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
 		info *grpc.UnaryServerInfo,
 		handler grpc.UnaryHandler,
 	) (interface{}, error) {
+		// 在上下文中放入字段集合，供处理器添加业务字段
+		ctx, _ = logging.ContextWithFields(ctx)
 		// 使用defer在函数结束时检查执行时间
 		defer func(startTime time.Time) {
 			elapsed := time.Since(startTime)
 			// 如果执行时间超过阈值，记录慢请求日志
 			if elapsed > o.SlowRequestThreshold {
-				logSlowRequest(ctx, info.FullMethod, elapsed)
+				logSlowRequest(ctx, o.getLogger(), info.FullMethod, elapsed)
 			}
 		}(time.Now())
 		// 执行原始处理器
@@ -59,6 +62,8 @@ func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
 		info *grpc.StreamServerInfo,
 		handler grpc.StreamHandler,
 	) error {
+		// 在流的上下文中放入字段集合，供处理器添加业务字段
+		stream, _ = logging.ServerStreamWithFields(stream)
 		// 获取流的上下文
 		ctx := stream.Context()
 		// 使用defer在函数结束时检查执行时间
@@ -66,7 +71,7 @@ func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
 			elapsed := time.Since(startTime)
 			// 如果执行时间超过阈值，记录慢请求日志
 			if elapsed > o.SlowRequestThreshold {
-				logSlowRequest(ctx, info.FullMethod, elapsed)
+				logSlowRequest(ctx, o.getLogger(), info.FullMethod, elapsed)
 			}
 		}(time.Now())
 		// 执行原始处理器
@@ -100,7 +105,7 @@ func UnaryClientInterceptor(opts ...Option) grpc.UnaryClientInterceptor {
 			elapsed := time.Since(startTime)
 			// 如果执行时间超过阈值，记录慢请求日志
 			if elapsed > o.SlowRequestThreshold {
-				logSlowRequest(ctx, method, elapsed)
+				logSlowRequest(ctx, o.getLogger(), method, elapsed)
 			}
 		}(time.Now())
 		// 执行gRPC调用
@@ -133,7 +138,7 @@ func StreamClientInterceptor(opts ...Option) grpc.StreamClientInterceptor {
 			elapsed := time.Since(startTime)
 			// 如果执行时间超过阈值，记录慢请求日志
 			if elapsed > o.SlowRequestThreshold {
-				logSlowRequest(ctx, method, elapsed)
+				logSlowRequest(ctx, o.getLogger(), method, elapsed)
 			}
 		}(time.Now())
 		// 执行流式gRPC调用
@@ -145,8 +150,12 @@ func StreamClientInterceptor(opts ...Option) grpc.StreamClientInterceptor {
 //
 // 参数:
 //   - ctx: 请求上下文
+//   - logger: 日志记录器
 //   - method: 方法名
 //   - elapsed: 执行耗时
-func logSlowRequest(ctx context.Context, method string, elapsed time.Duration) {
-	slog.WarnContext(ctx, "Slow gRPC call", slog.String("duration", elapsed.String()), slog.String("method", method))
+func logSlowRequest(ctx context.Context, logger *slog.Logger, method string, elapsed time.Duration) {
+	attrs := []slog.Attr{slog.String("duration", elapsed.String()), slog.String("method", method)}
+	// 添加处理器设置的业务字段
+	attrs = logging.FieldsFromContext(ctx).AppendAttrs(attrs)
+	logger.LogAttrs(ctx, slog.LevelWarn, "Slow gRPC call", attrs...)
 }
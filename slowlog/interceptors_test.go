@@ -1,11 +1,14 @@
 package slowlog
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"log/slog"
 	"testing"
 	"time"
 
+	"github.com/soyacen/grpc-middleware/logging"
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
@@ -269,3 +272,66 @@ func (m *mockServerStream) SetTrailer(md metadata.MD)       {}
 func (m *mockServerStream) Context() context.Context               { return m.ctx }
 func (m *mockServerStream) SendMsg(msg any) error                  { return nil }
 func (m *mockServerStream) RecvMsg(msg any) error                  { return nil }
+
+func TestUnaryServerInterceptor_WithLogger(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold time.Duration
+		sleep     time.Duration
+		wantLog   bool
+	}{
+		{"slow_request_logged", time.Millisecond, 5 * time.Millisecond, true},
+		{"fast_request_not_logged", time.Second, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			interceptor := UnaryServerInterceptor(SlowRequestThreshold(tt.threshold), WithLogger(slog.New(slog.NewTextHandler(buf, nil))))
+			handler := func(ctx context.Context, req any) (any, error) {
+				time.Sleep(tt.sleep)
+				return "ok", nil
+			}
+
+			_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test/method"}, handler)
+
+			assert.NoError(t, err)
+			if tt.wantLog {
+				assert.Contains(t, buf.String(), "Slow gRPC call")
+				assert.Contains(t, buf.String(), "method=/test/method")
+			} else {
+				assert.Empty(t, buf.String())
+			}
+		})
+	}
+}
+
+func TestInterceptors_ContextFields(t *testing.T) {
+	t.Run("一元服务端记录处理器设置的字段", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		interceptor := UnaryServerInterceptor(SlowRequestThreshold(-1), WithLogger(slog.New(slog.NewTextHandler(buf, nil))))
+		handler := func(ctx context.Context, req any) (any, error) {
+			logging.FieldsFromContext(ctx).Set("order_id", "o-1")
+			return "ok", nil
+		}
+
+		_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test/method"}, handler)
+		assert.NoError(t, err)
+		assert.Contains(t, buf.String(), "order_id=o-1")
+	})
+
+	t.Run("流式服务端复用已有的字段集合", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		interceptor := StreamServerInterceptor(SlowRequestThreshold(-1), WithLogger(slog.New(slog.NewTextHandler(buf, nil))))
+		ctx, fields := logging.ContextWithFields(context.Background())
+		fields.Set("tenant", "t-1")
+		handler := func(srv any, stream grpc.ServerStream) error {
+			assert.Same(t, fields, logging.FieldsFromContext(stream.Context()))
+			return nil
+		}
+
+		err := interceptor(nil, &mockServerStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: "/test/method"}, handler)
+		assert.NoError(t, err)
+		assert.Contains(t, buf.String(), "tenant=t-1")
+	})
+}
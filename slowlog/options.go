@@ -2,6 +2,7 @@
 package slowlog
 
 import (
+	"log/slog"
 	"time"
 )
 
@@ -9,6 +10,8 @@ import (
 type options struct {
 	// SlowRequestThreshold 慢请求阈值，超过此时间的请求会被记录
 	SlowRequestThreshold time.Duration
+	// Logger 日志记录器，为nil时使用slog.Default()
+	Logger *slog.Logger
 }
 
 // apply 应用所有配置选项
@@ -41,3 +44,25 @@ func SlowRequestThreshold(threshold time.Duration) Option {
 		o.SlowRequestThreshold = threshold
 	}
 }
+
+// WithLogger 设置慢请求日志使用的日志记录器
+// 未设置或设置为nil时使用slog.Default()
+//
+// 参数:
+//   - logger: 日志记录器
+//
+// 返回值:
+//   - Option: 配置选项函数
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.Logger = logger
+	}
+}
+
+// getLogger 返回配置的日志记录器，未配置时返回slog.Default()
+func (o *options) getLogger() *slog.Logger {
+	if o.Logger != nil {
+		return o.Logger
+	}
+	return slog.Default()
+}
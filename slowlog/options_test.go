@@ -1,6 +1,8 @@
 package slowlog
 
 import (
+	"io"
+	"log/slog"
 	"testing"
 	"time"
 
@@ -81,3 +83,23 @@ func TestOptionsApply(t *testing.T) {
 		})
 	}
 }
+
+func TestWithLogger(t *testing.T) {
+	custom := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tests := []struct {
+		name   string
+		logger *slog.Logger
+		want   *slog.Logger
+	}{
+		{"custom_logger", custom, custom},
+		{"nil_logger_falls_back_to_default", nil, slog.Default()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := defaultOptions()
+			o.apply(WithLogger(tt.logger))
+			assert.Same(t, tt.want, o.getLogger())
+		})
+	}
+}